@@ -22,6 +22,7 @@ import (
 	"net/http"
 	"strings"
 
+	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	"github.com/dchest/uniuri"
@@ -31,6 +32,7 @@ import (
 	"github.com/fission/fission/crd"
 	"github.com/fission/fission/environments/fetcher"
 	fetcherClient "github.com/fission/fission/environments/fetcher/client"
+	storageSvcClient "github.com/fission/fission/storagesvc/client"
 )
 
 // buildPackage helps to build source package into deployment package.
@@ -140,3 +142,61 @@ func updatePackage(fissionClient *crd.FissionClient,
 	// return resource version for function to update function package ref
 	return pkg, nil
 }
+
+// checkPackageQuota returns an error if replacing the deployment archive of
+// the package with the uploaded one exceeds the MaxPackageBytes of a
+// FissionQuota of its namespace.  Builds don't go through the controller,
+// which checks the packages created or updated through its API.
+func checkPackageQuota(fissionClient *crd.FissionClient, ssClient *storageSvcClient.Client,
+	pkg *crd.Package, uploadResp *fetcher.UploadResponse) error {
+
+	ns := pkg.Metadata.Namespace
+	if len(ns) == 0 {
+		ns = metav1.NamespaceDefault
+	}
+	quotas, err := fissionClient.FissionQuotas(ns).List(metav1.ListOptions{})
+	if errors.IsNotFound(err) {
+		// the FissionQuota CRD isn't installed yet
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if len(quotas.Items) == 0 {
+		return nil
+	}
+
+	pkgs, err := fissionClient.Packages(ns).List(metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+	previous := &fission.FissionQuotaStatus{}
+	for i := range pkgs.Items {
+		n, err := ssClient.PackageBytes(&pkgs.Items[i].Spec)
+		if err != nil {
+			return err
+		}
+		previous.PackageBytes += n
+	}
+
+	oldBytes, err := ssClient.ArchiveBytes(&pkg.Spec.Deployment)
+	if err != nil {
+		return err
+	}
+	newBytes, err := ssClient.ArchiveBytes(&fission.Archive{
+		Type: fission.ArchiveTypeUrl,
+		URL:  uploadResp.ArchiveDownloadUrl,
+	})
+	if err != nil {
+		return err
+	}
+	usage := *previous
+	usage.PackageBytes += newBytes - oldBytes
+	for i := range quotas.Items {
+		err = quotas.Items[i].Spec.Check(previous, &usage)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
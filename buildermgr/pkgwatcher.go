@@ -30,6 +30,7 @@ import (
 	"github.com/fission/fission"
 	"github.com/fission/fission/cache"
 	"github.com/fission/fission/crd"
+	storageSvcClient "github.com/fission/fission/storagesvc/client"
 )
 
 type (
@@ -38,6 +39,7 @@ type (
 		podStore         k8sCache.Store
 		builderNamespace string
 		storageSvcUrl    string
+		storageClient    *storageSvcClient.Client
 	}
 )
 
@@ -53,6 +55,7 @@ func makePackageWatcher(fissionClient *crd.FissionClient, getter k8sCache.Getter
 		podStore:         store,
 		builderNamespace: builderNamespace,
 		storageSvcUrl:    storageSvcUrl,
+		storageClient:    storageSvcClient.MakeClient(storageSvcUrl),
 	}
 	return pkgw
 }
@@ -64,8 +67,9 @@ func makePackageWatcher(fissionClient *crd.FissionClient, getter k8sCache.Getter
 // 2. Update package status to running state
 // 3. Check environment builder pod status
 // 4. Call buildPackage to build package
-// 5. Update package resource in package ref of functions that share the same package
-// 6. Update package status to succeed state
+// 5. Check that the deployment package fits the namespace's quotas
+// 6. Update package resource in package ref of functions that share the same package
+// 7. Update package status to succeed state
 // *. Update package status to failed state,if any one of steps above failed/time out
 func (pkgw *packageWatcher) build(buildCache *cache.Cache, srcpkg *crd.Package) {
 
@@ -146,6 +150,18 @@ func (pkgw *packageWatcher) build(buildCache *cache.Cache, srcpkg *crd.Package)
 				return
 			}
 
+			err = checkPackageQuota(pkgw.fissionClient, pkgw.storageClient, pkg, uploadResp)
+			if err != nil {
+				e := fmt.Sprintf("Error checking quotas of deployment package: %v", err)
+				log.Println(e)
+				buildLogs += fmt.Sprintf("%v\n", e)
+				if id, ok := storageSvcClient.ArchiveID(uploadResp.ArchiveDownloadUrl); ok {
+					pkgw.storageClient.Delete(id)
+				}
+				updatePackage(pkgw.fissionClient, pkg, fission.BuildStatusFailed, buildLogs, nil)
+				return
+			}
+
 			log.Printf("Start updating info of package: %v", pkg.Metadata.Name)
 
 			fnList, err := pkgw.fissionClient.
@@ -17,46 +17,93 @@ limitations under the License.
 package cache
 
 import (
+	"container/list"
 	"fmt"
+	"hash/fnv"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/fission/fission"
 )
 
-type requestType int
+// The cache is split into shards, each with its own lock, so that
+// concurrent requests for different keys rarely wait for each other.  Every
+// shard keeps its entries in least recently used order; when the cache has
+// a maximum number of entries, each shard holds its share of them and
+// evicts its least recently used entry to make room for a new one.
+
+const shardCount = 16
+
+// EvictionReason tells why an entry was removed from the cache.
+type EvictionReason int
 
 const (
-	GET requestType = iota
-	SET
-	DELETE
-	EXPIRE
-	COPY
+	// Expired entries were older than the cache's ctime or atime expiry.
+	Expired EvictionReason = iota
+
+	// Evicted entries were the least recently used of a full cache.
+	Evicted
 )
 
 type (
 	Value struct {
+		key   interface{}
 		ctime time.Time
 		atime time.Time
 		value interface{}
 	}
+
+	// Options configure a cache.  Zero values disable the corresponding
+	// limit.
+	Options struct {
+		// CtimeExpiry and AtimeExpiry expire entries that long
+		// after they were set, or last used.
+		CtimeExpiry time.Duration
+		AtimeExpiry time.Duration
+
+		// MaxEntries bounds the number of entries of the cache.
+		MaxEntries int
+
+		// OnEviction is called with every entry that expires or is
+		// evicted, without any lock of the cache held.
+		OnEviction func(key interface{}, value interface{}, reason EvictionReason)
+	}
+
+	// Stats are counters of the cache's operations since it was made.
+	Stats struct {
+		Hits        uint64
+		Misses      uint64
+		Evictions   uint64
+		Expirations uint64
+		Entries     int
+	}
+
 	Cache struct {
-		cache          map[interface{}]*Value
-		ctimeExpiry    time.Duration
-		atimeExpiry    time.Duration
-		requestChannel chan *request
+		// counters are first, for 64-bit alignment of atomic access
+		hits        uint64
+		misses      uint64
+		evictions   uint64
+		expirations uint64
+
+		ctimeExpiry time.Duration
+		atimeExpiry time.Duration
+		onEviction  func(key interface{}, value interface{}, reason EvictionReason)
+		shards      [shardCount]*shard
 	}
 
-	request struct {
-		requestType
-		key             interface{}
-		value           interface{}
-		responseChannel chan *response
+	shard struct {
+		lock       sync.Mutex
+		entries    map[interface{}]*list.Element // key -> element of lru, holding a *Value
+		lru        *list.List                    // most recently used first
+		maxEntries int
 	}
-	response struct {
-		error
-		existingValue interface{}
-		mapCopy       map[interface{}]interface{}
-		value         interface{}
+
+	// eviction is an entry removed from a shard, reported once the
+	// shard's lock is released.
+	eviction struct {
+		*Value
+		reason EvictionReason
 	}
 )
 
@@ -73,130 +120,217 @@ func (c *Cache) IsOld(v *Value) bool {
 }
 
 func MakeCache(ctimeExpiry, atimeExpiry time.Duration) *Cache {
+	return MakeCacheWithOptions(Options{
+		CtimeExpiry: ctimeExpiry,
+		AtimeExpiry: atimeExpiry,
+	})
+}
+
+// MakeCacheWithOptions returns a cache with the given expiry, size bound
+// and eviction callback.
+func MakeCacheWithOptions(opts Options) *Cache {
 	c := &Cache{
-		cache:          make(map[interface{}]*Value),
-		ctimeExpiry:    ctimeExpiry,
-		atimeExpiry:    atimeExpiry,
-		requestChannel: make(chan *request),
+		ctimeExpiry: opts.CtimeExpiry,
+		atimeExpiry: opts.AtimeExpiry,
+		onEviction:  opts.OnEviction,
 	}
-	go c.service()
-	if ctimeExpiry != time.Duration(0) || atimeExpiry != time.Duration(0) {
+
+	// spread the maximum number of entries over the shards, rounding
+	// up so that the cache holds at least MaxEntries
+	maxEntries := 0
+	if opts.MaxEntries > 0 {
+		maxEntries = (opts.MaxEntries + shardCount - 1) / shardCount
+	}
+	for i := range c.shards {
+		c.shards[i] = &shard{
+			entries:    make(map[interface{}]*list.Element),
+			lru:        list.New(),
+			maxEntries: maxEntries,
+		}
+	}
+
+	if c.ctimeExpiry != time.Duration(0) || c.atimeExpiry != time.Duration(0) {
 		go c.expiryService()
 	}
 	return c
 }
 
-func (c *Cache) service() {
-	for {
-		req := <-c.requestChannel
-		resp := &response{}
-		switch req.requestType {
-		case GET:
-			val, ok := c.cache[req.key]
-			if !ok {
-				resp.error = fission.MakeError(fission.ErrorNotFound,
-					fmt.Sprintf("key '%v' not found", req.key))
-			} else if c.IsOld(val) {
-				resp.error = fission.MakeError(fission.ErrorNotFound,
-					fmt.Sprintf("key '%v' expired (atime %v)", req.key, val.atime))
-				delete(c.cache, req.key)
-			} else {
-				// update atime
-				val.atime = time.Now()
-				c.cache[req.key] = val
-				resp.value = val.value
-			}
-			req.responseChannel <- resp
-		case SET:
-			now := time.Now()
-			if _, ok := c.cache[req.key]; ok {
-				val := c.cache[req.key]
-				val.atime = time.Now()
-				resp.existingValue = val.value
-				resp.error = fission.MakeError(fission.ErrorNameExists, "key already exists")
-			} else {
-				c.cache[req.key] = &Value{
-					value: req.value,
-					ctime: now,
-					atime: now,
-				}
-			}
-			req.responseChannel <- resp
-		case DELETE:
-			delete(c.cache, req.key)
-			req.responseChannel <- resp
-		case EXPIRE:
-			for k, v := range c.cache {
-				if c.IsOld(v) {
-					delete(c.cache, k)
-				}
-			}
-			// no response
-		case COPY:
-			resp.mapCopy = make(map[interface{}]interface{})
-			for k, v := range c.cache {
-				resp.mapCopy[k] = v.value
-			}
-			req.responseChannel <- resp
-		default:
-			resp.error = fission.MakeError(fission.ErrorInvalidArgument,
-				fmt.Sprintf("invalid request type: %v", req.requestType))
-			req.responseChannel <- resp
+// getShard returns the shard holding the key.
+func (c *Cache) getShard(key interface{}) *shard {
+	h := fnv.New32a()
+	switch k := key.(type) {
+	case string:
+		h.Write([]byte(k))
+	default:
+		fmt.Fprintf(h, "%v", k)
+	}
+	return c.shards[h.Sum32()%shardCount]
+}
+
+// removeElement removes an entry from the shard; the shard's lock is held.
+func (s *shard) removeElement(e *list.Element) *Value {
+	val := s.lru.Remove(e).(*Value)
+	delete(s.entries, val.key)
+	return val
+}
+
+// notify counts the removed entries and reports them to the eviction
+// callback.
+func (c *Cache) notify(evictions []eviction) {
+	for _, e := range evictions {
+		switch e.reason {
+		case Expired:
+			atomic.AddUint64(&c.expirations, 1)
+		case Evicted:
+			atomic.AddUint64(&c.evictions, 1)
+		}
+		if c.onEviction != nil {
+			c.onEviction(e.key, e.value, e.reason)
 		}
 	}
 }
 
 func (c *Cache) Get(key interface{}) (interface{}, error) {
-	respChannel := make(chan *response)
-	c.requestChannel <- &request{
-		requestType:     GET,
-		key:             key,
-		responseChannel: respChannel,
-	}
-	resp := <-respChannel
-	return resp.value, resp.error
+	s := c.getShard(key)
+	s.lock.Lock()
+	e, ok := s.entries[key]
+	if !ok {
+		s.lock.Unlock()
+		atomic.AddUint64(&c.misses, 1)
+		return nil, fission.MakeError(fission.ErrorNotFound,
+			fmt.Sprintf("key '%v' not found", key))
+	}
+	val := e.Value.(*Value)
+	if c.IsOld(val) {
+		s.removeElement(e)
+		s.lock.Unlock()
+		atomic.AddUint64(&c.misses, 1)
+		c.notify([]eviction{{Value: val, reason: Expired}})
+		return nil, fission.MakeError(fission.ErrorNotFound,
+			fmt.Sprintf("key '%v' expired (atime %v)", key, val.atime))
+	}
+	// update atime
+	val.atime = time.Now()
+	s.lru.MoveToFront(e)
+	value := val.value
+	s.lock.Unlock()
+
+	atomic.AddUint64(&c.hits, 1)
+	return value, nil
 }
 
 // if key exists in the cache, the new value is NOT set; instead an
 // error and the old value are returned
 func (c *Cache) Set(key interface{}, value interface{}) (error, interface{}) {
-	respChannel := make(chan *response)
-	c.requestChannel <- &request{
-		requestType:     SET,
-		key:             key,
-		value:           value,
-		responseChannel: respChannel,
-	}
-	resp := <-respChannel
-	return resp.error, resp.existingValue
+	now := time.Now()
+	s := c.getShard(key)
+	s.lock.Lock()
+	if e, ok := s.entries[key]; ok {
+		val := e.Value.(*Value)
+		val.atime = now
+		s.lru.MoveToFront(e)
+		existing := val.value
+		s.lock.Unlock()
+		return fission.MakeError(fission.ErrorNameExists, "key already exists"), existing
+	}
+
+	s.entries[key] = s.lru.PushFront(&Value{
+		key:   key,
+		value: value,
+		ctime: now,
+		atime: now,
+	})
+	var evictions []eviction
+	for s.maxEntries > 0 && s.lru.Len() > s.maxEntries {
+		evictions = append(evictions, eviction{Value: s.removeElement(s.lru.Back()), reason: Evicted})
+	}
+	s.lock.Unlock()
+
+	c.notify(evictions)
+	return nil, nil
 }
 
 func (c *Cache) Delete(key interface{}) error {
-	respChannel := make(chan *response)
-	c.requestChannel <- &request{
-		requestType:     DELETE,
-		key:             key,
-		responseChannel: respChannel,
-	}
-	resp := <-respChannel
-	return resp.error
+	s := c.getShard(key)
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if e, ok := s.entries[key]; ok {
+		s.removeElement(e)
+	}
+	return nil
 }
 
+// Copy returns the keys and values of the cache.  Range avoids the copy
+// for callers that only scan the cache.
 func (c *Cache) Copy() map[interface{}]interface{} {
-	respChannel := make(chan *response)
-	c.requestChannel <- &request{
-		requestType:     COPY,
-		responseChannel: respChannel,
+	mapCopy := make(map[interface{}]interface{})
+	c.Range(func(key interface{}, value interface{}) bool {
+		mapCopy[key] = value
+		return true
+	})
+	return mapCopy
+}
+
+// Range calls f with the key and value of every entry, one shard at a
+// time, until f returns false.  The shard's lock is held while f runs, so
+// f must not use the cache.
+func (c *Cache) Range(f func(key interface{}, value interface{}) bool) {
+	for _, s := range c.shards {
+		s.lock.Lock()
+		for e := s.lru.Front(); e != nil; e = e.Next() {
+			val := e.Value.(*Value)
+			if !f(val.key, val.value) {
+				s.lock.Unlock()
+				return
+			}
+		}
+		s.lock.Unlock()
+	}
+}
+
+// Len returns the number of entries of the cache, including expired ones
+// that haven't been removed yet.
+func (c *Cache) Len() int {
+	n := 0
+	for _, s := range c.shards {
+		s.lock.Lock()
+		n += s.lru.Len()
+		s.lock.Unlock()
+	}
+	return n
+}
+
+// Stats returns the cache's counters.
+func (c *Cache) Stats() Stats {
+	return Stats{
+		Hits:        atomic.LoadUint64(&c.hits),
+		Misses:      atomic.LoadUint64(&c.misses),
+		Evictions:   atomic.LoadUint64(&c.evictions),
+		Expirations: atomic.LoadUint64(&c.expirations),
+		Entries:     c.Len(),
+	}
+}
+
+// expire removes the expired entries of every shard.
+func (c *Cache) expire() {
+	for _, s := range c.shards {
+		var evictions []eviction
+		s.lock.Lock()
+		for e := s.lru.Front(); e != nil; {
+			next := e.Next()
+			if c.IsOld(e.Value.(*Value)) {
+				evictions = append(evictions, eviction{Value: s.removeElement(e), reason: Expired})
+			}
+			e = next
+		}
+		s.lock.Unlock()
+		c.notify(evictions)
 	}
-	resp := <-respChannel
-	return resp.mapCopy
 }
 
 func (c *Cache) expiryService() {
 	for {
 		time.Sleep(time.Minute)
-		c.requestChannel <- &request{
-			requestType: EXPIRE,
-		}
+		c.expire()
 	}
 }
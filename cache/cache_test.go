@@ -17,6 +17,7 @@ limitations under the License.
 package cache
 
 import (
+	"fmt"
 	"log"
 	"testing"
 	"time"
@@ -63,3 +64,188 @@ func TestCache(t *testing.T) {
 		log.Panicf("found expired element")
 	}
 }
+
+func TestCacheEviction(t *testing.T) {
+	evicted := make(map[interface{}]EvictionReason)
+	c := MakeCacheWithOptions(Options{
+		MaxEntries: 4 * shardCount,
+		OnEviction: func(key interface{}, value interface{}, reason EvictionReason) {
+			evicted[key] = reason
+		},
+	})
+
+	// fill the cache well beyond its size; it keeps a bounded number of
+	// entries, evicting the least recently used ones
+	for i := 0; i < 10*shardCount; i++ {
+		err, _ := c.Set(i, i)
+		checkErr(err)
+		_, err = c.Get(0)
+		if err != nil {
+			t.Fatalf("expected the most recently used entry to be kept, after %v entries", i)
+		}
+	}
+	if c.Len() > 4*shardCount {
+		t.Errorf("expected at most %v entries, got %v", 4*shardCount, c.Len())
+	}
+	if len(evicted) != 10*shardCount-c.Len() {
+		t.Errorf("expected every removed entry to be reported, got %v of %v",
+			len(evicted), 10*shardCount-c.Len())
+	}
+	for key, reason := range evicted {
+		if reason != Evicted {
+			t.Errorf("expected key %v to be evicted, got reason %v", key, reason)
+		}
+	}
+
+	stats := c.Stats()
+	if stats.Evictions != uint64(len(evicted)) || stats.Entries != c.Len() {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+}
+
+func TestCacheExpiry(t *testing.T) {
+	var expired []interface{}
+	c := MakeCacheWithOptions(Options{
+		CtimeExpiry: 50 * time.Millisecond,
+		OnEviction: func(key interface{}, value interface{}, reason EvictionReason) {
+			if reason == Expired {
+				expired = append(expired, key)
+			}
+		},
+	})
+
+	err, _ := c.Set("a", "b")
+	checkErr(err)
+	err, _ = c.Set("p", "q")
+	checkErr(err)
+	_, err = c.Get("a")
+	checkErr(err)
+
+	time.Sleep(100 * time.Millisecond)
+	_, err = c.Get("a")
+	if err == nil {
+		t.Errorf("found expired element")
+	}
+	c.expire()
+	if len(expired) != 2 || c.Len() != 0 {
+		t.Errorf("expected both entries to expire, got %v", expired)
+	}
+
+	stats := c.Stats()
+	if stats.Hits != 1 || stats.Misses != 1 || stats.Expirations != 2 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+}
+
+func TestCacheRange(t *testing.T) {
+	c := MakeCache(0, 0)
+	for i := 0; i < 100; i++ {
+		err, _ := c.Set(fmt.Sprintf("key-%v", i), i)
+		checkErr(err)
+	}
+
+	sum := 0
+	c.Range(func(key interface{}, value interface{}) bool {
+		sum += value.(int)
+		return true
+	})
+	if sum != 99*100/2 {
+		t.Errorf("expected to range over every entry, got sum %v", sum)
+	}
+
+	n := 0
+	c.Range(func(key interface{}, value interface{}) bool {
+		n++
+		return n < 10
+	})
+	if n != 10 {
+		t.Errorf("expected ranging to stop, got %v entries", n)
+	}
+}
+
+const benchmarkKeys = 1024
+
+func benchmarkKey(i int) string {
+	return fmt.Sprintf("function-%v", i%benchmarkKeys)
+}
+
+func BenchmarkCacheGet(b *testing.B) {
+	c := MakeCache(0, 0)
+	for i := 0; i < benchmarkKeys; i++ {
+		c.Set(benchmarkKey(i), i)
+	}
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for i := 0; pb.Next(); i++ {
+			c.Get(benchmarkKey(i))
+		}
+	})
+}
+
+func BenchmarkChanCacheGet(b *testing.B) {
+	c := makeChanCache(0, 0)
+	for i := 0; i < benchmarkKeys; i++ {
+		c.Set(benchmarkKey(i), i)
+	}
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for i := 0; pb.Next(); i++ {
+			c.Get(benchmarkKey(i))
+		}
+	})
+}
+
+func BenchmarkCacheSetDelete(b *testing.B) {
+	c := MakeCache(0, 0)
+	b.RunParallel(func(pb *testing.PB) {
+		for i := 0; pb.Next(); i++ {
+			c.Set(benchmarkKey(i), i)
+			c.Delete(benchmarkKey(i))
+		}
+	})
+}
+
+func BenchmarkChanCacheSetDelete(b *testing.B) {
+	c := makeChanCache(0, 0)
+	b.RunParallel(func(pb *testing.PB) {
+		for i := 0; pb.Next(); i++ {
+			c.Set(benchmarkKey(i), i)
+			c.Delete(benchmarkKey(i))
+		}
+	})
+}
+
+func BenchmarkCacheCopy(b *testing.B) {
+	c := MakeCache(0, 0)
+	for i := 0; i < benchmarkKeys; i++ {
+		c.Set(benchmarkKey(i), i)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		c.Copy()
+	}
+}
+
+func BenchmarkChanCacheCopy(b *testing.B) {
+	c := makeChanCache(0, 0)
+	for i := 0; i < benchmarkKeys; i++ {
+		c.Set(benchmarkKey(i), i)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		c.Copy()
+	}
+}
+
+func BenchmarkCacheRange(b *testing.B) {
+	c := MakeCache(0, 0)
+	for i := 0; i < benchmarkKeys; i++ {
+		c.Set(benchmarkKey(i), i)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		c.Range(func(key interface{}, value interface{}) bool {
+			return true
+		})
+	}
+}
@@ -36,6 +36,13 @@ func UrlForFunction(name string) string {
 	return fmt.Sprintf("%v/%v", prefix, name)
 }
 
+// UrlForInvocation returns the router path of the record of an invocation
+// of a job function.
+func UrlForInvocation(id string) string {
+	prefix := "/fission-invocation"
+	return fmt.Sprintf("%v/%v", prefix, id)
+}
+
 func SetupStackTraceHandler() {
 	// register signal handler for dumping stack trace.
 	c := make(chan os.Signal, 1)
@@ -88,6 +95,81 @@ func MergeContainerSpecs(specs ...*apiv1.Container) apiv1.Container {
 	return *result
 }
 
+// MergePodSpecs overlays user supplied pod specs, like the PodSpec of an
+// environment, on a pod spec generated by Fission.
+//
+// The spec takes precedence over the overlays, and lower indexes of the
+// overlays over higher ones. Containers of an overlay named like one of the
+// spec's are merged into it with MergeContainerSpecs; other containers are
+// added. Volumes of an overlay named like one of the spec's are ignored,
+// others are added. The service account of an overlay replaces the spec's.
+// Other fields are merged like MergeContainerSpecs does.
+func MergePodSpecs(spec *apiv1.PodSpec, overlays ...*apiv1.PodSpec) apiv1.PodSpec {
+	result := *spec
+	serviceAccount := ""
+	for _, overlay := range overlays {
+		if overlay == nil {
+			continue
+		}
+
+		// containers and volumes are merged by name below
+		o := *overlay
+		o.Containers, o.InitContainers, o.Volumes = nil, nil, nil
+		err := mergo.Merge(&result, &o)
+		if err != nil {
+			panic(err)
+		}
+
+		if len(serviceAccount) == 0 {
+			serviceAccount = overlay.ServiceAccountName
+		}
+		result.Containers = mergeContainers(result.Containers, overlay.Containers)
+		result.InitContainers = mergeContainers(result.InitContainers, overlay.InitContainers)
+		result.Volumes = mergeVolumes(result.Volumes, overlay.Volumes)
+	}
+	if len(serviceAccount) > 0 {
+		result.ServiceAccountName = serviceAccount
+	}
+	return result
+}
+
+func mergeContainers(containers []apiv1.Container, overlay []apiv1.Container) []apiv1.Container {
+	result := make([]apiv1.Container, 0, len(containers)+len(overlay))
+	merged := make(map[string]bool)
+	for i := range containers {
+		c := containers[i]
+		for j := range overlay {
+			if overlay[j].Name == c.Name {
+				c = MergeContainerSpecs(&containers[i], &overlay[j])
+				merged[c.Name] = true
+				break
+			}
+		}
+		result = append(result, c)
+	}
+	for _, c := range overlay {
+		if !merged[c.Name] {
+			result = append(result, c)
+		}
+	}
+	return result
+}
+
+func mergeVolumes(volumes []apiv1.Volume, overlay []apiv1.Volume) []apiv1.Volume {
+	result := make([]apiv1.Volume, 0, len(volumes)+len(overlay))
+	names := make(map[string]bool)
+	for _, v := range volumes {
+		names[v.Name] = true
+		result = append(result, v)
+	}
+	for _, v := range overlay {
+		if !names[v.Name] {
+			result = append(result, v)
+		}
+	}
+	return result
+}
+
 // IsNetworkDialError returns true if its a network dial error
 func IsNetworkDialError(err error) bool {
 	netErr, ok := err.(net.Error)
@@ -31,6 +31,7 @@ import (
 	"github.com/fission/fission"
 	"github.com/fission/fission/crd"
 	"github.com/fission/fission/fission/logdb"
+	storageSvcClient "github.com/fission/fission/storagesvc/client"
 )
 
 type (
@@ -38,8 +39,10 @@ type (
 		fissionClient     *crd.FissionClient
 		kubernetesClient  *kubernetes.Clientset
 		storageServiceUrl string
+		storageClient     *storageSvcClient.Client // sizes archives for quotas
 		builderManagerUrl string
 		workflowApiUrl    string
+		executorUrl       string
 		functionNamespace string
 		useIstio          bool
 	}
@@ -60,6 +63,7 @@ func MakeAPI() (*API, error) {
 	} else {
 		api.storageServiceUrl = "http://storagesvc"
 	}
+	api.storageClient = storageSvcClient.MakeClient(api.storageServiceUrl)
 
 	u = os.Getenv("BUILDER_MANAGER_URL")
 	if len(u) > 0 {
@@ -75,6 +79,13 @@ func MakeAPI() (*API, error) {
 		api.workflowApiUrl = "http://workflows-apiserver"
 	}
 
+	u = os.Getenv("EXECUTOR_URL")
+	if len(u) > 0 {
+		api.executorUrl = strings.TrimSuffix(u, "/")
+	} else {
+		api.executorUrl = "http://executor"
+	}
+
 	fnNs := os.Getenv("FISSION_FUNCTION_NAMESPACE")
 	if len(fnNs) > 0 {
 		api.functionNamespace = fnNs
@@ -189,12 +200,19 @@ func (api *API) Serve(port int) {
 	r.HandleFunc("/v2/triggers/messagequeue/{mqTrigger}", api.MessageQueueTriggerApiUpdate).Methods("PUT")
 	r.HandleFunc("/v2/triggers/messagequeue/{mqTrigger}", api.MessageQueueTriggerApiDelete).Methods("DELETE")
 
+	r.HandleFunc("/v2/quotas", api.FissionQuotaApiList).Methods("GET")
+	r.HandleFunc("/v2/quotas", api.FissionQuotaApiCreate).Methods("POST")
+	r.HandleFunc("/v2/quotas/{quota}", api.FissionQuotaApiGet).Methods("GET")
+	r.HandleFunc("/v2/quotas/{quota}", api.FissionQuotaApiUpdate).Methods("PUT")
+	r.HandleFunc("/v2/quotas/{quota}", api.FissionQuotaApiDelete).Methods("DELETE")
+
 	r.HandleFunc("/v2/deleteTpr", api.Tpr2crdApi).Methods("DELETE")
 
 	r.HandleFunc("/proxy/{dbType}", api.FunctionLogsApiPost).Methods("POST")
 	r.HandleFunc("/proxy/storage/v1/archive", api.StorageServiceProxy)
 	r.HandleFunc("/proxy/logs/{function}", api.FunctionPodLogs).Methods("POST")
 	r.HandleFunc("/proxy/workflows-apiserver/{path:.*}", api.WorkflowApiserverProxy)
+	r.HandleFunc("/proxy/executor/v2/admin/{path:.*}", api.ExecutorAdminProxy)
 
 	address := fmt.Sprintf(":%v", port)
 
@@ -0,0 +1,149 @@
+/*
+Copyright 2018 The Fission Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	executorClient "github.com/fission/fission/executor/client"
+)
+
+// executorUrl returns the url of the executor's admin API, proxied by the
+// controller.
+func (c *Client) executorUrl(relativeUrl string) string {
+	return c.Url + "/proxy/executor/v2/admin/" + relativeUrl
+}
+
+func functionServicesUrl(m *metav1.ObjectMeta) string {
+	return fmt.Sprintf("functions/%v/%v/services", url.PathEscape(m.Namespace), url.PathEscape(m.Name))
+}
+
+func poolUrl(m *metav1.ObjectMeta) string {
+	return fmt.Sprintf("pools/%v/%v", url.PathEscape(m.Namespace), url.PathEscape(m.Name))
+}
+
+// FunctionServiceList returns the function services cached by the executor
+// for the function.
+func (c *Client) FunctionServiceList(m *metav1.ObjectMeta) ([]executorClient.FunctionServiceInfo, error) {
+	q := url.Values{}
+	q.Set("namespace", m.Namespace)
+	q.Set("function", m.Name)
+	resp, err := http.Get(c.executorUrl("functionServices?" + q.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	return decodeFunctionServices(c.handleResponse(resp))
+}
+
+// FunctionServiceEvict has the executor delete the function's services, and
+// returns them.
+func (c *Client) FunctionServiceEvict(m *metav1.ObjectMeta) ([]executorClient.FunctionServiceInfo, error) {
+	req, err := http.NewRequest("DELETE", c.executorUrl(functionServicesUrl(m)), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	return decodeFunctionServices(c.handleResponse(resp))
+}
+
+// FunctionSpecialize has the executor create a service for the function,
+// even if it has one already.
+func (c *Client) FunctionSpecialize(m *metav1.ObjectMeta) (*executorClient.FunctionServiceInfo, error) {
+	resp, err := http.Post(c.executorUrl(functionServicesUrl(m)), "application/json", nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := c.handleResponse(resp)
+	if err != nil {
+		return nil, err
+	}
+
+	var info executorClient.FunctionServiceInfo
+	err = json.Unmarshal(body, &info)
+	if err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
+
+func (c *Client) EnvironmentPoolGet(m *metav1.ObjectMeta) (*executorClient.PoolInfo, error) {
+	resp, err := http.Get(c.executorUrl(poolUrl(m)))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := c.handleResponse(resp)
+	if err != nil {
+		return nil, err
+	}
+
+	var info executorClient.PoolInfo
+	err = json.Unmarshal(body, &info)
+	if err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
+
+// EnvironmentPoolDrain deletes the idle pods of the environment's pool.
+func (c *Client) EnvironmentPoolDrain(m *metav1.ObjectMeta) error {
+	return c.poolAction(m, "drain")
+}
+
+// EnvironmentPoolRecreate replaces the idle pods of the environment's pool
+// with new ones, in a rolling update.
+func (c *Client) EnvironmentPoolRecreate(m *metav1.ObjectMeta) error {
+	return c.poolAction(m, "recreate")
+}
+
+func (c *Client) poolAction(m *metav1.ObjectMeta, action string) error {
+	resp, err := http.Post(c.executorUrl(poolUrl(m)+"/"+action), "application/json", nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	_, err = c.handleResponse(resp)
+	return err
+}
+
+func decodeFunctionServices(body []byte, err error) ([]executorClient.FunctionServiceInfo, error) {
+	if err != nil {
+		return nil, err
+	}
+	infos := make([]executorClient.FunctionServiceInfo, 0)
+	err = json.Unmarshal(body, &infos)
+	if err != nil {
+		return nil, err
+	}
+	return infos, nil
+}
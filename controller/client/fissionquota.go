@@ -0,0 +1,142 @@
+/*
+Copyright 2018 The Fission Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/fission/fission"
+	"github.com/fission/fission/crd"
+)
+
+func (c *Client) FissionQuotaCreate(q *crd.FissionQuota) (*metav1.ObjectMeta, error) {
+	err := q.Validate()
+	if err != nil {
+		return nil, fission.AggregateValidationErrors("FissionQuota", err)
+	}
+
+	reqbody, err := json.Marshal(q)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := http.Post(c.url("quotas"), "application/json", bytes.NewReader(reqbody))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := c.handleCreateResponse(resp)
+	if err != nil {
+		return nil, err
+	}
+
+	var m metav1.ObjectMeta
+	err = json.Unmarshal(body, &m)
+	if err != nil {
+		return nil, err
+	}
+
+	return &m, nil
+}
+
+func (c *Client) FissionQuotaGet(m *metav1.ObjectMeta) (*crd.FissionQuota, error) {
+	relativeUrl := fmt.Sprintf("quotas/%v", m.Name)
+	relativeUrl += fmt.Sprintf("?namespace=%v", m.Namespace)
+
+	resp, err := http.Get(c.url(relativeUrl))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := c.handleResponse(resp)
+	if err != nil {
+		return nil, err
+	}
+
+	var q crd.FissionQuota
+	err = json.Unmarshal(body, &q)
+	if err != nil {
+		return nil, err
+	}
+
+	return &q, nil
+}
+
+func (c *Client) FissionQuotaUpdate(q *crd.FissionQuota) (*metav1.ObjectMeta, error) {
+	err := q.Validate()
+	if err != nil {
+		return nil, fission.AggregateValidationErrors("FissionQuota", err)
+	}
+
+	reqbody, err := json.Marshal(q)
+	if err != nil {
+		return nil, err
+	}
+	relativeUrl := fmt.Sprintf("quotas/%v", q.Metadata.Name)
+
+	resp, err := c.put(relativeUrl, "application/json", reqbody)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := c.handleResponse(resp)
+	if err != nil {
+		return nil, err
+	}
+
+	var m metav1.ObjectMeta
+	err = json.Unmarshal(body, &m)
+	if err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+func (c *Client) FissionQuotaDelete(m *metav1.ObjectMeta) error {
+	relativeUrl := fmt.Sprintf("quotas/%v", m.Name)
+	relativeUrl += fmt.Sprintf("?namespace=%v", m.Namespace)
+	return c.delete(relativeUrl)
+}
+
+func (c *Client) FissionQuotaList() ([]crd.FissionQuota, error) {
+	resp, err := http.Get(c.url("quotas"))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := c.handleResponse(resp)
+	if err != nil {
+		return nil, err
+	}
+
+	quotas := make([]crd.FissionQuota, 0)
+	err = json.Unmarshal(body, &quotas)
+	if err != nil {
+		return nil, err
+	}
+
+	return quotas, nil
+}
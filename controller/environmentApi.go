@@ -35,6 +35,7 @@ func (a *API) EnvironmentApiList(w http.ResponseWriter, r *http.Request) {
 		a.respondWithError(w, err)
 		return
 	}
+	a.setEnvironmentStatuses(envs.Items, metav1.NamespaceAll, "")
 
 	resp, err := json.Marshal(envs.Items)
 	if err != nil {
@@ -59,6 +60,8 @@ func (a *API) EnvironmentApiCreate(w http.ResponseWriter, r *http.Request) {
 		a.respondWithError(w, err)
 		return
 	}
+	// the status is reported by the executor, not stored
+	env.Status = fission.EnvironmentStatus{}
 
 	enew, err := a.fissionClient.Environments(env.Metadata.Namespace).Create(&env)
 	if err != nil {
@@ -89,8 +92,10 @@ func (a *API) EnvironmentApiGet(w http.ResponseWriter, r *http.Request) {
 		a.respondWithError(w, err)
 		return
 	}
+	envs := []crd.Environment{*env}
+	a.setEnvironmentStatuses(envs, ns, name)
 
-	resp, err := json.Marshal(env)
+	resp, err := json.Marshal(envs[0])
 	if err != nil {
 		a.respondWithError(w, err)
 		return
@@ -121,6 +126,7 @@ func (a *API) EnvironmentApiUpdate(w http.ResponseWriter, r *http.Request) {
 		a.respondWithError(w, err)
 		return
 	}
+	env.Status = fission.EnvironmentStatus{}
 
 	enew, err := a.fissionClient.Environments(env.Metadata.Namespace).Update(&env)
 	if err != nil {
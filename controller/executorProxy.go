@@ -0,0 +1,129 @@
+/*
+Copyright 2018 The Fission Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"time"
+
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/fission/fission/crd"
+	executorClient "github.com/fission/fission/executor/client"
+)
+
+// executorStatusClient gets function statuses from the executor; reading
+// functions doesn't wait long for them.
+var executorStatusClient = &http.Client{Timeout: 5 * time.Second}
+
+// ExecutorAdminProxy passes requests to the admin API of the executor, for
+// the CLI; the rest of the executor's API isn't exposed.
+func (api *API) ExecutorAdminProxy(w http.ResponseWriter, r *http.Request) {
+	u := api.executorUrl
+	executorUrl, err := url.Parse(u)
+	if err != nil {
+		msg := fmt.Sprintf("Error parsing url %v: %v", u, err)
+		http.Error(w, msg, 500)
+		return
+	}
+
+	vars := mux.Vars(r)
+	path := fmt.Sprintf("/v2/admin/%s", vars["path"])
+	director := func(req *http.Request) {
+		req.URL.Scheme = executorUrl.Scheme
+		req.URL.Host = executorUrl.Host
+		req.URL.Path = path
+	}
+	proxy := &httputil.ReverseProxy{
+		Director: director,
+	}
+	proxy.ServeHTTP(w, r)
+}
+
+// setFunctionStatuses fills in the status of the functions, as reported by
+// the executor.  The functions are the named one, or all functions in the
+// namespace if name is empty.  If the executor can't be reached, their
+// status is left unknown.
+func (api *API) setFunctionStatuses(fns []crd.Function, namespace string, name string) {
+	u := fmt.Sprintf("%v/v2/admin/functionStatuses?namespace=%v&function=%v",
+		api.executorUrl, url.QueryEscape(namespace), url.QueryEscape(name))
+	resp, err := executorStatusClient.Get(u)
+	if err != nil {
+		log.Printf("Error getting function statuses from executor: %v", err)
+		return
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		log.Printf("Error getting function statuses from executor: %v", resp.Status)
+		return
+	}
+
+	var statuses []executorClient.FunctionStatusInfo
+	err = json.NewDecoder(resp.Body).Decode(&statuses)
+	if err != nil {
+		log.Printf("Error decoding function statuses from executor: %v", err)
+		return
+	}
+	byUID := make(map[types.UID]int)
+	for i, s := range statuses {
+		byUID[s.Function.UID] = i
+	}
+	for i := range fns {
+		if j, ok := byUID[fns[i].Metadata.UID]; ok {
+			fns[i].Status = statuses[j].Status
+		}
+	}
+}
+
+// setEnvironmentStatuses fills in the status of the environments, as
+// reported by the executor, like setFunctionStatuses.
+func (api *API) setEnvironmentStatuses(envs []crd.Environment, namespace string, name string) {
+	u := fmt.Sprintf("%v/v2/admin/environmentStatuses?namespace=%v&environment=%v",
+		api.executorUrl, url.QueryEscape(namespace), url.QueryEscape(name))
+	resp, err := executorStatusClient.Get(u)
+	if err != nil {
+		log.Printf("Error getting environment statuses from executor: %v", err)
+		return
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		log.Printf("Error getting environment statuses from executor: %v", resp.Status)
+		return
+	}
+
+	var statuses []executorClient.EnvironmentStatusInfo
+	err = json.NewDecoder(resp.Body).Decode(&statuses)
+	if err != nil {
+		log.Printf("Error decoding environment statuses from executor: %v", err)
+		return
+	}
+	byUID := make(map[types.UID]int)
+	for i, s := range statuses {
+		byUID[s.Environment.UID] = i
+	}
+	for i := range envs {
+		if j, ok := byUID[envs[i].Metadata.UID]; ok {
+			envs[i].Status = statuses[j].Status
+		}
+	}
+}
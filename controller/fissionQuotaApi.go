@@ -0,0 +1,172 @@
+/*
+Copyright 2018 The Fission Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/fission/fission"
+	"github.com/fission/fission/crd"
+)
+
+func (a *API) FissionQuotaApiList(w http.ResponseWriter, r *http.Request) {
+	quotas, err := a.fissionClient.FissionQuotas(metav1.NamespaceAll).List(metav1.ListOptions{})
+	if err != nil {
+		a.respondWithError(w, err)
+		return
+	}
+	a.setQuotaStatuses(quotas.Items)
+
+	resp, err := json.Marshal(quotas.Items)
+	if err != nil {
+		a.respondWithError(w, err)
+		return
+	}
+
+	a.respondWithSuccess(w, resp)
+}
+
+func (a *API) FissionQuotaApiCreate(w http.ResponseWriter, r *http.Request) {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		a.respondWithError(w, err)
+		return
+	}
+
+	var q crd.FissionQuota
+	err = json.Unmarshal(body, &q)
+	if err != nil {
+		a.respondWithError(w, err)
+		return
+	}
+
+	err = q.Spec.Validate()
+	if err != nil {
+		err = fission.MakeError(fission.ErrorInvalidArgument, err.Error())
+		a.respondWithError(w, err)
+		return
+	}
+	// the status is the usage of the namespace, not stored
+	q.Status = fission.FissionQuotaStatus{}
+
+	qnew, err := a.fissionClient.FissionQuotas(q.Metadata.Namespace).Create(&q)
+	if err != nil {
+		a.respondWithError(w, err)
+		return
+	}
+
+	resp, err := json.Marshal(qnew.Metadata)
+	if err != nil {
+		a.respondWithError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	a.respondWithSuccess(w, resp)
+}
+
+func (a *API) FissionQuotaApiGet(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	name := vars["quota"]
+	ns := vars["namespace"]
+	if len(ns) == 0 {
+		ns = metav1.NamespaceDefault
+	}
+
+	q, err := a.fissionClient.FissionQuotas(ns).Get(name)
+	if err != nil {
+		a.respondWithError(w, err)
+		return
+	}
+	quotas := []crd.FissionQuota{*q}
+	a.setQuotaStatuses(quotas)
+
+	resp, err := json.Marshal(quotas[0])
+	if err != nil {
+		a.respondWithError(w, err)
+		return
+	}
+
+	a.respondWithSuccess(w, resp)
+}
+
+func (a *API) FissionQuotaApiUpdate(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	name := vars["quota"]
+
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		a.respondWithError(w, err)
+		return
+	}
+
+	var q crd.FissionQuota
+	err = json.Unmarshal(body, &q)
+	if err != nil {
+		a.respondWithError(w, err)
+		return
+	}
+
+	if name != q.Metadata.Name {
+		err = fission.MakeError(fission.ErrorInvalidArgument, "FissionQuota name doesn't match URL")
+		a.respondWithError(w, err)
+		return
+	}
+
+	err = q.Spec.Validate()
+	if err != nil {
+		err = fission.MakeError(fission.ErrorInvalidArgument, err.Error())
+		a.respondWithError(w, err)
+		return
+	}
+	q.Status = fission.FissionQuotaStatus{}
+
+	qnew, err := a.fissionClient.FissionQuotas(q.Metadata.Namespace).Update(&q)
+	if err != nil {
+		a.respondWithError(w, err)
+		return
+	}
+
+	resp, err := json.Marshal(qnew.Metadata)
+	if err != nil {
+		a.respondWithError(w, err)
+		return
+	}
+	a.respondWithSuccess(w, resp)
+}
+
+func (a *API) FissionQuotaApiDelete(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	name := vars["quota"]
+	ns := vars["namespace"]
+	if len(ns) == 0 {
+		ns = metav1.NamespaceDefault
+	}
+
+	err := a.fissionClient.FissionQuotas(ns).Delete(name, &metav1.DeleteOptions{})
+	if err != nil {
+		a.respondWithError(w, err)
+		return
+	}
+
+	a.respondWithSuccess(w, []byte(""))
+}
@@ -49,6 +49,7 @@ func (a *API) FunctionApiList(w http.ResponseWriter, r *http.Request) {
 		a.respondWithError(w, err)
 		return
 	}
+	a.setFunctionStatuses(funcs.Items, metav1.NamespaceAll, "")
 
 	resp, err := json.Marshal(funcs.Items)
 	if err != nil {
@@ -72,6 +73,18 @@ func (a *API) FunctionApiCreate(w http.ResponseWriter, r *http.Request) {
 		a.respondWithError(w, err)
 		return
 	}
+	// the status is reported by the executor, not stored
+	f.Status = fission.FunctionStatus{}
+
+	err = a.checkQuotas(quotaNamespace(f.Metadata.Namespace), func(usage *fission.FissionQuotaStatus) error {
+		usage.Functions++
+		usage.Scale += fission.FunctionScale(&f.Spec)
+		return nil
+	})
+	if err != nil {
+		a.respondWithError(w, err)
+		return
+	}
 
 	fnew, err := a.fissionClient.Functions(f.Metadata.Namespace).Create(&f)
 	if err != nil {
@@ -102,8 +115,10 @@ func (a *API) FunctionApiGet(w http.ResponseWriter, r *http.Request) {
 		a.respondWithError(w, err)
 		return
 	}
+	fns := []crd.Function{*f}
+	a.setFunctionStatuses(fns, ns, name)
 
-	resp, err := json.Marshal(f)
+	resp, err := json.Marshal(fns[0])
 	if err != nil {
 		a.respondWithError(w, err)
 		return
@@ -133,6 +148,22 @@ func (a *API) FunctionApiUpdate(w http.ResponseWriter, r *http.Request) {
 		a.respondWithError(w, err)
 		return
 	}
+	f.Status = fission.FunctionStatus{}
+
+	ns := quotaNamespace(f.Metadata.Namespace)
+	existing, err := a.fissionClient.Functions(ns).Get(name)
+	if err != nil {
+		a.respondWithError(w, err)
+		return
+	}
+	err = a.checkQuotas(ns, func(usage *fission.FissionQuotaStatus) error {
+		usage.Scale += fission.FunctionScale(&f.Spec) - fission.FunctionScale(&existing.Spec)
+		return nil
+	})
+	if err != nil {
+		a.respondWithError(w, err)
+		return
+	}
 
 	fnew, err := a.fissionClient.Functions(f.Metadata.Namespace).Update(&f)
 	if err != nil {
@@ -70,6 +70,16 @@ func (a *API) PackageApiCreate(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	err = a.checkQuotas(quotaNamespace(f.Metadata.Namespace), func(usage *fission.FissionQuotaStatus) error {
+		n, err := a.storageClient.PackageBytes(&f.Spec)
+		usage.PackageBytes += n
+		return err
+	})
+	if err != nil {
+		a.respondWithError(w, err)
+		return
+	}
+
 	fnew, err := a.fissionClient.Packages(f.Metadata.Namespace).Create(&f)
 	if err != nil {
 		a.respondWithError(w, err)
@@ -137,6 +147,26 @@ func (a *API) PackageApiUpdate(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	ns := quotaNamespace(f.Metadata.Namespace)
+	existing, err := a.fissionClient.Packages(ns).Get(name)
+	if err != nil {
+		a.respondWithError(w, err)
+		return
+	}
+	err = a.checkQuotas(ns, func(usage *fission.FissionQuotaStatus) error {
+		n, err := a.storageClient.PackageBytes(&f.Spec)
+		if err != nil {
+			return err
+		}
+		existingBytes, err := a.storageClient.PackageBytes(&existing.Spec)
+		usage.PackageBytes += n - existingBytes
+		return err
+	})
+	if err != nil {
+		a.respondWithError(w, err)
+		return
+	}
+
 	fnew, err := a.fissionClient.Packages(f.Metadata.Namespace).Update(&f)
 	if err != nil {
 		a.respondWithError(w, err)
@@ -0,0 +1,138 @@
+/*
+Copyright 2018 The Fission Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+
+	"github.com/fission/fission"
+	"github.com/fission/fission/crd"
+)
+
+// The controller enforces the FissionQuotas of a namespace on the functions
+// and packages created or updated through its API; the builder manager
+// enforces their MaxPackageBytes on builds, and the executor their
+// MaxSpecializedPods when it specializes pods.  Usage is computed from the
+// namespace's objects when it's needed, and isn't stored; archives are
+// sized by the storage service rather than by what packages claim.
+
+// quotaNamespace returns the namespace objects without one are created in.
+func quotaNamespace(ns string) string {
+	if len(ns) == 0 {
+		return metav1.NamespaceDefault
+	}
+	return ns
+}
+
+// quotaUsage computes what the functions of the namespace use.
+func (a *API) quotaUsage(ns string) (*fission.FissionQuotaStatus, error) {
+	usage := &fission.FissionQuotaStatus{}
+
+	fns, err := a.fissionClient.Functions(ns).List(metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	usage.Functions = len(fns.Items)
+	for i := range fns.Items {
+		usage.Scale += fission.FunctionScale(&fns.Items[i].Spec)
+	}
+
+	pkgs, err := a.fissionClient.Packages(ns).List(metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for i := range pkgs.Items {
+		n, err := a.storageClient.PackageBytes(&pkgs.Items[i].Spec)
+		if err != nil {
+			return nil, err
+		}
+		usage.PackageBytes += n
+	}
+
+	// pods specialized by poolmgr are labeled with their function's
+	// namespace
+	pods, err := a.kubernetesClient.CoreV1().Pods(a.functionNamespace).List(metav1.ListOptions{
+		LabelSelector: labels.Set(map[string]string{
+			"functionNamespace": ns,
+			"unmanaged":         "true",
+		}).AsSelector().String(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	for i := range pods.Items {
+		if pods.Items[i].ObjectMeta.DeletionTimestamp == nil {
+			usage.SpecializedPods++
+		}
+	}
+	return usage, nil
+}
+
+// checkQuotas returns an error if the change made by update to the usage of
+// the namespace exceeds one of its quotas.  Without quotas, update isn't
+// called.
+func (a *API) checkQuotas(ns string, update func(usage *fission.FissionQuotaStatus) error) error {
+	quotas, err := a.fissionClient.FissionQuotas(ns).List(metav1.ListOptions{})
+	if kerrors.IsNotFound(err) {
+		// the FissionQuota CRD isn't installed yet
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if len(quotas.Items) == 0 {
+		return nil
+	}
+
+	previous, err := a.quotaUsage(ns)
+	if err != nil {
+		return err
+	}
+	usage := *previous
+	err = update(&usage)
+	if err != nil {
+		return err
+	}
+	for i := range quotas.Items {
+		err = quotas.Items[i].Spec.Check(previous, &usage)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// setQuotaStatuses fills in the usage of the quotas' namespaces.  Quotas
+// are returned without it if it can't be computed.
+func (a *API) setQuotaStatuses(quotas []crd.FissionQuota) {
+	usages := make(map[string]*fission.FissionQuotaStatus)
+	for i := range quotas {
+		ns := quotaNamespace(quotas[i].Metadata.Namespace)
+		usage, ok := usages[ns]
+		if !ok {
+			var err error
+			usage, err = a.quotaUsage(ns)
+			if err != nil {
+				continue
+			}
+			usages[ns] = usage
+		}
+		quotas[i].Status = *usage
+	}
+}
@@ -153,6 +153,13 @@ func configureClient(config *rest.Config) {
 				&metav1.ListOptions{},
 				&metav1.DeleteOptions{},
 			)
+			scheme.AddKnownTypes(
+				groupversion,
+				&FissionQuota{},
+				&FissionQuotaList{},
+				&metav1.ListOptions{},
+				&metav1.DeleteOptions{},
+			)
 			return nil
 		})
 	schemeBuilder.AddToScheme(scheme.Scheme)
@@ -211,6 +218,9 @@ func (fc *FissionClient) MessageQueueTriggers(ns string) MessageQueueTriggerInte
 func (fc *FissionClient) Packages(ns string) PackageInterface {
 	return MakePackageInterface(fc.crdClient, ns)
 }
+func (fc *FissionClient) FissionQuotas(ns string) FissionQuotaInterface {
+	return MakeFissionQuotaInterface(fc.crdClient, ns)
+}
 
 func (fc *FissionClient) WaitForCRDs() error {
 	return waitForCRDs(fc.crdClient)
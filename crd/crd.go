@@ -176,6 +176,22 @@ func EnsureFissionCRDs(clientset *apiextensionsclient.Clientset) error {
 				},
 			},
 		},
+		// Quotas: limits on what the functions of a namespace may use
+		{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "fissionquotas.fission.io",
+			},
+			Spec: apiextensionsv1beta1.CustomResourceDefinitionSpec{
+				Group:   crdGroupName,
+				Version: crdVersion,
+				Scope:   apiextensionsv1beta1.NamespaceScoped,
+				Names: apiextensionsv1beta1.CustomResourceDefinitionNames{
+					Kind:     "FissionQuota",
+					Plural:   "fissionquotas",
+					Singular: "fissionquota",
+				},
+			},
+		},
 	}
 	for _, crd := range crds {
 		err := ensureCRD(clientset, &crd)
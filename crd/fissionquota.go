@@ -0,0 +1,120 @@
+/*
+Copyright 2018 The Fission Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package crd
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+)
+
+type (
+	FissionQuotaInterface interface {
+		Create(*FissionQuota) (*FissionQuota, error)
+		Get(name string) (*FissionQuota, error)
+		Update(*FissionQuota) (*FissionQuota, error)
+		Delete(name string, options *metav1.DeleteOptions) error
+		List(opts metav1.ListOptions) (*FissionQuotaList, error)
+		Watch(opts metav1.ListOptions) (watch.Interface, error)
+	}
+
+	fissionQuotaClient struct {
+		client    *rest.RESTClient
+		namespace string
+	}
+)
+
+func MakeFissionQuotaInterface(crdClient *rest.RESTClient, namespace string) FissionQuotaInterface {
+	return &fissionQuotaClient{
+		client:    crdClient,
+		namespace: namespace,
+	}
+}
+
+func (fc *fissionQuotaClient) Create(f *FissionQuota) (*FissionQuota, error) {
+	var result FissionQuota
+	err := fc.client.Post().
+		Resource("fissionquotas").
+		Namespace(fc.namespace).
+		Body(f).
+		Do().Into(&result)
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+func (fc *fissionQuotaClient) Get(name string) (*FissionQuota, error) {
+	var result FissionQuota
+	err := fc.client.Get().
+		Resource("fissionquotas").
+		Namespace(fc.namespace).
+		Name(name).
+		Do().Into(&result)
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+func (fc *fissionQuotaClient) Update(f *FissionQuota) (*FissionQuota, error) {
+	var result FissionQuota
+	err := fc.client.Put().
+		Resource("fissionquotas").
+		Namespace(fc.namespace).
+		Name(f.Metadata.Name).
+		Body(f).
+		Do().Into(&result)
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+func (fc *fissionQuotaClient) Delete(name string, opts *metav1.DeleteOptions) error {
+	return fc.client.Delete().
+		Namespace(fc.namespace).
+		Resource("fissionquotas").
+		Name(name).
+		Body(opts).
+		Do().
+		Error()
+}
+
+func (fc *fissionQuotaClient) List(opts metav1.ListOptions) (*FissionQuotaList, error) {
+	var result FissionQuotaList
+	err := fc.client.Get().
+		Namespace(fc.namespace).
+		Resource("fissionquotas").
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Do().
+		Into(&result)
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+func (fc *fissionQuotaClient) Watch(opts metav1.ListOptions) (watch.Interface, error) {
+	return fc.client.Get().
+		Prefix("watch").
+		Namespace(fc.namespace).
+		Resource("fissionquotas").
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Watch()
+}
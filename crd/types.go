@@ -58,6 +58,9 @@ type (
 		metav1.TypeMeta `json:",inline"`
 		Metadata        metav1.ObjectMeta    `json:"metadata"`
 		Spec            fission.FunctionSpec `json:"spec"`
+
+		// Status is reported by the executor, see fission.FunctionStatus
+		Status fission.FunctionStatus `json:"status,omitempty"`
 	}
 	FunctionList struct {
 		metav1.TypeMeta `json:",inline"`
@@ -71,6 +74,9 @@ type (
 		metav1.TypeMeta `json:",inline"`
 		Metadata        metav1.ObjectMeta       `json:"metadata"`
 		Spec            fission.EnvironmentSpec `json:"spec"`
+
+		// Status is reported by the executor, see fission.EnvironmentStatus
+		Status fission.EnvironmentStatus `json:"status,omitempty"`
 	}
 	EnvironmentList struct {
 		metav1.TypeMeta `json:",inline"`
@@ -129,6 +135,22 @@ type (
 
 		Items []MessageQueueTrigger `json:"items"`
 	}
+
+	// Quotas of namespaces
+	FissionQuota struct {
+		metav1.TypeMeta `json:",inline"`
+		Metadata        metav1.ObjectMeta        `json:"metadata"`
+		Spec            fission.FissionQuotaSpec `json:"spec"`
+
+		// Status is the usage of the namespace, see fission.FissionQuotaStatus
+		Status fission.FissionQuotaStatus `json:"status,omitempty"`
+	}
+	FissionQuotaList struct {
+		metav1.TypeMeta `json:",inline"`
+		Metadata        metav1.ListMeta `json:"metadata"`
+
+		Items []FissionQuota `json:"items"`
+	}
 )
 
 // Each CRD type needs:
@@ -161,6 +183,9 @@ func (m *MessageQueueTrigger) GetObjectKind() schema.ObjectKind {
 func (p *Package) GetObjectKind() schema.ObjectKind {
 	return &p.TypeMeta
 }
+func (q *FissionQuota) GetObjectKind() schema.ObjectKind {
+	return &q.TypeMeta
+}
 
 func (f *Function) GetObjectMeta() metav1.Object {
 	return &f.Metadata
@@ -183,6 +208,9 @@ func (m *MessageQueueTrigger) GetObjectMeta() metav1.Object {
 func (p *Package) GetObjectMeta() metav1.Object {
 	return &p.Metadata
 }
+func (q *FissionQuota) GetObjectMeta() metav1.Object {
+	return &q.Metadata
+}
 
 func (fl *FunctionList) GetObjectKind() schema.ObjectKind {
 	return &fl.TypeMeta
@@ -205,6 +233,9 @@ func (ml *MessageQueueTriggerList) GetObjectKind() schema.ObjectKind {
 func (pl *PackageList) GetObjectKind() schema.ObjectKind {
 	return &pl.TypeMeta
 }
+func (ql *FissionQuotaList) GetObjectKind() schema.ObjectKind {
+	return &ql.TypeMeta
+}
 
 func (fl *FunctionList) GetListMeta() metav1.List {
 	return &fl.Metadata
@@ -227,6 +258,9 @@ func (ml *MessageQueueTriggerList) GetListMeta() metav1.List {
 func (pl *PackageList) GetListMeta() metav1.List {
 	return &pl.Metadata
 }
+func (ql *FissionQuotaList) GetListMeta() metav1.List {
+	return &ql.Metadata
+}
 
 func validateMetadata(field string, m metav1.ObjectMeta) error {
 	return fission.ValidateKubeReference(field, m.Name, m.Namespace)
@@ -359,3 +393,21 @@ func (ml *MessageQueueTriggerList) Validate() error {
 	}
 	return result.ErrorOrNil()
 }
+
+func (q *FissionQuota) Validate() error {
+	var result *multierror.Error
+
+	result = multierror.Append(result,
+		validateMetadata("FissionQuota", q.Metadata),
+		q.Spec.Validate())
+
+	return result.ErrorOrNil()
+}
+
+func (ql *FissionQuotaList) Validate() error {
+	var result *multierror.Error
+	for _, q := range ql.Items {
+		result = multierror.Append(result, q.Validate())
+	}
+	return result.ErrorOrNil()
+}
@@ -20,6 +20,10 @@ const (
 
 var specialized bool
 
+// functionEnv is the environment of the function, passed to its executable
+// along with the request's.
+var functionEnv []*EnvVar
+
 type (
 	BinaryServer struct {
 		fetchedCodePath  string
@@ -41,6 +45,10 @@ type (
 		// URL to expose this function at. Optional; defaults
 		// to "/".
 		URL string `json:"url"`
+
+		// EnvVars are environment variables of the function,
+		// passed to its executable. Optional.
+		EnvVars map[string]string `json:"envVars,omitempty"`
 	}
 )
 
@@ -101,6 +109,10 @@ func (bs *BinaryServer) SpecializeHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
+	for name, value := range request.EnvVars {
+		functionEnv = append(functionEnv, &EnvVar{name, value})
+	}
+
 	fmt.Println("Specializing ...")
 	specialized = true
 	fmt.Println("Done")
@@ -115,6 +127,9 @@ func (bs *BinaryServer) InvocationHandler(w http.ResponseWriter, r *http.Request
 
 	// CGI-like passing of environment variables
 	execEnv := NewEnv(nil)
+	for _, envVar := range functionEnv {
+		execEnv.SetEnv(envVar)
+	}
 	execEnv.SetEnv(&EnvVar{"REQUEST_METHOD", r.Method})
 	execEnv.SetEnv(&EnvVar{"REQUEST_URI", r.RequestURI})
 	execEnv.SetEnv(&EnvVar{"CONTENT_LENGTH", fmt.Sprintf("%d", r.ContentLength)})
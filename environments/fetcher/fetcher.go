@@ -467,7 +467,13 @@ func (fetcher *Fetcher) UploadHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 func (fetcher *Fetcher) rename(src string, dst string) error {
-	err := os.Rename(src, dst)
+	// a container running several functions may be asked to fetch
+	// a function it already has
+	err := os.RemoveAll(dst)
+	if err != nil {
+		return errors.New(fmt.Sprintf("Failed to remove existing file: %v", err))
+	}
+	err = os.Rename(src, dst)
 	if err != nil {
 		return errors.New(fmt.Sprintf("Failed to move file: %v", err))
 	}
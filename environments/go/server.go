@@ -8,6 +8,8 @@ import (
 	"os"
 	"path/filepath"
 	"plugin"
+	"strings"
+	"sync"
 
 	"github.com/fission/fission/environments/go/context"
 )
@@ -32,11 +34,45 @@ type (
 		// URL to expose this function at. Optional; defaults
 		// to "/".
 		URL string `json:"url"`
+
+		// EnvVars are environment variables of the function,
+		// set before loading it. Optional.
+		EnvVars map[string]string `json:"envVars,omitempty"`
 	}
 )
 
 var userFunc http.HandlerFunc
 
+// Containers of environments allowing several functions per container
+// load each function at its own URL.  Environment variables are set for
+// the whole process, so such functions can't set them.
+var (
+	urlFuncs     = make(map[string]http.HandlerFunc)
+	urlFuncsLock sync.RWMutex
+)
+
+// isDefaultURL returns true if the function is loaded as the only function
+// of the container.
+func isDefaultURL(url string) bool {
+	return len(url) == 0 || url == "/"
+}
+
+// lookupFunc returns the function loaded at the request's path, or at a
+// prefix of it, falling back to the container's only function.
+func lookupFunc(path string) http.HandlerFunc {
+	urlFuncsLock.RLock()
+	defer urlFuncsLock.RUnlock()
+	if f, ok := urlFuncs[path]; ok {
+		return f
+	}
+	for url, f := range urlFuncs {
+		if strings.HasPrefix(path, strings.TrimSuffix(url, "/")+"/") {
+			return f
+		}
+	}
+	return userFunc
+}
+
 func loadPlugin(codePath, entrypoint string) http.HandlerFunc {
 
 	// if codepath's a directory, load the file inside it
@@ -107,12 +143,6 @@ func specializeHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 func specializeHandlerV2(w http.ResponseWriter, r *http.Request) {
-	if userFunc != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		w.Write([]byte("Not a generic container"))
-		return
-	}
-
 	body, err := ioutil.ReadAll(r.Body)
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
@@ -125,6 +155,14 @@ func specializeHandlerV2(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// functions loaded at their own URL may be added, or replaced, at
+	// any time; the container's only function is loaded once
+	if isDefaultURL(loadreq.URL) && userFunc != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("Not a generic container"))
+		return
+	}
+
 	_, err = os.Stat(loadreq.FilePath)
 	if err != nil {
 		if os.IsNotExist(err) {
@@ -136,8 +174,31 @@ func specializeHandlerV2(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	// the process environment is shared by the functions of the
+	// container, so only a container's single function may set it
+	if !isDefaultURL(loadreq.URL) && len(loadreq.EnvVars) > 0 {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("Environment variables can't be set for a function sharing the container"))
+		return
+	}
+	for name, value := range loadreq.EnvVars {
+		err = os.Setenv(name, value)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(fmt.Sprintf("Failed to set environment variable %v: %v", name, err)))
+			return
+		}
+	}
+
 	fmt.Println("Specializing ...")
-	userFunc = loadPlugin(loadreq.FilePath, loadreq.FunctionName)
+	f := loadPlugin(loadreq.FilePath, loadreq.FunctionName)
+	if isDefaultURL(loadreq.URL) {
+		userFunc = f
+	} else {
+		urlFuncsLock.Lock()
+		urlFuncs[loadreq.URL] = f
+		urlFuncsLock.Unlock()
+	}
 	fmt.Println("Done")
 }
 
@@ -150,14 +211,16 @@ func main() {
 	http.HandleFunc("/specialize", specializeHandler)
 	http.HandleFunc("/v2/specialize", specializeHandlerV2)
 
-	// Generic route -- all http requests go to the user function.
+	// Generic route -- all http requests go to the user function
+	// loaded at their path, or to the container's only function.
 	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
-		if userFunc == nil {
+		f := lookupFunc(r.URL.Path)
+		if f == nil {
 			w.WriteHeader(http.StatusInternalServerError)
 			w.Write([]byte("Generic container: no requests supported"))
 			return
 		}
-		userFunc(w, r)
+		f(w, r)
 	})
 
 	fmt.Println("Listening on 8888 ...")
@@ -0,0 +1,246 @@
+/*
+Copyright 2018 The Fission Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package executor
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/fission/fission"
+	"github.com/fission/fission/crd"
+	executorClient "github.com/fission/fission/executor/client"
+	"github.com/fission/fission/executor/fscache"
+)
+
+// The admin API inspects and manipulates the function services and pools
+// of the executor.  Function services are listed from the cache of the
+// replica serving the request; evicting one deletes its Kubernetes objects,
+// which invalidates it on the other replicas too.
+
+func (executor *Executor) listFunctionServicesApi(w http.ResponseWriter, r *http.Request) {
+	fsvcs, err := executor.listFunctionServices(r.FormValue("namespace"), r.FormValue("function"))
+	if err != nil {
+		code, msg := fission.GetHTTPError(err)
+		http.Error(w, msg, code)
+		return
+	}
+	writeJSON(w, functionServiceInfos(fsvcs))
+}
+
+func (executor *Executor) evictFunctionApi(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	fsvcs, err := executor.evictFunction(vars["namespace"], vars["function"])
+	if err != nil {
+		code, msg := fission.GetHTTPError(err)
+		log.Printf("Error: %v: %v", code, msg)
+		http.Error(w, msg, code)
+		return
+	}
+	writeJSON(w, functionServiceInfos(fsvcs))
+}
+
+func (executor *Executor) specializeFunctionApi(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	fsvc, err := executor.specializeFunction(vars["namespace"], vars["function"])
+	if err != nil {
+		code, msg := fission.GetHTTPError(err)
+		log.Printf("Error: %v: %v", code, msg)
+		http.Error(w, msg, code)
+		return
+	}
+	writeJSON(w, functionServiceInfo(fsvc))
+}
+
+func (executor *Executor) functionStatusesApi(w http.ResponseWriter, r *http.Request) {
+	statuses, err := executor.functionStatuses(r.FormValue("namespace"), r.FormValue("function"))
+	if err != nil {
+		code, msg := fission.GetHTTPError(err)
+		http.Error(w, msg, code)
+		return
+	}
+	writeJSON(w, statuses)
+}
+
+func (executor *Executor) environmentStatusesApi(w http.ResponseWriter, r *http.Request) {
+	statuses, err := executor.environmentStatuses(r.FormValue("namespace"), r.FormValue("environment"))
+	if err != nil {
+		code, msg := fission.GetHTTPError(err)
+		http.Error(w, msg, code)
+		return
+	}
+	writeJSON(w, statuses)
+}
+
+func (executor *Executor) getPoolApi(w http.ResponseWriter, r *http.Request) {
+	pm, env, err := executor.getPoolManager(mux.Vars(r))
+	if err == nil {
+		var info *executorClient.PoolInfo
+		info, err = pm.GetPoolInfo(env)
+		if err == nil {
+			writeJSON(w, info)
+			return
+		}
+	}
+	code, msg := fission.GetHTTPError(err)
+	http.Error(w, msg, code)
+}
+
+func (executor *Executor) drainPoolApi(w http.ResponseWriter, r *http.Request) {
+	pm, env, err := executor.getPoolManager(mux.Vars(r))
+	if err == nil {
+		err = pm.DrainPool(env)
+	}
+	if err != nil {
+		code, msg := fission.GetHTTPError(err)
+		log.Printf("Error: %v: %v", code, msg)
+		http.Error(w, msg, code)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+func (executor *Executor) recreatePoolApi(w http.ResponseWriter, r *http.Request) {
+	pm, env, err := executor.getPoolManager(mux.Vars(r))
+	if err == nil {
+		err = pm.RecreatePool(env)
+	}
+	if err != nil {
+		code, msg := fission.GetHTTPError(err)
+		log.Printf("Error: %v: %v", code, msg)
+		http.Error(w, msg, code)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// listFunctionServices returns the cached function services, optionally
+// only those of a namespace, or of a function in a namespace.
+func (executor *Executor) listFunctionServices(namespace string, name string) ([]*fscache.FuncSvc, error) {
+	fsvcs, err := executor.fsCache.List()
+	if err != nil {
+		return nil, err
+	}
+	matching := make([]*fscache.FuncSvc, 0, len(fsvcs))
+	for _, fsvc := range fsvcs {
+		if len(namespace) > 0 && fsvc.Function.Namespace != namespace {
+			continue
+		}
+		if len(name) > 0 && fsvc.Function.Name != name {
+			continue
+		}
+		matching = append(matching, fsvc)
+	}
+	return matching, nil
+}
+
+// evictFunction has the backends evict all cached services of the
+// function, of any version, and returns them.
+func (executor *Executor) evictFunction(namespace string, name string) ([]*fscache.FuncSvc, error) {
+	fsvcs, err := executor.listFunctionServices(namespace, name)
+	if err != nil {
+		return nil, err
+	}
+	if len(fsvcs) == 0 {
+		return nil, fission.MakeError(fission.ErrorNotFound,
+			fmt.Sprintf("no cached function services for function %v", name))
+	}
+	for _, fsvc := range fsvcs {
+		backend, err := executor.getBackend(fsvc.Executor)
+		if err != nil {
+			return nil, err
+		}
+		log.Printf("[%v] Evicting function service at %v", name, fsvc.Address)
+		err = backend.Evict(fsvc)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return fsvcs, nil
+}
+
+// specializeFunction has the function's backend create a service for the
+// current version of the function, whether or not one is cached.  For
+// poolmgr functions that's another instance specialized from the pool.
+func (executor *Executor) specializeFunction(namespace string, name string) (*fscache.FuncSvc, error) {
+	fn, err := executor.fissionClient.Functions(namespace).Get(name)
+	if err != nil {
+		return nil, err
+	}
+	return executor.createServiceForFunction(&fn.Metadata)
+}
+
+// getPoolManager returns the backend managing pools, and the environment
+// named by the request.
+func (executor *Executor) getPoolManager(vars map[string]string) (PoolManager, *crd.Environment, error) {
+	backend, err := executor.getBackend(fission.ExecutorTypePoolmgr)
+	if err != nil {
+		return nil, nil, err
+	}
+	pm, ok := backend.(PoolManager)
+	if !ok {
+		return nil, nil, fission.MakeError(fission.ErrorNotImplmented, "no executor type manages pools")
+	}
+	env, err := executor.fissionClient.Environments(vars["namespace"]).Get(vars["environment"])
+	if err != nil {
+		return nil, nil, err
+	}
+	return pm, env, nil
+}
+
+func functionServiceInfo(fsvc *fscache.FuncSvc) *executorClient.FunctionServiceInfo {
+	info := &executorClient.FunctionServiceInfo{
+		Function:          *fsvc.Function,
+		Executor:          fsvc.Executor,
+		Address:           fsvc.Address,
+		KubernetesObjects: fsvc.KubernetesObjects,
+		Ctime:             fsvc.Ctime,
+		Atime:             fsvc.Atime,
+		Concurrency:       fsvc.Concurrency,
+		Latency:           fsvc.Latency,
+	}
+	if fsvc.Environment != nil {
+		info.Environment = metav1.ObjectMeta{
+			Name:      fsvc.Environment.Metadata.Name,
+			Namespace: fsvc.Environment.Metadata.Namespace,
+			UID:       fsvc.Environment.Metadata.UID,
+		}
+	}
+	return info
+}
+
+func functionServiceInfos(fsvcs []*fscache.FuncSvc) []*executorClient.FunctionServiceInfo {
+	infos := make([]*executorClient.FunctionServiceInfo, 0, len(fsvcs))
+	for _, fsvc := range fsvcs {
+		infos = append(infos, functionServiceInfo(fsvc))
+	}
+	return infos
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	resp, err := json.Marshal(v)
+	if err != nil {
+		http.Error(w, "Failed to encode response", 500)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(resp)
+}
@@ -0,0 +1,57 @@
+package executor
+
+import (
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/fission/fission"
+	"github.com/fission/fission/executor/fscache"
+)
+
+func TestEvictFunction(t *testing.T) {
+	poolmgr := &fakeBackend{}
+	newdeploy := &fakeBackend{}
+	fsCache := fscache.MakeFunctionServiceCache()
+	executor := MakeExecutor(map[fission.ExecutorType]ExecutorBackend{
+		fission.ExecutorTypePoolmgr:   poolmgr,
+		fission.ExecutorTypeNewdeploy: newdeploy,
+	}, nil, nil, fsCache, nil)
+
+	foo := &metav1.ObjectMeta{Name: "foo", Namespace: "default", UID: types.UID("1"), ResourceVersion: "1"}
+	bar := &metav1.ObjectMeta{Name: "bar", Namespace: "default", UID: types.UID("2"), ResourceVersion: "1"}
+	for _, fsvc := range []fscache.FuncSvc{
+		{Name: "foo-1", Function: foo, Address: "10.0.0.1:8888", Executor: fscache.POOLMGR},
+		{Name: "foo-2", Function: foo, Address: "10.0.0.2:8888", Executor: fscache.POOLMGR},
+		{Name: "bar", Function: bar, Address: "bar.fission-function", Executor: fscache.NEWDEPLOY},
+	} {
+		_, err := fsCache.Add(fsvc)
+		if err != nil {
+			t.Fatalf("error adding function service: %v", err)
+		}
+	}
+
+	fsvcs, err := executor.listFunctionServices("default", "foo")
+	if err != nil || len(fsvcs) != 2 {
+		t.Fatalf("expected 2 function services of foo, got %v (%v)", len(fsvcs), err)
+	}
+	fsvcs, err = executor.listFunctionServices("other", "")
+	if err != nil || len(fsvcs) != 0 {
+		t.Errorf("expected no function services in namespace other, got %v (%v)", len(fsvcs), err)
+	}
+
+	// every instance goes to the backend of the function
+	fsvcs, err = executor.evictFunction("default", "foo")
+	if err != nil || len(fsvcs) != 2 {
+		t.Fatalf("expected 2 evicted function services, got %v (%v)", len(fsvcs), err)
+	}
+	if len(poolmgr.evicted) != 2 || len(newdeploy.evicted) != 0 {
+		t.Errorf("expected poolmgr to evict foo's instances, got %v and %v", poolmgr.evicted, newdeploy.evicted)
+	}
+
+	_, err = executor.evictFunction("default", "baz")
+	if err == nil {
+		t.Errorf("expected an error evicting a function without services")
+	}
+}
@@ -26,9 +26,13 @@ import (
 	"strings"
 
 	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	"github.com/fission/fission"
+	executorClient "github.com/fission/fission/executor/client"
+	"github.com/fission/fission/executor/fscache"
+	"github.com/fission/fission/executor/jobmgr"
 )
 
 func (executor *Executor) getServiceForFunctionApi(w http.ResponseWriter, r *http.Request) {
@@ -46,7 +50,7 @@ func (executor *Executor) getServiceForFunctionApi(w http.ResponseWriter, r *htt
 		return
 	}
 
-	serviceName, err := executor.getServiceForFunction(&m)
+	addresses, err := executor.getServicesForFunction(&m)
 	if err != nil {
 		code, msg := fission.GetHTTPError(err)
 		log.Printf("Error: %v: %v", code, msg)
@@ -54,32 +58,78 @@ func (executor *Executor) getServiceForFunctionApi(w http.ResponseWriter, r *htt
 		return
 	}
 
-	w.Write([]byte(serviceName))
+	w.Write([]byte(addresses[0]))
 }
 
-// getServiceForFunction first checks if this function's service is cached, if yes, it validates the address.
-// if it's a valid address, just returns it.
-// else, invalidates its cache entry and makes a new request to create a service for this function and finally responds
-// with new address or error.
+func (executor *Executor) getServicesForFunctionApi(w http.ResponseWriter, r *http.Request) {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, "Failed to read request", 500)
+		return
+	}
+
+	// get function metadata
+	m := metav1.ObjectMeta{}
+	err = json.Unmarshal(body, &m)
+	if err != nil {
+		http.Error(w, "Failed to parse request", 400)
+		return
+	}
+
+	addresses, err := executor.getServicesForFunction(&m)
+	if err != nil {
+		code, msg := fission.GetHTTPError(err)
+		log.Printf("Error: %v: %v", code, msg)
+		http.Error(w, msg, code)
+		return
+	}
+
+	resp, err := json.Marshal(addresses)
+	if err != nil {
+		http.Error(w, "Failed to encode response", 500)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(resp)
+}
+
+// getServicesForFunction first checks if this function's service instances are cached, if yes, it validates their
+// addresses, invalidating the cache entries of invalid ones, and returns the valid addresses.
+// if none is valid, it makes a new request to create a service for this function and finally responds with the new
+// address or error.
 //
-// checking for the validity of the address causes a little more over-head than desired. but, it ensures that
-// stale addresses are not returned to the router.
-// To make it optimal, plan is to add an eager cache invalidator function that watches for pod deletion events and
-// invalidates the cache entry if the pod address was cached.
-func (executor *Executor) getServiceForFunction(m *metav1.ObjectMeta) (string, error) {
+// checking for the validity of the address ensures that stale addresses are not returned to the router. For poolmgr
+// functions it's a lookup in the pod informer, which also removes cache entries as soon as their pod stops being
+// ready.
+func (executor *Executor) getServicesForFunction(m *metav1.ObjectMeta) ([]string, error) {
 	// Check function -> svc cache
 	log.Printf("[%v] Checking for cached function service", m.Name)
-	fsvc, err := executor.fsCache.GetByFunction(m)
+	fsvcs, err := executor.fsCache.ListByFunction(m)
 	if err == nil {
-		if executor.isValidAddress(fsvc) {
-			// Cached, return svc address
-			return fsvc.Address, nil
-		} else {
-			log.Printf("[%v] Deleting cache entry for invalid address : %s", m.Name, fsvc.Address)
-			executor.fsCache.DeleteEntry(fsvc)
+		addresses := make([]string, 0, len(fsvcs))
+		for _, fsvc := range fsvcs {
+			if executor.isValidAddress(fsvc) {
+				addresses = append(addresses, fsvc.Address)
+			} else {
+				log.Printf("[%v] Deleting cache entry for invalid address : %s", m.Name, fsvc.Address)
+				executor.fsCache.DeleteEntry(fsvc)
+			}
+		}
+		if len(addresses) > 0 {
+			// Cached, return svc addresses
+			funcSvcCacheRequests.WithLabelValues("hit").Inc()
+			return addresses, nil
 		}
 	}
 
+	funcSvcCacheRequests.WithLabelValues("miss").Inc()
+
+	// don't retry a version of the function that keeps failing until
+	// its backoff is over
+	if err := executor.backoffError(m); err != nil {
+		return nil, err
+	}
+
 	respChan := make(chan *createFuncServiceResponse)
 	executor.requestChan <- &createFuncServiceRequest{
 		funcMeta: m,
@@ -87,20 +137,32 @@ func (executor *Executor) getServiceForFunction(m *metav1.ObjectMeta) (string, e
 	}
 	resp := <-respChan
 	if resp.err != nil {
-		return "", resp.err
+		return nil, resp.err
 	}
-	return resp.funcSvc.Address, resp.err
+	return []string{resp.funcSvc.Address}, nil
 }
 
-// find funcSvc and update its atime
+// find funcSvc and update its atime, and record the load the router saw
+// on it, if reported.
 func (executor *Executor) tapService(w http.ResponseWriter, r *http.Request) {
 	body, err := ioutil.ReadAll(r.Body)
 	if err != nil {
 		http.Error(w, "Failed to read request", 500)
 		return
 	}
-	svcName := string(body)
-	svcHost := strings.TrimPrefix(svcName, "http://")
+
+	tapReq := &executorClient.TapServiceRequest{}
+	if r.Header.Get("Content-Type") == "application/json" {
+		err = json.Unmarshal(body, tapReq)
+		if err != nil {
+			http.Error(w, "Failed to parse request", 400)
+			return
+		}
+	} else {
+		// plain service url, from older routers
+		tapReq.ServiceUrl = string(body)
+	}
+	svcHost := strings.TrimPrefix(tapReq.ServiceUrl, "http://")
 
 	err = executor.fsCache.TouchByAddress(svcHost)
 	if err != nil {
@@ -108,7 +170,123 @@ func (executor *Executor) tapService(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Not found", 404)
 		return
 	}
+
+	if tapReq.Concurrency > 0 {
+		err = executor.fsCache.RecordLoad(svcHost, tapReq.Router, tapReq.Concurrency, tapReq.Requests, tapReq.Latency)
+		if err == nil {
+			go executor.scaleFunction(svcHost)
+		}
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// functionServiceEventsApi streams function service changes to the caller as
+// newline-delimited JSON FuncSvcEvents. The stream starts with an ADDED event
+// for every currently cached function service and a SYNCED event, followed
+// by ADDED and DELETED events as the cache changes. The stream is closed if
+// the caller falls behind. The router uses it to pre-fill and invalidate
+// its own cache.
+func (executor *Executor) functionServiceEventsApi(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming not supported", 500)
+		return
+	}
+
+	// subscribe before listing, so that no change is missed between the two
+	events, unsubscribe := executor.fsCache.Subscribe()
+	defer unsubscribe()
+
+	fsvcs, err := executor.fsCache.List()
+	if err != nil {
+		http.Error(w, "Failed to list function services", 500)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
+
+	encoder := json.NewEncoder(w)
+	for _, fsvc := range fsvcs {
+		err = encoder.Encode(&fscache.FuncSvcEvent{
+			Type:     fscache.FuncSvcAdded,
+			Function: *fsvc.Function,
+			Address:  fsvc.Address,
+		})
+		if err != nil {
+			return
+		}
+	}
+	err = encoder.Encode(&fscache.FuncSvcEvent{Type: fscache.FuncSvcSynced})
+	if err != nil {
+		return
+	}
+	flusher.Flush()
+
+	for {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				// we fell behind; the caller reconnects and resyncs
+				return
+			}
+			err = encoder.Encode(event)
+			if err != nil {
+				log.Printf("Error streaming function service event: %v", err)
+				return
+			}
+			flusher.Flush()
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// invokeJobApi starts an invocation of a job function and responds with its
+// record, which includes the id to look up its outcome with.
+func (executor *Executor) invokeJobApi(w http.ResponseWriter, r *http.Request) {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, "Failed to read request", 500)
+		return
+	}
+
+	req := &jobmgr.InvocationRequest{}
+	err = json.Unmarshal(body, req)
+	if err != nil {
+		http.Error(w, "Failed to parse request", 400)
+		return
+	}
+
+	inv, err := executor.jobm.Invoke(req)
+	if err != nil {
+		code, msg := fission.GetHTTPError(err)
+		log.Printf("Error: %v: %v", code, msg)
+		http.Error(w, msg, code)
+		return
+	}
+	executor.writeInvocation(w, inv)
+}
+
+func (executor *Executor) getInvocationApi(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	inv, err := executor.jobm.GetInvocation(id)
+	if err != nil {
+		code, msg := fission.GetHTTPError(err)
+		http.Error(w, msg, code)
+		return
+	}
+	executor.writeInvocation(w, inv)
+}
+
+func (executor *Executor) writeInvocation(w http.ResponseWriter, inv *jobmgr.Invocation) {
+	resp, err := json.Marshal(inv)
+	if err != nil {
+		http.Error(w, "Failed to encode response", 500)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(resp)
 }
 
 func (executor *Executor) healthHandler(w http.ResponseWriter, r *http.Request) {
@@ -118,14 +296,28 @@ func (executor *Executor) healthHandler(w http.ResponseWriter, r *http.Request)
 func (executor *Executor) Serve(port int) {
 	r := mux.NewRouter()
 	r.HandleFunc("/v2/getServiceForFunction", executor.getServiceForFunctionApi).Methods("POST")
+	r.HandleFunc("/v2/getServicesForFunction", executor.getServicesForFunctionApi).Methods("POST")
 	r.HandleFunc("/v2/tapService", executor.tapService).Methods("POST")
+	r.HandleFunc("/v2/functionServiceEvents", executor.functionServiceEventsApi).Methods("GET")
+	r.HandleFunc("/v2/jobs", executor.invokeJobApi).Methods("POST")
+	r.HandleFunc("/v2/jobs/{id}", executor.getInvocationApi).Methods("GET")
+	r.HandleFunc("/v2/admin/functionServices", executor.listFunctionServicesApi).Methods("GET")
+	r.HandleFunc("/v2/admin/functions/{namespace}/{function}/services", executor.evictFunctionApi).Methods("DELETE")
+	r.HandleFunc("/v2/admin/functions/{namespace}/{function}/services", executor.specializeFunctionApi).Methods("POST")
+	r.HandleFunc("/v2/admin/functionStatuses", executor.functionStatusesApi).Methods("GET")
+	r.HandleFunc("/v2/admin/environmentStatuses", executor.environmentStatusesApi).Methods("GET")
+	r.HandleFunc("/v2/admin/pools/{namespace}/{environment}", executor.getPoolApi).Methods("GET")
+	r.HandleFunc("/v2/admin/pools/{namespace}/{environment}/drain", executor.drainPoolApi).Methods("POST")
+	r.HandleFunc("/v2/admin/pools/{namespace}/{environment}/recreate", executor.recreatePoolApi).Methods("POST")
 	r.HandleFunc("/healthz", executor.healthHandler).Methods("GET")
+	r.Handle("/metrics", promhttp.Handler()).Methods("GET")
 	address := fmt.Sprintf(":%v", port)
 	log.Printf("starting executor at port %v", port)
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
-	executor.ndm.Run(ctx)
-	executor.gpm.Run(ctx)
+	for _, backend := range executor.backends {
+		backend.Run(ctx)
+	}
 	r.Use(fission.LoggingMiddleware)
 	log.Fatal(http.ListenAndServe(address, r))
 }
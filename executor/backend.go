@@ -0,0 +1,111 @@
+/*
+Copyright 2018 The Fission Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package executor
+
+import (
+	"context"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/fission/fission"
+	"github.com/fission/fission/crd"
+	executorClient "github.com/fission/fission/executor/client"
+	"github.com/fission/fission/executor/fscache"
+)
+
+type (
+	// ExecutorBackend is an execution strategy for functions.  It creates
+	// the services that serve a function's requests and manages the
+	// Kubernetes objects behind them.  Backends are registered with the
+	// executor under the ExecutorType that functions select them with.
+	ExecutorBackend interface {
+		// Run starts the backend's informers and controllers on every
+		// executor replica; they stop when ctx is done.
+		Run(ctx context.Context)
+
+		// StartLeading is called once this executor replica is elected
+		// leader.  Only the leader manages the Kubernetes objects shared
+		// by all replicas.
+		StartLeading()
+
+		// GetFuncSvc returns a service for the function, creating one if
+		// needed.  It's called when the function service cache has no
+		// valid service for the function; m is the metadata the function
+		// was requested with.
+		GetFuncSvc(m *metav1.ObjectMeta, fn *crd.Function, env *crd.Environment) (*fscache.FuncSvc, error)
+
+		// IsValid returns true if a cached function service of this
+		// backend can still serve requests.
+		IsValid(fsvc *fscache.FuncSvc) bool
+
+		// CleanupOldObjects deletes the Kubernetes objects of the backend
+		// that previous executor runs left behind, sparing the ones it
+		// adopted at startup and any object a replica made or
+		// specialized since startTime.  It's called once, on the leader
+		// replica.
+		CleanupOldObjects(startTime time.Time)
+
+		// ReapIdle deletes the function services that have been idle
+		// for longer than their idle timeout, and returns how many it
+		// deleted.  It's called periodically on the leader replica.
+		ReapIdle() int
+
+		// Evict removes a function service from the cache and deletes
+		// the Kubernetes objects behind it, so that the function's next
+		// request gets a new one.
+		Evict(fsvc *fscache.FuncSvc) error
+	}
+
+	// FunctionScaler is implemented by backends that add instances of a
+	// function's service when the router reports a high load on them.
+	FunctionScaler interface {
+		ScaleFunction(fsvc *fscache.FuncSvc)
+	}
+
+	// PoolManager is implemented by backends that keep pools of generic
+	// pods for environments.
+	PoolManager interface {
+		// GetPoolInfo describes the pool of the environment, creating
+		// the pool if needed.
+		GetPoolInfo(env *crd.Environment) (*executorClient.PoolInfo, error)
+
+		// DrainPool deletes the idle pods of the environment's pool;
+		// the pool replaces them with new ones.
+		DrainPool(env *crd.Environment) error
+
+		// RecreatePool replaces all idle pods of the environment's pool
+		// with new ones, in a rolling update of its deployment.
+		RecreatePool(env *crd.Environment) error
+	}
+
+	// FunctionStatusReporter is implemented by backends that can tell
+	// the state of a function's instances.
+	FunctionStatusReporter interface {
+		// FunctionStatus sets the condition and ready replicas of the
+		// instances of the function's current version on status.
+		FunctionStatus(fn *crd.Function, env *crd.Environment, status *fission.FunctionStatus)
+	}
+
+	// EnvironmentStatusReporter is implemented by backends that run
+	// pods of environments.
+	EnvironmentStatusReporter interface {
+		// EnvironmentStatus sets the phase of the rollout of the
+		// environment's current version on status.
+		EnvironmentStatus(env *crd.Environment, status *fission.EnvironmentStatus)
+	}
+)
@@ -0,0 +1,102 @@
+package executor
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/fission/fission"
+	"github.com/fission/fission/crd"
+	"github.com/fission/fission/executor/fscache"
+)
+
+// fakeBackend is an ExecutorBackend whose services are valid if they're
+// listed in valid.
+type fakeBackend struct {
+	valid    map[string]bool
+	validate int
+	evicted  []string
+}
+
+func (b *fakeBackend) Run(ctx context.Context) {}
+func (b *fakeBackend) StartLeading()           {}
+func (b *fakeBackend) GetFuncSvc(m *metav1.ObjectMeta, fn *crd.Function, env *crd.Environment) (*fscache.FuncSvc, error) {
+	return nil, fission.MakeError(fission.ErrorNotImplmented, "not implemented")
+}
+func (b *fakeBackend) IsValid(fsvc *fscache.FuncSvc) bool {
+	b.validate++
+	return b.valid[fsvc.Address]
+}
+func (b *fakeBackend) CleanupOldObjects(startTime time.Time) {}
+func (b *fakeBackend) ReapIdle() int                         { return 0 }
+func (b *fakeBackend) Evict(fsvc *fscache.FuncSvc) error {
+	b.evicted = append(b.evicted, fsvc.Address)
+	return nil
+}
+
+// fakeScaler is a fakeBackend that also scales functions.
+type fakeScaler struct {
+	fakeBackend
+	scaled chan string
+}
+
+func (b *fakeScaler) ScaleFunction(fsvc *fscache.FuncSvc) {
+	b.scaled <- fsvc.Address
+}
+
+func TestExecutorBackends(t *testing.T) {
+	poolmgr := &fakeScaler{
+		fakeBackend: fakeBackend{valid: map[string]bool{"10.0.0.1:8888": true}},
+		scaled:      make(chan string, 1),
+	}
+	newdeploy := &fakeBackend{valid: map[string]bool{}}
+	fsCache := fscache.MakeFunctionServiceCache()
+	executor := MakeExecutor(map[fission.ExecutorType]ExecutorBackend{
+		fission.ExecutorTypePoolmgr:   poolmgr,
+		fission.ExecutorTypeNewdeploy: newdeploy,
+	}, nil, nil, fsCache, nil)
+
+	// functions without an executor type use poolmgr
+	backend, err := executor.getBackend("")
+	if err != nil || backend != poolmgr {
+		t.Errorf("expected the poolmgr backend for an empty executor type")
+	}
+	_, err = executor.getBackend("unknown")
+	if err == nil {
+		t.Errorf("expected an error for an unknown executor type")
+	}
+
+	// validation goes to the backend of the function service
+	fsvc := &fscache.FuncSvc{Address: "10.0.0.1:8888", Executor: fscache.POOLMGR}
+	if !executor.isValidAddress(fsvc) || poolmgr.validate != 1 || newdeploy.validate != 0 {
+		t.Errorf("expected poolmgr to validate its function service")
+	}
+	fsvc = &fscache.FuncSvc{Address: "svc.ns", Executor: fscache.NEWDEPLOY}
+	if executor.isValidAddress(fsvc) || newdeploy.validate != 1 {
+		t.Errorf("expected newdeploy to invalidate its function service")
+	}
+
+	// only backends that scale functions are asked to
+	m := &metav1.ObjectMeta{Name: "foo", Namespace: "default", UID: types.UID("1"), ResourceVersion: "1"}
+	_, err = fsCache.Add(fscache.FuncSvc{
+		Name:     "foo",
+		Function: m,
+		Address:  "10.0.0.1:8888",
+		Executor: fscache.POOLMGR,
+	})
+	if err != nil {
+		t.Fatalf("error adding function service: %v", err)
+	}
+	executor.scaleFunction("10.0.0.1:8888")
+	select {
+	case address := <-poolmgr.scaled:
+		if address != "10.0.0.1:8888" {
+			t.Errorf("expected the function service at 10.0.0.1:8888 to be scaled, got %v", address)
+		}
+	default:
+		t.Errorf("expected poolmgr to scale the function")
+	}
+}
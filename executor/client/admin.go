@@ -0,0 +1,69 @@
+/*
+Copyright 2018 The Fission Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/pkg/api"
+
+	"github.com/fission/fission"
+)
+
+// Types of the executor's admin API, served under /v2/admin.  The
+// controller proxies it for the CLI at /proxy/executor/v2/admin.
+type (
+	// FunctionServiceInfo describes an instance of a function's service
+	// in the function service cache of the executor replica that served
+	// the request.
+	FunctionServiceInfo struct {
+		Function          metav1.ObjectMeta     `json:"function"`
+		Environment       metav1.ObjectMeta     `json:"environment"`
+		Executor          fission.ExecutorType  `json:"executor"`
+		Address           string                `json:"address"`
+		KubernetesObjects []api.ObjectReference `json:"kubernetesObjects"`
+		Ctime             time.Time             `json:"ctime"`
+		Atime             time.Time             `json:"atime"`
+		Concurrency       int                   `json:"concurrency"` // concurrent requests last reported by the router
+		Latency           time.Duration         `json:"latency"`     // mean request latency last reported by the router
+	}
+
+	// FunctionStatusInfo is the status of a function, as reported by the
+	// executor replica that served the request.
+	FunctionStatusInfo struct {
+		Function metav1.ObjectMeta      `json:"function"`
+		Status   fission.FunctionStatus `json:"status"`
+	}
+
+	// EnvironmentStatusInfo is the status of an environment, as reported
+	// by the executor replica that served the request.
+	EnvironmentStatusInfo struct {
+		Environment metav1.ObjectMeta         `json:"environment"`
+		Status      fission.EnvironmentStatus `json:"status"`
+	}
+
+	// PoolInfo describes the pool of generic pods of an environment.
+	PoolInfo struct {
+		Environment metav1.ObjectMeta `json:"environment"`
+		Deployment  string            `json:"deployment"`
+		Replicas    int32             `json:"replicas"`    // idle pods the pool is sized for
+		Pods        int               `json:"pods"`        // idle pods
+		ReadyPods   int               `json:"readyPods"`   // idle pods ready to be specialized
+		Specialized int               `json:"specialized"` // cached function services specialized from the pool
+	}
+)
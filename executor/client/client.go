@@ -18,30 +18,64 @@ package client
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"io/ioutil"
 	"log"
 	"net/http"
 	"net/url"
+	"os"
 	"strings"
 	"time"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	"github.com/fission/fission"
+	"github.com/fission/fission/executor/fscache"
+	"github.com/fission/fission/executor/jobmgr"
 )
 
-type Client struct {
-	executorUrl string
-	tappedByUrl map[string]bool
-	requestChan chan string
-}
+type (
+	Client struct {
+		executorUrl string
+		hostname    string // identifies this router in its load reports
+		tappedByUrl map[string]*tapStats
+		requestChan chan *tapRequest
+	}
+
+	// TapServiceRequest reports that a function service was used, along
+	// with the load the router saw on it since its last report.
+	TapServiceRequest struct {
+		ServiceUrl  string        `json:"serviceUrl"`
+		Concurrency int           `json:"concurrency"` // most requests in flight at once
+		Requests    int           `json:"requests"`    // requests since the last report
+		Latency     time.Duration `json:"latency"`     // mean request latency
+		// the reporting router; the load of every router adds up
+		Router string `json:"router,omitempty"`
+	}
+
+	tapRequest struct {
+		serviceUrl  string
+		concurrency int
+		latency     time.Duration
+	}
+	tapStats struct {
+		concurrency  int
+		requests     int
+		totalLatency time.Duration
+	}
+)
 
 func MakeClient(executorUrl string) *Client {
+	hostname, err := os.Hostname()
+	if err != nil {
+		log.Printf("Error getting hostname to report load as: %v", err)
+	}
 	c := &Client{
 		executorUrl: strings.TrimSuffix(executorUrl, "/"),
-		tappedByUrl: make(map[string]bool),
-		requestChan: make(chan string),
+		hostname:    hostname,
+		tappedByUrl: make(map[string]*tapStats),
+		requestChan: make(chan *tapRequest),
 	}
 	go c.service()
 	return c
@@ -73,36 +107,171 @@ func (c *Client) GetServiceForFunction(metadata *metav1.ObjectMeta) (string, err
 	return string(svcName), nil
 }
 
+// GetServicesForFunction returns the addresses of every instance of the
+// function's service, specializing one if there's none.
+func (c *Client) GetServicesForFunction(metadata *metav1.ObjectMeta) ([]string, error) {
+	executorUrl := c.executorUrl + "/v2/getServicesForFunction"
+
+	body, err := json.Marshal(metadata)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := http.Post(executorUrl, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		return nil, fission.MakeErrorFromHTTP(resp)
+	}
+
+	var addresses []string
+	err = json.NewDecoder(resp.Body).Decode(&addresses)
+	if err != nil {
+		return nil, err
+	}
+	return addresses, nil
+}
+
+// InvokeJob starts an invocation of a job function, and returns its record
+// without waiting for it to finish.
+func (c *Client) InvokeJob(req *jobmgr.InvocationRequest) (*jobmgr.Invocation, error) {
+	executorUrl := c.executorUrl + "/v2/jobs"
+
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := http.Post(executorUrl, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		return nil, fission.MakeErrorFromHTTP(resp)
+	}
+
+	inv := &jobmgr.Invocation{}
+	err = json.NewDecoder(resp.Body).Decode(inv)
+	if err != nil {
+		return nil, err
+	}
+	return inv, nil
+}
+
+// GetInvocation returns the record of an invocation of a job function.
+func (c *Client) GetInvocation(id string) (*jobmgr.Invocation, error) {
+	executorUrl := c.executorUrl + "/v2/jobs/" + url.PathEscape(id)
+
+	resp, err := http.Get(executorUrl)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		return nil, fission.MakeErrorFromHTTP(resp)
+	}
+
+	inv := &jobmgr.Invocation{}
+	err = json.NewDecoder(resp.Body).Decode(inv)
+	if err != nil {
+		return nil, err
+	}
+	return inv, nil
+}
+
+// WatchFunctionServices connects to the executor's function service event
+// stream and calls handler for every event received. It blocks until the
+// stream ends, an error occurs or ctx is cancelled.
+func (c *Client) WatchFunctionServices(ctx context.Context, handler func(*fscache.FuncSvcEvent)) error {
+	executorUrl := c.executorUrl + "/v2/functionServiceEvents"
+
+	req, err := http.NewRequest("GET", executorUrl, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := http.DefaultClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		return fission.MakeErrorFromHTTP(resp)
+	}
+
+	decoder := json.NewDecoder(resp.Body)
+	for {
+		event := &fscache.FuncSvcEvent{}
+		err = decoder.Decode(event)
+		if err != nil {
+			return err
+		}
+		handler(event)
+	}
+}
+
 func (c *Client) service() {
 	ticker := time.NewTicker(time.Second * 5)
 	for {
 		select {
-		case serviceUrl := <-c.requestChan:
-			c.tappedByUrl[serviceUrl] = true
+		case req := <-c.requestChan:
+			stats, ok := c.tappedByUrl[req.serviceUrl]
+			if !ok {
+				stats = &tapStats{}
+				c.tappedByUrl[req.serviceUrl] = stats
+			}
+			if req.concurrency > stats.concurrency {
+				stats.concurrency = req.concurrency
+			}
+			stats.requests++
+			stats.totalLatency += req.latency
 		case <-ticker.C:
 			urls := c.tappedByUrl
-			c.tappedByUrl = make(map[string]bool)
+			c.tappedByUrl = make(map[string]*tapStats)
 			if len(urls) > 0 {
 				go func() {
-					for u := range c.tappedByUrl {
-						c._tapService(u)
+					for u, stats := range urls {
+						c._tapService(&TapServiceRequest{
+							ServiceUrl:  u,
+							Router:      c.hostname,
+							Concurrency: stats.concurrency,
+							Requests:    stats.requests,
+							Latency:     stats.totalLatency / time.Duration(stats.requests),
+						})
 					}
 					log.Printf("Tapped %v services in batch", len(urls))
 				}()
-				log.Printf("Tapped %v services in batch", len(urls))
 			}
 		}
 	}
 }
 
-func (c *Client) TapService(serviceUrl *url.URL) {
-	c.requestChan <- serviceUrl.String()
+// TapService records a request to a function service, which was one of
+// concurrency requests in flight to it and took latency to respond.
+func (c *Client) TapService(serviceUrl *url.URL, concurrency int, latency time.Duration) {
+	c.requestChan <- &tapRequest{
+		serviceUrl:  serviceUrl.String(),
+		concurrency: concurrency,
+		latency:     latency,
+	}
 }
 
-func (c *Client) _tapService(serviceUrlStr string) error {
+func (c *Client) _tapService(tapReq *TapServiceRequest) error {
 	executorUrl := c.executorUrl + "/v2/tapService"
 
-	resp, err := http.Post(executorUrl, "application/octet-stream", bytes.NewReader([]byte(serviceUrlStr)))
+	body, err := json.Marshal(tapReq)
+	if err != nil {
+		return err
+	}
+
+	resp, err := http.Post(executorUrl, "application/json", bytes.NewReader(body))
 	if err != nil {
 		return err
 	}
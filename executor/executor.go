@@ -17,33 +17,39 @@ limitations under the License.
 package executor
 
 import (
+	"fmt"
 	"log"
+	"os"
 	"runtime/debug"
-	"strings"
 	"sync"
 	"time"
 
-	"github.com/dchest/uniuri"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 
 	"github.com/fission/fission"
 	"github.com/fission/fission/cache"
 	"github.com/fission/fission/crd"
 	"github.com/fission/fission/executor/fscache"
+	"github.com/fission/fission/executor/jobmgr"
 	"github.com/fission/fission/executor/newdeploy"
 	"github.com/fission/fission/executor/poolmgr"
 )
 
 type (
 	Executor struct {
-		gpm           *poolmgr.GenericPoolManager
-		ndm           *newdeploy.NewDeploy
+		backends      map[fission.ExecutorType]ExecutorBackend
+		jobm          *jobmgr.JobManager
 		functionEnv   *cache.Cache
 		fissionClient *crd.FissionClient
 		fsCache       *fscache.FunctionServiceCache
 
 		requestChan chan *createFuncServiceRequest
 		fsCreateWg  map[string]*sync.WaitGroup
+
+		failureLock   sync.Mutex
+		failures      map[types.UID]*functionFailure // last failure to specialize, by function
+		statusRecords *statusRecords                 // nil if function statuses aren't recorded
 	}
 	createFuncServiceRequest struct {
 		funcMeta *metav1.ObjectMeta
@@ -56,16 +62,22 @@ type (
 	}
 )
 
-func MakeExecutor(gpm *poolmgr.GenericPoolManager, ndm *newdeploy.NewDeploy, fissionClient *crd.FissionClient, fsCache *fscache.FunctionServiceCache) *Executor {
+// MakeExecutor returns an executor serving functions through the given
+// backends, by executor type; jobm serves the invocations of job functions.
+// Function statuses are kept in records if records isn't nil.
+func MakeExecutor(backends map[fission.ExecutorType]ExecutorBackend, jobm *jobmgr.JobManager, fissionClient *crd.FissionClient, fsCache *fscache.FunctionServiceCache, records *statusRecords) *Executor {
 	executor := &Executor{
-		gpm:           gpm,
-		ndm:           ndm,
+		backends:      backends,
+		jobm:          jobm,
 		functionEnv:   cache.MakeCache(10*time.Second, 0),
 		fissionClient: fissionClient,
 		fsCache:       fsCache,
 
 		requestChan: make(chan *createFuncServiceRequest),
 		fsCreateWg:  make(map[string]*sync.WaitGroup),
+		failures:    make(map[types.UID]*functionFailure),
+
+		statusRecords: records,
 	}
 	go executor.serveCreateFuncServices()
 	return executor
@@ -95,6 +107,7 @@ func (executor *Executor) serveCreateFuncServices() {
 			// the specialization of different functions
 			go func() {
 				fsvc, err := executor.createServiceForFunction(m)
+				err = executor.recordFailure(m, err)
 				req.respChan <- &createFuncServiceResponse{
 					funcSvc: fsvc,
 					err:     err,
@@ -106,10 +119,18 @@ func (executor *Executor) serveCreateFuncServices() {
 			// There's an existing request for this function, wait for it to finish
 			go func() {
 				log.Printf("Waiting for concurrent request for the same function: %v", m)
+				funcSvcCreateWaiters.Inc()
 				wg.Wait()
+				funcSvcCreateWaiters.Dec()
 
-				// get the function service from the cache
+				// get the function service from the cache, or
+				// fail like the request waited on
 				fsvc, err := executor.fsCache.GetByFunction(m)
+				if err != nil {
+					if ferr := executor.backoffError(m); ferr != nil {
+						err = ferr
+					}
+				}
 				req.respChan <- &createFuncServiceResponse{
 					funcSvc: fsvc,
 					err:     err,
@@ -119,15 +140,6 @@ func (executor *Executor) serveCreateFuncServices() {
 	}
 }
 
-func (executor *Executor) getFunctionExecutorType(meta *metav1.ObjectMeta) (fission.ExecutorType, error) {
-	fn, err := executor.fissionClient.Functions(meta.Namespace).Get(meta.Name)
-	if err != nil {
-		return "", err
-	}
-	return fn.Spec.InvokeStrategy.ExecutionStrategy.ExecutorType, nil
-
-}
-
 func (executor *Executor) createServiceForFunction(meta *metav1.ObjectMeta) (*fscache.FuncSvc, error) {
 	log.Printf("[%v] No cached function service found, creating one", meta.Name)
 
@@ -138,25 +150,45 @@ func (executor *Executor) createServiceForFunction(meta *metav1.ObjectMeta) (*fs
 		return nil, err
 	}
 
-	executorType, err := executor.getFunctionExecutorType(meta)
+	fn, err := executor.fissionClient.Functions(meta.Namespace).Get(meta.Name)
 	if err != nil {
 		return nil, err
 	}
+	backend, err := executor.getBackend(fn.Spec.InvokeStrategy.ExecutionStrategy.ExecutorType)
+	if err != nil {
+		return nil, err
+	}
+	return backend.GetFuncSvc(meta, fn, env)
+}
 
-	switch executorType {
-	case fission.ExecutorTypeNewdeploy:
-		fs, err := executor.ndm.GetFuncSvc(meta)
-		return fs, err
-	default:
-		pool, err := executor.gpm.GetPool(env)
-		if err != nil {
-			return nil, err
-		}
-		// from GenericPool -> get one function container
-		// (this also adds to the cache)
-		log.Printf("[%v] getting function service from pool", meta.Name)
-		fsvc, err := pool.GetFuncSvc(meta)
-		return fsvc, err
+// getBackend returns the backend registered for an executor type; functions
+// that don't set one use poolmgr.
+func (executor *Executor) getBackend(executorType fission.ExecutorType) (ExecutorBackend, error) {
+	if len(executorType) == 0 {
+		executorType = fission.ExecutorTypePoolmgr
+	}
+	backend, ok := executor.backends[executorType]
+	if !ok {
+		return nil, fission.MakeError(fission.ErrorInvalidArgument,
+			fmt.Sprintf("unknown executor type %v", executorType))
+	}
+	return backend, nil
+}
+
+// scaleFunction lets the backend of the function with an instance at
+// address add another instance, if it scales on load and the function's
+// instances are busy.
+func (executor *Executor) scaleFunction(address string) {
+	fsvc, err := executor.fsCache.GetByAddress(address)
+	if err != nil {
+		return
+	}
+	backend, err := executor.getBackend(fsvc.Executor)
+	if err != nil {
+		return
+	}
+	if scaler, ok := backend.(FunctionScaler); ok {
+		scaler.ScaleFunction(fsvc)
 	}
 }
 
@@ -189,13 +221,13 @@ func (executor *Executor) getFunctionEnv(m *metav1.ObjectMeta) (*crd.Environment
 	return env, nil
 }
 
-// isValidAddress invokes isValidService or isValidPod depending on the type of executor
+// isValidAddress has the backend of the function service check that it's still valid
 func (executor *Executor) isValidAddress(fsvc *fscache.FuncSvc) bool {
-	if fsvc.Executor == fscache.NEWDEPLOY {
-		return executor.ndm.IsValidService(fsvc.Address)
-	} else {
-		return executor.gpm.IsValidPod(fsvc.KubernetesObjects, fsvc.Address)
+	backend, err := executor.getBackend(fsvc.Executor)
+	if err != nil {
+		return false
 	}
+	return backend.IsValid(fsvc)
 }
 
 func dumpStackTrace() {
@@ -223,10 +255,20 @@ func StartExecutor(fissionNamespace string, functionNamespace string, port int)
 
 	fsCache := fscache.MakeFunctionServiceCache()
 
-	poolID := strings.ToLower(uniuri.NewLen(8))
-	cleanupObjects(kubernetesClient, functionNamespace, poolID)
-	go idleObjectReaper(kubernetesClient, fissionClient, fsCache, time.Minute*2)
+	// Objects no replica created or specialized since this executor
+	// started, and that it didn't adopt, are cleaned up.
+	startTime := time.Now()
+
+	// All executor replicas share an instance id, since they manage
+	// the same objects.
+	poolID, err := getExecutorInstanceId(kubernetesClient, fissionNamespace)
+	if err != nil {
+		log.Printf("Failed to get executor instance id: %v", err)
+		return err
+	}
 
+	// The executor types adopt what previous instances left behind
+	// while they start up; only the rest is cleaned up.
 	gpm := poolmgr.MakeGenericPoolManager(
 		fissionClient, kubernetesClient,
 		functionNamespace, fsCache, poolID)
@@ -235,9 +277,37 @@ func StartExecutor(fissionNamespace string, functionNamespace string, port int)
 		fissionClient, kubernetesClient, restClient,
 		functionNamespace, fsCache, poolID)
 
-	api := MakeExecutor(gpm, ndm, fissionClient, fsCache)
+	jobm := jobmgr.MakeJobManager(fissionClient, kubernetesClient, functionNamespace)
+
+	backends := map[fission.ExecutorType]ExecutorBackend{
+		fission.ExecutorTypePoolmgr:   gpm,
+		fission.ExecutorTypeNewdeploy: ndm,
+		fission.ExecutorTypeJob:       jobm,
+	}
+
+	// Each replica records its failures under its own name.
+	replicaName, err := os.Hostname()
+	if err != nil {
+		log.Printf("Failed to get hostname: %v", err)
+		return err
+	}
+	records := makeStatusRecords(kubernetesClient, functionNamespace, replicaName)
+
+	executor := MakeExecutor(backends, jobm, fissionClient, fsCache, records)
+
+	// Every replica serves function services; the leader alone manages
+	// the backends' shared objects, reaps idle function services and
+	// records the status of functions.
+	go runLeaderElection(kubernetesClient, fissionNamespace, func() {
+		for _, backend := range backends {
+			backend.StartLeading()
+		}
+		cleanupObjects(backends, startTime)
+		go idleObjectReaper(backends)
+		go executor.functionStatusSyncer()
+	})
 
-	go api.Serve(port)
+	go executor.Serve(port)
 
 	return nil
 }
@@ -17,7 +17,9 @@ limitations under the License.
 package fscache
 
 import (
+	"fmt"
 	"log"
+	"sync"
 	"time"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -30,17 +32,37 @@ import (
 )
 
 type fscRequestType int
-type executorType int
+
+// FuncSvcEventType is the kind of change a FuncSvcEvent describes.
+type FuncSvcEventType string
 
 const (
 	TOUCH fscRequestType = iota
 	LISTOLD
 	LOG
+	LIST
+	SUBSCRIBE
+	UNSUBSCRIBE
+	ADD
+	DELETE
 )
 
 const (
-	POOLMGR executorType = iota
-	NEWDEPLOY
+	FuncSvcAdded   FuncSvcEventType = "ADDED"
+	FuncSvcDeleted FuncSvcEventType = "DELETED"
+	// FuncSvcSynced follows the ADDED events of the function services
+	// cached when the subscription started.
+	FuncSvcSynced FuncSvcEventType = "SYNCED"
+)
+
+// size of the per-subscriber event buffer; a subscriber that falls this far
+// behind is unsubscribed, so that it reconnects and resyncs instead of
+// silently missing events.
+const subscriberBufferSize = 256
+
+const (
+	POOLMGR   fission.ExecutorType = fission.ExecutorTypePoolmgr
+	NEWDEPLOY fission.ExecutorType = fission.ExecutorTypeNewdeploy
 )
 
 type (
@@ -50,17 +72,49 @@ type (
 		Environment       *crd.Environment      // function's environment
 		Address           string                // Host:Port or IP:Port that the function's service can be reached at.
 		KubernetesObjects []api.ObjectReference // Kubernetes Objects (within the function namespace)
-		Executor          executorType
+		Executor          fission.ExecutorType
+		IdleTimeout       time.Duration             // unused for longer than this, the service may be reaped; 0 means the reaper's default
+		ExecutionStrategy fission.ExecutionStrategy // function's execution strategy, for scaling its instances
 
 		Ctime time.Time
 		Atime time.Time
+
+		Concurrency int           // concurrent requests recently reported by all routers
+		Latency     time.Duration // mean request latency last reported by a router
+		Requests    int64         // requests reported by the routers since the service was cached
+	}
+
+	// routerLoad is the concurrency one router last reported on a
+	// function service.
+	routerLoad struct {
+		concurrency int
+		time        time.Time
+	}
+
+	// funcSvcGroup holds the instances of a function's service, by address.
+	funcSvcGroup struct {
+		instances map[string]*FuncSvc
+	}
+
+	// FuncSvcEvent is sent to subscribers whenever a function service is
+	// added to or removed from the cache.
+	FuncSvcEvent struct {
+		Type     FuncSvcEventType  `json:"type"`
+		Function metav1.ObjectMeta `json:"function"`
+		Address  string            `json:"address"`
 	}
 
 	FunctionServiceCache struct {
-		byFunction    *cache.Cache // function-key -> funcSvc  : map[string]*funcSvc
-		byAddress     *cache.Cache // address      -> function : map[string]metav1.ObjectMeta
-		byFunctionUID *cache.Cache // function uid -> function : map[string]metav1.ObjectMeta
+		byFunction    *cache.Cache // function-key -> instances : map[string]*funcSvcGroup
+		byAddress     *cache.Cache // address      -> function  : map[string]metav1.ObjectMeta
+		byFunctionUID *cache.Cache // function uid -> function  : map[string]metav1.ObjectMeta
+		lock          sync.Mutex   // protects the instances of every function service
+
+		// concurrency reported by each router, by instance address;
+		// protected by lock
+		routerLoads map[string]map[string]routerLoad
 
+		subscribers    map[chan *FuncSvcEvent]struct{}
 		requestChannel chan *fscRequest
 	}
 	fscRequest struct {
@@ -68,6 +122,8 @@ type (
 		address           string
 		kubernetesObjects []api.ObjectReference
 		age               time.Duration
+		fsvc              *FuncSvc
+		subscriber        chan *FuncSvcEvent
 		responseChannel   chan *fscResponse
 	}
 	fscResponse struct {
@@ -96,6 +152,8 @@ func MakeFunctionServiceCache() *FunctionServiceCache {
 		byFunction:     cache.MakeCache(0, 0),
 		byAddress:      cache.MakeCache(0, 0),
 		byFunctionUID:  cache.MakeCache(0, 0),
+		routerLoads:    make(map[string]map[string]routerLoad),
+		subscribers:    make(map[chan *FuncSvcEvent]struct{}),
 		requestChannel: make(chan *fscRequest),
 	}
 	go fsc.service()
@@ -112,22 +170,43 @@ func (fsc *FunctionServiceCache) service() {
 			resp.error = fsc._touchByAddress(req.address)
 		case LISTOLD:
 			// get svcs idle for > req.age
-			fscs := fsc.byFunction.Copy()
 			funcObjects := make([]*FuncSvc, 0)
-			for _, funcSvc := range fscs {
-				fsvc := funcSvc.(*FuncSvc)
-				if time.Since(fsvc.Atime) > req.age {
+			for _, fsvc := range fsc.listAll() {
+				if time.Since(fsvc.Atime) > fsvc.idleTimeout(req.age) {
 					funcObjects = append(funcObjects, fsvc)
 				}
 			}
 			resp.objects = funcObjects
+		case LIST:
+			resp.objects = fsc.listAll()
+		case SUBSCRIBE:
+			fsc.subscribers[req.subscriber] = struct{}{}
+		case UNSUBSCRIBE:
+			if _, ok := fsc.subscribers[req.subscriber]; ok {
+				delete(fsc.subscribers, req.subscriber)
+				close(req.subscriber)
+			}
+		case ADD:
+			// changes are published here, in the order they're
+			// made, so that subscribers see the same order
+			existing, err := fsc.add(req.fsvc)
+			resp.error = err
+			if existing != nil {
+				resp.objects = []*FuncSvc{existing}
+			} else if err == nil {
+				fsc.publish(FuncSvcAdded, req.fsvc)
+			}
+		case DELETE:
+			resp.deleted = fsc.deleteEntry(req.fsvc)
+			if resp.deleted {
+				fsc.publish(FuncSvcDeleted, req.fsvc)
+			}
 		case LOG:
-			funcCopy := fsc.byFunction.Copy()
-			log.Printf("Cache has %v entries", len(funcCopy))
-			for key, fsvcI := range funcCopy {
-				fsvc := fsvcI.(*FuncSvc)
+			fsvcs := fsc.listAll()
+			log.Printf("Cache has %v entries", len(fsvcs))
+			for _, fsvc := range fsvcs {
 				for _, kubeObj := range fsvc.KubernetesObjects {
-					log.Printf("%v\t%v\t%v", key, kubeObj.Kind, kubeObj.Name)
+					log.Printf("%v\t%v\t%v\t%v", crd.CacheKey(fsvc.Function), fsvc.Address, kubeObj.Kind, kubeObj.Name)
 				}
 			}
 		}
@@ -135,60 +214,163 @@ func (fsc *FunctionServiceCache) service() {
 	}
 }
 
+// listAll returns a copy of every instance of every function service.
+func (fsc *FunctionServiceCache) listAll() []*FuncSvc {
+	fsc.lock.Lock()
+	defer fsc.lock.Unlock()
+
+	funcObjects := make([]*FuncSvc, 0)
+	fsc.byFunction.Range(func(key interface{}, groupI interface{}) bool {
+		for _, fsvc := range groupI.(*funcSvcGroup).instances {
+			fsvcCopy := *fsvc
+			funcObjects = append(funcObjects, &fsvcCopy)
+		}
+		return true
+	})
+	return funcObjects
+}
+
+// oldest returns the longest-lived instance of the group, so that callers
+// expecting a single function service keep getting the same one.
+func (group *funcSvcGroup) oldest() *FuncSvc {
+	var oldest *FuncSvc
+	for _, fsvc := range group.instances {
+		if oldest == nil || fsvc.Ctime.Before(oldest.Ctime) {
+			oldest = fsvc
+		}
+	}
+	return oldest
+}
+
+func (fsc *FunctionServiceCache) getGroup(m *metav1.ObjectMeta) (*funcSvcGroup, error) {
+	groupI, err := fsc.byFunction.Get(crd.CacheKey(m))
+	if err != nil {
+		return nil, err
+	}
+	return groupI.(*funcSvcGroup), nil
+}
+
+func (fsc *FunctionServiceCache) getGroupByUID(uid types.UID) (*funcSvcGroup, error) {
+	mI, err := fsc.byFunctionUID.Get(uid)
+	if err != nil {
+		return nil, err
+	}
+	m := mI.(metav1.ObjectMeta)
+	return fsc.getGroup(&m)
+}
+
+// GetByFunction returns one instance of the function's service, updating
+// its atime.
 func (fsc *FunctionServiceCache) GetByFunction(m *metav1.ObjectMeta) (*FuncSvc, error) {
-	key := crd.CacheKey(m)
+	group, err := fsc.getGroup(m)
+	if err != nil {
+		return nil, err
+	}
+	return fsc.touchOldest(group)
+}
 
-	fsvcI, err := fsc.byFunction.Get(key)
+// GetByFunctionUID is like GetByFunction, for the function with this uid.
+func (fsc *FunctionServiceCache) GetByFunctionUID(uid types.UID) (*FuncSvc, error) {
+	group, err := fsc.getGroupByUID(uid)
 	if err != nil {
 		return nil, err
 	}
+	return fsc.touchOldest(group)
+}
+
+func (fsc *FunctionServiceCache) touchOldest(group *funcSvcGroup) (*FuncSvc, error) {
+	fsc.lock.Lock()
+	defer fsc.lock.Unlock()
+
+	fsvc := group.oldest()
+	if fsvc == nil {
+		return nil, fission.MakeError(fission.ErrorNotFound, "function service has no instances")
+	}
 
 	// update atime
-	fsvc := fsvcI.(*FuncSvc)
 	fsvc.Atime = time.Now()
 
 	fsvcCopy := *fsvc
 	return &fsvcCopy, nil
 }
 
-func (fsc *FunctionServiceCache) GetByFunctionUID(uid types.UID) (*FuncSvc, error) {
-	mI, err := fsc.byFunctionUID.Get(uid)
+// ListByFunction returns a copy of every instance of the function's
+// service.
+func (fsc *FunctionServiceCache) ListByFunction(m *metav1.ObjectMeta) ([]*FuncSvc, error) {
+	group, err := fsc.getGroup(m)
 	if err != nil {
 		return nil, err
 	}
+	return fsc.listGroup(group)
+}
 
-	m := mI.(metav1.ObjectMeta)
-
-	fsvcI, err := fsc.byFunction.Get(crd.CacheKey(&m))
+// ListByFunctionUID is like ListByFunction, for the function with this uid.
+func (fsc *FunctionServiceCache) ListByFunctionUID(uid types.UID) ([]*FuncSvc, error) {
+	group, err := fsc.getGroupByUID(uid)
 	if err != nil {
 		return nil, err
 	}
+	return fsc.listGroup(group)
+}
 
-	// update atime
-	fsvc := fsvcI.(*FuncSvc)
-	fsvc.Atime = time.Now()
+func (fsc *FunctionServiceCache) listGroup(group *funcSvcGroup) ([]*FuncSvc, error) {
+	fsc.lock.Lock()
+	defer fsc.lock.Unlock()
 
-	fsvcCopy := *fsvc
-	return &fsvcCopy, nil
+	if len(group.instances) == 0 {
+		return nil, fission.MakeError(fission.ErrorNotFound, "function service has no instances")
+	}
+	fsvcs := make([]*FuncSvc, 0, len(group.instances))
+	for _, fsvc := range group.instances {
+		fsvcCopy := *fsvc
+		fsvcs = append(fsvcs, &fsvcCopy)
+	}
+	return fsvcs, nil
 }
 
+// Add adds an instance of a function's service.  If the function already
+// has an instance at the same address, that one is returned along with a
+// NameExists error.
 func (fsc *FunctionServiceCache) Add(fsvc FuncSvc) (*FuncSvc, error) {
-	err, existing := fsc.byFunction.Set(crd.CacheKey(fsvc.Function), &fsvc)
+	responseChannel := make(chan *fscResponse)
+	fsc.requestChannel <- &fscRequest{
+		requestType:     ADD,
+		fsvc:            &fsvc,
+		responseChannel: responseChannel,
+	}
+	resp := <-responseChannel
+	if len(resp.objects) > 0 {
+		return resp.objects[0], resp.error
+	}
+	return nil, resp.error
+}
+
+func (fsc *FunctionServiceCache) add(fsvc *FuncSvc) (*FuncSvc, error) {
+	fsc.lock.Lock()
+	defer fsc.lock.Unlock()
+
+	group, err := fsc.getGroup(fsvc.Function)
 	if err != nil {
-		if existing != nil {
-			f := existing.(*FuncSvc)
-			err2 := fsc.TouchByAddress(f.Address)
-			if err2 != nil {
-				return nil, err2
-			}
-			fCopy := *f
-			return &fCopy, err
+		group = &funcSvcGroup{
+			instances: make(map[string]*FuncSvc),
 		}
-		return nil, err
+		fsc.byFunction.Set(crd.CacheKey(fsvc.Function), group)
 	}
+	if f, ok := group.instances[fsvc.Address]; ok {
+		f.Atime = time.Now()
+		fCopy := *f
+		return &fCopy, fission.MakeError(fission.ErrorNameExists, "function service already exists")
+	}
+
 	now := time.Now()
-	fsvc.Ctime = now
-	fsvc.Atime = now
+	if fsvc.Ctime.IsZero() {
+		fsvc.Ctime = now
+	}
+	if fsvc.Atime.IsZero() {
+		fsvc.Atime = now
+	}
+	fsvcCopy := *fsvc
+	group.instances[fsvc.Address] = &fsvcCopy
 
 	// Add to byAddress cache. Ignore NameExists errors
 	// because of multiple-specialization. See issue #331.
@@ -231,28 +413,145 @@ func (fsc *FunctionServiceCache) TouchByAddress(address string) error {
 }
 
 func (fsc *FunctionServiceCache) _touchByAddress(address string) error {
-	mI, err := fsc.byAddress.Get(address)
+	fsc.lock.Lock()
+	defer fsc.lock.Unlock()
+
+	fsvc, err := fsc.getByAddress(address)
 	if err != nil {
 		return err
 	}
+	fsvc.Atime = time.Now()
+	return nil
+}
+
+// GetByAddress returns a copy of the instance at address.
+func (fsc *FunctionServiceCache) GetByAddress(address string) (*FuncSvc, error) {
+	fsc.lock.Lock()
+	defer fsc.lock.Unlock()
+
+	fsvc, err := fsc.getByAddress(address)
+	if err != nil {
+		return nil, err
+	}
+	fsvcCopy := *fsvc
+	return &fsvcCopy, nil
+}
+
+// getByAddress returns the instance at address; the caller must hold the
+// lock.
+func (fsc *FunctionServiceCache) getByAddress(address string) (*FuncSvc, error) {
+	mI, err := fsc.byAddress.Get(address)
+	if err != nil {
+		return nil, err
+	}
 	m := mI.(metav1.ObjectMeta)
-	fsvcI, err := fsc.byFunction.Get(crd.CacheKey(&m))
+	group, err := fsc.getGroup(&m)
+	if err != nil {
+		return nil, err
+	}
+	fsvc, ok := group.instances[address]
+	if !ok {
+		return nil, fission.MakeError(fission.ErrorNotFound,
+			fmt.Sprintf("no function service at address %v", address))
+	}
+	return fsvc, nil
+}
+
+// routerLoadMaxAge is how long the concurrency a router reported counts
+// toward a function service's; routers report every few seconds while they
+// send requests to it.
+const routerLoadMaxAge = 15 * time.Second
+
+// RecordLoad records the concurrency and mean latency of requests to the
+// instance at address, as observed by a router, and adds the number of
+// requests the router made since its last report.  The instance's
+// concurrency is the sum of the recent reports of all routers.
+func (fsc *FunctionServiceCache) RecordLoad(address string, router string, concurrency int, requests int, latency time.Duration) error {
+	fsc.lock.Lock()
+	defer fsc.lock.Unlock()
+
+	fsvc, err := fsc.getByAddress(address)
 	if err != nil {
 		return err
 	}
-	fsvc := fsvcI.(*FuncSvc)
-	fsvc.Atime = time.Now()
+
+	now := time.Now()
+	loads, ok := fsc.routerLoads[address]
+	if !ok {
+		loads = make(map[string]routerLoad)
+		fsc.routerLoads[address] = loads
+	}
+	loads[router] = routerLoad{concurrency: concurrency, time: now}
+	total := 0
+	for r, load := range loads {
+		if now.Sub(load.time) > routerLoadMaxAge {
+			delete(loads, r)
+			continue
+		}
+		total += load.concurrency
+	}
+
+	fsvc.Concurrency = total
+	fsvc.Latency = latency
+	fsvc.Requests += int64(requests)
 	return nil
 }
 
+// DeleteEntry removes an instance of a function's service; the function
+// is removed from the cache along with its last instance.
 func (fsc *FunctionServiceCache) DeleteEntry(fsvc *FuncSvc) {
-	fsc.byFunction.Delete(crd.CacheKey(fsvc.Function))
+	responseChannel := make(chan *fscResponse)
+	fsc.requestChannel <- &fscRequest{
+		requestType:     DELETE,
+		fsvc:            fsvc,
+		responseChannel: responseChannel,
+	}
+	<-responseChannel
+}
+
+func (fsc *FunctionServiceCache) deleteEntry(fsvc *FuncSvc) bool {
+	fsc.lock.Lock()
+	defer fsc.lock.Unlock()
+
+	group, err := fsc.getGroup(fsvc.Function)
+	if err != nil {
+		return false
+	}
+	if _, ok := group.instances[fsvc.Address]; !ok {
+		return false
+	}
+	delete(group.instances, fsvc.Address)
+	delete(fsc.routerLoads, fsvc.Address)
 	fsc.byAddress.Delete(fsvc.Address)
-	fsc.byFunctionUID.Delete(fsvc.Function.UID)
+
+	if len(group.instances) == 0 {
+		fsc.byFunction.Delete(crd.CacheKey(fsvc.Function))
+		fsc.byFunctionUID.Delete(fsvc.Function.UID)
+	}
+	return true
+}
+
+// idleTimeout returns how long fsvc may stay unused before it's old,
+// falling back to defaultTimeout when it doesn't have its own.
+func (fsvc *FuncSvc) idleTimeout(defaultTimeout time.Duration) time.Duration {
+	if fsvc.IdleTimeout > 0 {
+		return fsvc.IdleTimeout
+	}
+	return defaultTimeout
 }
 
+// DeleteOld deletes fsvc if it has been idle for longer than its idle
+// timeout, or minAge if it has none.
 func (fsc *FunctionServiceCache) DeleteOld(fsvc *FuncSvc, minAge time.Duration) (bool, error) {
-	if time.Since(fsvc.Atime) < minAge {
+	fsc.lock.Lock()
+	atime := fsvc.Atime
+	if live, err := fsc.getByAddress(fsvc.Address); err == nil {
+		// fsvc may be a stale copy
+		atime = live.Atime
+	}
+	fsc.lock.Unlock()
+
+	if time.Since(atime) < fsvc.idleTimeout(minAge) {
 		return false, nil
 	}
 
@@ -261,6 +560,8 @@ func (fsc *FunctionServiceCache) DeleteOld(fsvc *FuncSvc, minAge time.Duration)
 	return true, nil
 }
 
+// ListOld lists the function services that have been idle for longer than
+// their idle timeout, or age for those without one.
 func (fsc *FunctionServiceCache) ListOld(age time.Duration) ([]*FuncSvc, error) {
 	responseChannel := make(chan *fscResponse)
 	fsc.requestChannel <- &fscRequest{
@@ -272,6 +573,63 @@ func (fsc *FunctionServiceCache) ListOld(age time.Duration) ([]*FuncSvc, error)
 	return resp.objects, resp.error
 }
 
+// List returns a copy of every function service in the cache.
+func (fsc *FunctionServiceCache) List() ([]*FuncSvc, error) {
+	responseChannel := make(chan *fscResponse)
+	fsc.requestChannel <- &fscRequest{
+		requestType:     LIST,
+		responseChannel: responseChannel,
+	}
+	resp := <-responseChannel
+	return resp.objects, resp.error
+}
+
+// Subscribe returns a channel that receives an event for every function
+// service added to or removed from the cache from now on, and a function
+// to stop the subscription. The channel is closed on unsubscribe, or when
+// the subscriber falls too far behind to keep up with the cache.
+func (fsc *FunctionServiceCache) Subscribe() (<-chan *FuncSvcEvent, func()) {
+	subscriber := make(chan *FuncSvcEvent, subscriberBufferSize)
+	responseChannel := make(chan *fscResponse)
+	fsc.requestChannel <- &fscRequest{
+		requestType:     SUBSCRIBE,
+		subscriber:      subscriber,
+		responseChannel: responseChannel,
+	}
+	<-responseChannel
+
+	unsubscribe := func() {
+		responseChannel := make(chan *fscResponse)
+		fsc.requestChannel <- &fscRequest{
+			requestType:     UNSUBSCRIBE,
+			subscriber:      subscriber,
+			responseChannel: responseChannel,
+		}
+		<-responseChannel
+	}
+	return subscriber, unsubscribe
+}
+
+// publish sends an event to the subscribers; it's only called by the
+// service goroutine.
+func (fsc *FunctionServiceCache) publish(eventType FuncSvcEventType, fsvc *FuncSvc) {
+	event := &FuncSvcEvent{
+		Type:     eventType,
+		Function: *fsvc.Function,
+		Address:  fsvc.Address,
+	}
+	for subscriber := range fsc.subscribers {
+		// never block the cache on a slow subscriber
+		select {
+		case subscriber <- event:
+		default:
+			log.Printf("Closing function service event stream of slow subscriber at %v %v", event.Type, event.Function.Name)
+			delete(fsc.subscribers, subscriber)
+			close(subscriber)
+		}
+	}
+}
+
 func (fsc *FunctionServiceCache) Log() {
 	log.Printf("--- FunctionService Cache Contents")
 	responseChannel := make(chan *fscResponse)
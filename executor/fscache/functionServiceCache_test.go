@@ -1,11 +1,14 @@
 package fscache
 
 import (
+	"fmt"
 	"log"
+	"sync"
 	"testing"
 	"time"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/pkg/api"
 
 	"github.com/fission/fission"
@@ -110,3 +113,217 @@ func TestFunctionServiceCache(t *testing.T) {
 		log.Panicf("found fsvc by function uid while expecting empty cache: %v", err)
 	}
 }
+
+func TestFunctionServiceCacheEvents(t *testing.T) {
+	fsc := MakeFunctionServiceCache()
+	events, unsubscribe := fsc.Subscribe()
+	defer unsubscribe()
+
+	fsvc := &FuncSvc{
+		Function: &metav1.ObjectMeta{
+			Name: "foo",
+			UID:  "1212",
+		},
+		Environment: &crd.Environment{
+			Metadata: metav1.ObjectMeta{
+				Name: "foo-env",
+				UID:  "2323",
+			},
+		},
+		Address: "xxx",
+	}
+	_, err := fsc.Add(*fsvc)
+	if err != nil {
+		log.Panicf("Failed to add fsvc: %v", err)
+	}
+
+	fsvcs, err := fsc.List()
+	if err != nil || len(fsvcs) != 1 {
+		log.Panicf("Expected 1 fsvc in list, found %v (err: %v)", len(fsvcs), err)
+	}
+
+	fsc.DeleteEntry(fsvc)
+
+	for _, expected := range []FuncSvcEventType{FuncSvcAdded, FuncSvcDeleted} {
+		select {
+		case event := <-events:
+			if event.Type != expected || event.Function.Name != "foo" || event.Address != "xxx" {
+				log.Panicf("Unexpected event %#v, expected type %v", event, expected)
+			}
+		case <-time.After(time.Second):
+			log.Panicf("Timed out waiting for %v event", expected)
+		}
+	}
+}
+
+func TestFunctionServiceCacheSlowSubscriber(t *testing.T) {
+	fsc := MakeFunctionServiceCache()
+	events, unsubscribe := fsc.Subscribe()
+	defer unsubscribe()
+
+	for i := 0; i <= subscriberBufferSize; i++ {
+		fsc.Add(FuncSvc{
+			Function: &metav1.ObjectMeta{Name: "foo", UID: "1212"},
+			Address:  fmt.Sprintf("10.0.0.%v", i),
+		})
+	}
+
+	// the buffered events are still delivered, then the stream ends
+	n := 0
+	for range events {
+		n++
+	}
+	if n != subscriberBufferSize {
+		log.Panicf("Expected %v events before the stream closed, got %v", subscriberBufferSize, n)
+	}
+}
+
+func TestFunctionServiceCacheIdleTimeout(t *testing.T) {
+	fsc := MakeFunctionServiceCache()
+
+	now := time.Now()
+	for i, idleTimeout := range []time.Duration{0, 10 * time.Minute} {
+		fsvc := FuncSvc{
+			Function: &metav1.ObjectMeta{
+				Name: fmt.Sprintf("foo-%v", i),
+				UID:  types.UID(fmt.Sprintf("uid-%v", i)),
+			},
+			Environment: &crd.Environment{
+				Metadata: metav1.ObjectMeta{
+					Name: "foo-env",
+				},
+			},
+			Address:     fmt.Sprintf("address-%v", i),
+			IdleTimeout: idleTimeout,
+			Ctime:       now,
+			Atime:       now.Add(-5 * time.Minute),
+		}
+		_, err := fsc.Add(fsvc)
+		if err != nil {
+			log.Panicf("Failed to add fsvc: %v", err)
+		}
+	}
+
+	// only the function without its own idle timeout is old
+	old, err := fsc.ListOld(2 * time.Minute)
+	if err != nil {
+		log.Panicf("Failed to list old fsvcs: %v", err)
+	}
+	if len(old) != 1 || old[0].Function.Name != "foo-0" {
+		log.Panicf("Expected only foo-0 to be old, got %v fsvcs", len(old))
+	}
+
+	deleted, err := fsc.DeleteOld(old[0], 2*time.Minute)
+	if err != nil || !deleted {
+		log.Panicf("Expected to delete old fsvc (err: %v)", err)
+	}
+
+	fsvc, err := fsc.GetByFunction(&metav1.ObjectMeta{Name: "foo-1", UID: "uid-1"})
+	if err != nil {
+		log.Panicf("Failed to get fsvc: %v", err)
+	}
+	deleted, err = fsc.DeleteOld(fsvc, 2*time.Minute)
+	if err != nil || deleted {
+		log.Panicf("Deleted fsvc within its idle timeout (err: %v)", err)
+	}
+}
+
+func TestFunctionServiceCacheInstances(t *testing.T) {
+	fsc := MakeFunctionServiceCache()
+
+	fn := &metav1.ObjectMeta{
+		Name: "foo",
+		UID:  "1212",
+	}
+	for _, address := range []string{"10.0.0.1:8888", "10.0.0.2:8888"} {
+		_, err := fsc.Add(FuncSvc{
+			Function: fn,
+			Environment: &crd.Environment{
+				Metadata: metav1.ObjectMeta{
+					Name: "foo-env",
+				},
+			},
+			Address: address,
+		})
+		if err != nil {
+			log.Panicf("Failed to add fsvc at %v: %v", address, err)
+		}
+	}
+
+	// adding an instance at a known address returns the existing one
+	existing, err := fsc.Add(FuncSvc{Function: fn, Address: "10.0.0.1:8888"})
+	if !IsNameExistError(err) || existing == nil || existing.Address != "10.0.0.1:8888" {
+		log.Panicf("Expected existing instance, got %#v (err: %v)", existing, err)
+	}
+
+	fsvcs, err := fsc.ListByFunction(fn)
+	if err != nil || len(fsvcs) != 2 {
+		log.Panicf("Expected 2 instances, found %v (err: %v)", len(fsvcs), err)
+	}
+
+	// the concurrency reported by each router adds up; a router's new
+	// report replaces its previous one
+	for _, load := range []struct {
+		router      string
+		concurrency int
+	}{{"router-a", 1}, {"router-b", 3}, {"router-a", 2}} {
+		err = fsc.RecordLoad("10.0.0.2:8888", load.router, load.concurrency, 10, time.Second)
+		if err != nil {
+			log.Panicf("Failed to record load: %v", err)
+		}
+	}
+
+	// removing one instance keeps the other
+	fsc.DeleteEntry(&FuncSvc{Function: fn, Address: "10.0.0.1:8888"})
+	fsvcs, err = fsc.ListByFunctionUID(fn.UID)
+	if err != nil || len(fsvcs) != 1 || fsvcs[0].Address != "10.0.0.2:8888" ||
+		fsvcs[0].Concurrency != 5 || fsvcs[0].Requests != 30 {
+		log.Panicf("Expected only the second instance to be left, found %v (err: %v)", len(fsvcs), err)
+	}
+
+	fsc.DeleteEntry(fsvcs[0])
+	_, err = fsc.GetByFunction(fn)
+	if err == nil {
+		log.Panicf("found fsvc while expecting empty cache")
+	}
+}
+
+func TestFunctionServiceCacheEventOrder(t *testing.T) {
+	fsc := MakeFunctionServiceCache()
+	events, unsubscribe := fsc.Subscribe()
+	defer unsubscribe()
+
+	fsvc := FuncSvc{
+		Function: &metav1.ObjectMeta{Name: "foo", UID: "1212"},
+		Address:  "xxx",
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			fsc.Add(fsvc)
+		}()
+		go func() {
+			defer wg.Done()
+			fsc.DeleteEntry(&fsvc)
+		}()
+	}
+	wg.Wait()
+
+	// the last event tells whether the service is cached
+	var last *FuncSvcEvent
+	for done := false; !done; {
+		select {
+		case event := <-events:
+			last = event
+		default:
+			done = true
+		}
+	}
+	_, err := fsc.GetByAddress("xxx")
+	cached := err == nil
+	if last == nil || (last.Type == FuncSvcAdded) != cached {
+		t.Errorf("expected the last event to match the cache, got %#v with the service cached: %v", last, cached)
+	}
+}
@@ -0,0 +1,156 @@
+/*
+Copyright 2018 The Fission Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package jobmgr
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	k8s_err "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	apiv1 "k8s.io/client-go/pkg/api/v1"
+
+	"github.com/fission/fission"
+)
+
+type (
+	InvocationStatus string
+
+	// InvocationRequest is a request to run a job function once.
+	InvocationRequest struct {
+		Function metav1.ObjectMeta `json:"function"`
+		Method   string            `json:"method"`
+		Header   http.Header       `json:"header"`
+		Body     []byte            `json:"body"`
+	}
+
+	// Invocation is the record of one run of a job function.  It's kept in
+	// a config map for a while after the run completes, so that its
+	// outcome can be looked up by id.  The outcome is the HTTP status of
+	// the function's response; the runtime server doesn't exit after
+	// serving it, so ExitCode is set only if the container crashed.
+	Invocation struct {
+		ID             string            `json:"id"`
+		Function       metav1.ObjectMeta `json:"function"`
+		JobName        string            `json:"jobName"`
+		Status         InvocationStatus  `json:"status"`
+		Attempts       int               `json:"attempts"`
+		StartTime      time.Time         `json:"startTime"`
+		Deadline       time.Time         `json:"deadline"`
+		CompletionTime *time.Time        `json:"completionTime,omitempty"`
+		StatusCode     int               `json:"statusCode,omitempty"` // HTTP status of the function's response
+		ExitCode       *int32            `json:"exitCode,omitempty"`   // of the function container, if it crashed
+		Response       string            `json:"response,omitempty"`
+		Logs           string            `json:"logs,omitempty"`
+		Message        string            `json:"message,omitempty"`
+	}
+)
+
+const (
+	InvocationPending   InvocationStatus = "Pending"
+	InvocationRunning   InvocationStatus = "Running"
+	InvocationSucceeded InvocationStatus = "Succeeded"
+	InvocationFailed    InvocationStatus = "Failed"
+)
+
+const (
+	invocationIdLabel = "invocationId"
+	invocationDataKey = "invocation"
+
+	// keeps the record well within the size limit of a config map
+	maxRecordedOutput = 64 * 1024
+)
+
+// finished returns true once the invocation has succeeded or failed.
+func (inv *Invocation) finished() bool {
+	return inv.Status == InvocationSucceeded || inv.Status == InvocationFailed
+}
+
+// complete marks the invocation finished with the given status.
+func (inv *Invocation) complete(status InvocationStatus, message string) {
+	now := time.Now()
+	inv.Status = status
+	inv.CompletionTime = &now
+	inv.Message = message
+}
+
+// truncateOutput keeps the tail of output that's too long to record.
+func truncateOutput(output []byte) string {
+	if len(output) > maxRecordedOutput {
+		output = output[len(output)-maxRecordedOutput:]
+	}
+	return string(output)
+}
+
+func invocationRecordName(id string) string {
+	return fmt.Sprintf("invocation-%v", id)
+}
+
+func invocationLabels(inv *Invocation) map[string]string {
+	return map[string]string{
+		"executorType":    fission.ExecutorTypeJob,
+		"functionName":    inv.Function.Name,
+		"functionUid":     string(inv.Function.UID),
+		invocationIdLabel: inv.ID,
+	}
+}
+
+// saveInvocation writes the invocation's record, creating it the first time.
+func (jm *JobManager) saveInvocation(inv *Invocation, create bool) error {
+	data, err := json.Marshal(inv)
+	if err != nil {
+		return err
+	}
+	cm := &apiv1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   invocationRecordName(inv.ID),
+			Labels: invocationLabels(inv),
+		},
+		Data: map[string]string{
+			invocationDataKey: string(data),
+		},
+	}
+	if create {
+		_, err = jm.kubernetesClient.CoreV1().ConfigMaps(jm.namespace).Create(cm)
+	} else {
+		_, err = jm.kubernetesClient.CoreV1().ConfigMaps(jm.namespace).Update(cm)
+	}
+	return err
+}
+
+func decodeInvocation(cm *apiv1.ConfigMap) (*Invocation, error) {
+	inv := &Invocation{}
+	err := json.Unmarshal([]byte(cm.Data[invocationDataKey]), inv)
+	if err != nil {
+		return nil, err
+	}
+	return inv, nil
+}
+
+// GetInvocation returns the record of the invocation with the given id.
+func (jm *JobManager) GetInvocation(id string) (*Invocation, error) {
+	cm, err := jm.kubernetesClient.CoreV1().ConfigMaps(jm.namespace).Get(invocationRecordName(id), metav1.GetOptions{})
+	if err != nil {
+		if k8s_err.IsNotFound(err) {
+			return nil, fission.MakeError(fission.ErrorNotFound, fmt.Sprintf("invocation %v not found", id))
+		}
+		return nil, err
+	}
+	return decodeInvocation(cm)
+}
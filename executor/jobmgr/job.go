@@ -0,0 +1,214 @@
+/*
+Copyright 2018 The Fission Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package jobmgr
+
+import (
+	"encoding/json"
+	"log"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	apiv1 "k8s.io/client-go/pkg/api/v1"
+	batchv1 "k8s.io/client-go/pkg/apis/batch/v1"
+
+	"github.com/fission/fission"
+	"github.com/fission/fission/crd"
+	"github.com/fission/fission/environments/fetcher"
+	"github.com/fission/fission/executor/util"
+)
+
+const (
+	envVersion = "ENV_VERSION"
+
+	// defaultActiveDeadline is how long an invocation of a function that
+	// doesn't set its own deadline may run.
+	defaultActiveDeadline = time.Hour
+)
+
+// activeDeadline returns how long an invocation of a function may run,
+// including the time to start its pods and any retries.
+func activeDeadline(strategy fission.ExecutionStrategy) time.Duration {
+	if strategy.ActiveDeadline <= 0 {
+		return defaultActiveDeadline
+	}
+	return time.Duration(strategy.ActiveDeadline) * time.Second
+}
+
+// getJobSpec returns a job running the function's environment container,
+// with a fetcher that loads the function's package into it on startup.
+// The job keeps one pod running until the invocation is done with it.
+func (jm *JobManager) getJobSpec(fn *crd.Function, env *crd.Environment, inv *Invocation) (*batchv1.Job, error) {
+	targetFilename := "user"
+	parallelism := int32(1)
+	deadlineSeconds := int64(activeDeadline(fn.Spec.InvokeStrategy.ExecutionStrategy).Seconds())
+	gracePeriodSeconds := int64(30)
+	if env.Spec.TerminationGracePeriod > 0 {
+		gracePeriodSeconds = env.Spec.TerminationGracePeriod
+	}
+
+	fetchReq := &fetcher.FetchRequest{
+		FetchType: fetcher.FETCH_DEPLOYMENT,
+		Package: metav1.ObjectMeta{
+			Namespace: fn.Spec.Package.PackageRef.Namespace,
+			Name:      fn.Spec.Package.PackageRef.Name,
+		},
+		Filename:   targetFilename,
+		Secrets:    fn.Spec.Secrets,
+		ConfigMaps: fn.Spec.ConfigMaps,
+	}
+
+	// the function's environment is set on its container, and isn't
+	// passed in the load request, which ends up on the fetcher's command
+	// line
+	fnEnv, _, err := util.GetFunctionEnv(jm.kubernetesClient, fn, jm.namespace, inv.JobName)
+	if err != nil {
+		return nil, err
+	}
+
+	loadReq := fission.FunctionLoadRequest{
+		FilePath:         filepath.Join(jm.sharedMountPath, targetFilename),
+		FunctionName:     fn.Spec.Package.FunctionName,
+		FunctionMetadata: &fn.Metadata,
+	}
+
+	fetchPayload, err := json.Marshal(fetchReq)
+	if err != nil {
+		return nil, err
+	}
+	loadPayload, err := json.Marshal(loadReq)
+	if err != nil {
+		return nil, err
+	}
+
+	fetcherResources, err := util.GetFetcherResources()
+	if err != nil {
+		log.Printf("Error while parsing fetcher resources: %v", err)
+		return nil, err
+	}
+
+	labels := invocationLabels(inv)
+	volumeMounts := []apiv1.VolumeMount{
+		{
+			Name:      fission.SharedVolumeUserfunc,
+			MountPath: jm.sharedMountPath,
+		},
+		{
+			Name:      fission.SharedVolumeSecrets,
+			MountPath: jm.sharedSecretPath,
+		},
+		{
+			Name:      fission.SharedVolumeConfigmaps,
+			MountPath: jm.sharedCfgMapPath,
+		},
+	}
+
+	job := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   inv.JobName,
+			Labels: labels,
+			Annotations: map[string]string{
+				fission.FUNCTION_RESOURCEVERSION_ANNOTATION: fn.Metadata.ResourceVersion,
+			},
+		},
+		Spec: batchv1.JobSpec{
+			Parallelism:           &parallelism,
+			ActiveDeadlineSeconds: &deadlineSeconds,
+			Template: apiv1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: labels,
+				},
+				Spec: apiv1.PodSpec{
+					Volumes: []apiv1.Volume{
+						{
+							Name: fission.SharedVolumeUserfunc,
+							VolumeSource: apiv1.VolumeSource{
+								EmptyDir: &apiv1.EmptyDirVolumeSource{},
+							},
+						},
+						{
+							Name: fission.SharedVolumeSecrets,
+							VolumeSource: apiv1.VolumeSource{
+								EmptyDir: &apiv1.EmptyDirVolumeSource{},
+							},
+						},
+						{
+							Name: fission.SharedVolumeConfigmaps,
+							VolumeSource: apiv1.VolumeSource{
+								EmptyDir: &apiv1.EmptyDirVolumeSource{},
+							},
+						},
+					},
+					Containers: []apiv1.Container{
+						fission.MergeContainerSpecs(&apiv1.Container{
+							Name:                   fn.Metadata.Name,
+							Image:                  env.Spec.Runtime.Image,
+							ImagePullPolicy:        apiv1.PullIfNotPresent,
+							TerminationMessagePath: "/dev/termination-log",
+							VolumeMounts:           volumeMounts,
+							Resources:              util.GetFunctionResources(env, fn),
+							Env:                    fnEnv,
+						}, env.Spec.Runtime.Container),
+						{
+							Name:                   "fetcher",
+							Image:                  jm.fetcherImg,
+							ImagePullPolicy:        jm.fetcherImagePullPolicy,
+							TerminationMessagePath: "/dev/termination-log",
+							VolumeMounts:           volumeMounts,
+							Command: []string{"/fetcher", "-specialize-on-startup",
+								"-fetch-request", string(fetchPayload),
+								"-load-request", string(loadPayload),
+								"-secret-dir", jm.sharedSecretPath,
+								"-cfgmap-dir", jm.sharedCfgMapPath,
+								jm.sharedMountPath},
+							Env: []apiv1.EnvVar{
+								{
+									Name:  envVersion,
+									Value: strconv.Itoa(env.Spec.Version),
+								},
+							},
+							Resources: fetcherResources,
+							ReadinessProbe: &apiv1.Probe{
+								InitialDelaySeconds: 1,
+								PeriodSeconds:       1,
+								FailureThreshold:    30,
+								Handler: apiv1.Handler{
+									HTTPGet: &apiv1.HTTPGetAction{
+										Path: "/healthz",
+										Port: intstr.IntOrString{
+											Type:   intstr.Int,
+											IntVal: 8000,
+										},
+									},
+								},
+							},
+						},
+					},
+					RestartPolicy:                 apiv1.RestartPolicyNever,
+					ServiceAccountName:            "fission-fetcher",
+					TerminationGracePeriodSeconds: &gracePeriodSeconds,
+				},
+			},
+		},
+	}
+
+	job.Spec.Template.Spec = util.MergeFunctionPodSpec(&job.Spec.Template.Spec, env, fn)
+
+	return job, nil
+}
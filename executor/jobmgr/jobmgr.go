@@ -0,0 +1,433 @@
+/*
+Copyright 2018 The Fission Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package jobmgr
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/dchest/uniuri"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	apiv1 "k8s.io/client-go/pkg/api/v1"
+
+	"github.com/fission/fission"
+	"github.com/fission/fission/crd"
+	"github.com/fission/fission/executor/fscache"
+	"github.com/fission/fission/executor/util"
+)
+
+type (
+	// JobManager runs each invocation of a job function in a Kubernetes
+	// job of its own, and keeps a record of its outcome.
+	JobManager struct {
+		kubernetesClient *kubernetes.Clientset
+		fissionClient    *crd.FissionClient
+
+		fetcherImg             string
+		fetcherImagePullPolicy apiv1.PullPolicy
+		namespace              string
+		sharedMountPath        string
+		sharedSecretPath       string
+		sharedCfgMapPath       string
+	}
+)
+
+const (
+	// how long the records of finished invocations are kept
+	invocationRetention = 24 * time.Hour
+
+	// how long past its deadline an unfinished invocation is considered
+	// abandoned by the executor replica running it
+	abandonedGracePeriod = time.Minute
+
+	// lines of the function container's log recorded with an invocation
+	logTailLines = int64(100)
+)
+
+var (
+	deletePropagation = metav1.DeletePropagationBackground
+	delOpt            = metav1.DeleteOptions{PropagationPolicy: &deletePropagation}
+)
+
+func MakeJobManager(
+	fissionClient *crd.FissionClient,
+	kubernetesClient *kubernetes.Clientset,
+	namespace string,
+) *JobManager {
+
+	log.Printf("Creating Job ExecutorType")
+
+	fetcherImg := os.Getenv("FETCHER_IMAGE")
+	if len(fetcherImg) == 0 {
+		fetcherImg = "fission/fetcher"
+	}
+	fetcherImagePullPolicy := os.Getenv("FETCHER_IMAGE_PULL_POLICY")
+	if len(fetcherImagePullPolicy) == 0 {
+		fetcherImagePullPolicy = "IfNotPresent"
+	}
+
+	return &JobManager{
+		fissionClient:    fissionClient,
+		kubernetesClient: kubernetesClient,
+		namespace:        namespace,
+
+		fetcherImg:             fetcherImg,
+		fetcherImagePullPolicy: apiv1.PullPolicy(fetcherImagePullPolicy),
+		sharedMountPath:        "/userfunc",
+		sharedSecretPath:       "/secrets",
+		sharedCfgMapPath:       "/configs",
+	}
+}
+
+// jobName returns the name of the job of an invocation; the function name
+// is shortened to keep the job-name label Kubernetes puts on the job's pods
+// within the label length limit.
+func jobName(fnName string, id string) string {
+	if len(fnName) > 40 {
+		fnName = strings.TrimSuffix(fnName[:40], "-")
+	}
+	return fmt.Sprintf("%v-%v", fnName, id)
+}
+
+// Invoke starts an invocation of a job function and returns its record,
+// without waiting for the function to run.
+func (jm *JobManager) Invoke(req *InvocationRequest) (*Invocation, error) {
+	fn, err := jm.fissionClient.Functions(req.Function.Namespace).Get(req.Function.Name)
+	if err != nil {
+		return nil, err
+	}
+	strategy := fn.Spec.InvokeStrategy.ExecutionStrategy
+	if strategy.ExecutorType != fission.ExecutorTypeJob {
+		return nil, fission.MakeError(fission.ErrorInvalidArgument,
+			fmt.Sprintf("function %v doesn't use the %v executor", fn.Metadata.Name, fission.ExecutorTypeJob))
+	}
+	env, err := jm.fissionClient.Environments(fn.Spec.Environment.Namespace).Get(fn.Spec.Environment.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	id := strings.ToLower(uniuri.NewLen(10))
+	now := time.Now()
+	inv := &Invocation{
+		ID: id,
+		Function: metav1.ObjectMeta{
+			Name:            fn.Metadata.Name,
+			Namespace:       fn.Metadata.Namespace,
+			UID:             fn.Metadata.UID,
+			ResourceVersion: fn.Metadata.ResourceVersion,
+		},
+		JobName:   jobName(fn.Metadata.Name, id),
+		Status:    InvocationPending,
+		StartTime: now,
+		Deadline:  now.Add(activeDeadline(strategy)),
+	}
+
+	job, err := jm.getJobSpec(fn, env, inv)
+	if err != nil {
+		return nil, err
+	}
+
+	// the record goes first, so that every job can be accounted for
+	err = jm.saveInvocation(inv, true)
+	if err != nil {
+		return nil, err
+	}
+	job, err = jm.kubernetesClient.BatchV1().Jobs(jm.namespace).Create(job)
+	if err != nil {
+		inv.complete(InvocationFailed, fmt.Sprintf("error creating job: %v", err))
+		jm.saveInvocation(inv, false)
+		jm.kubernetesClient.CoreV1().Secrets(jm.namespace).Delete(inv.JobName, nil)
+		return nil, err
+	}
+	// the copy of the function's secret and configmap keys goes with the job
+	err = util.SetFunctionEnvOwner(jm.kubernetesClient, jm.namespace, inv.JobName, metav1.OwnerReference{
+		APIVersion: "batch/v1",
+		Kind:       "Job",
+		Name:       job.ObjectMeta.Name,
+		UID:        job.ObjectMeta.UID,
+	})
+	if err != nil {
+		log.Printf("[%v] Error setting the owner of the environment of job %v: %v", fn.Metadata.Name, inv.JobName, err)
+	}
+
+	log.Printf("[%v] Started invocation %v in job %v", fn.Metadata.Name, id, inv.JobName)
+	// run updates a copy of its own, and keeps the record up to date for
+	// the caller to look the outcome up in
+	running := *inv
+	go jm.run(&running, fn, req)
+	return inv, nil
+}
+
+// run passes the request to a pod of the invocation's job, retrying on a
+// new pod if the function fails, records the outcome and deletes the job.
+func (jm *JobManager) run(inv *Invocation, fn *crd.Function, req *InvocationRequest) {
+	retries := fn.Spec.InvokeStrategy.ExecutionStrategy.Retries
+	tried := make(map[types.UID]bool)
+
+	var pod *apiv1.Pod
+	for inv.Attempts <= retries && !inv.finished() {
+		if pod != nil {
+			// the job replaces the pod with a fresh one for the next attempt
+			jm.deletePod(pod)
+		}
+
+		p, err := jm.waitForPod(inv, tried)
+		if err != nil {
+			inv.complete(InvocationFailed, err.Error())
+			break
+		}
+		pod = p
+		tried[pod.ObjectMeta.UID] = true
+		inv.Attempts++
+
+		if pod.Status.Phase == apiv1.PodFailed {
+			inv.Message = fmt.Sprintf("pod %v failed", pod.ObjectMeta.Name)
+			continue
+		}
+
+		inv.Status = InvocationRunning
+		err = jm.saveInvocation(inv, false)
+		if err != nil {
+			log.Printf("[%v] Error saving invocation %v: %v", inv.Function.Name, inv.ID, err)
+		}
+
+		statusCode, body, err := jm.invokePod(pod, req, inv.Deadline)
+		if err != nil {
+			inv.Message = fmt.Sprintf("error invoking function: %v", err)
+			continue
+		}
+		inv.StatusCode = statusCode
+		inv.Response = truncateOutput(body)
+		if statusCode >= 500 {
+			inv.Message = fmt.Sprintf("function responded with status %v", statusCode)
+			continue
+		}
+		if statusCode >= 400 {
+			inv.complete(InvocationFailed, fmt.Sprintf("function responded with status %v", statusCode))
+		} else {
+			inv.complete(InvocationSucceeded, "")
+		}
+	}
+	if !inv.finished() {
+		// out of retries; keep the error of the last attempt
+		inv.complete(InvocationFailed, inv.Message)
+	}
+
+	if pod != nil {
+		inv.Logs, inv.ExitCode = jm.podOutput(pod, fn.Metadata.Name)
+	}
+	log.Printf("[%v] Invocation %v %v after %v attempts", inv.Function.Name, inv.ID, strings.ToLower(string(inv.Status)), inv.Attempts)
+
+	err := jm.saveInvocation(inv, false)
+	if err != nil {
+		log.Printf("[%v] Error saving invocation %v: %v", inv.Function.Name, inv.ID, err)
+	}
+	jm.deleteJob(inv.JobName)
+}
+
+// waitForPod waits for a pod of the invocation's job that wasn't tried yet
+// to become ready, or to fail.
+func (jm *JobManager) waitForPod(inv *Invocation, tried map[types.UID]bool) (*apiv1.Pod, error) {
+	selector := labels.Set(map[string]string{invocationIdLabel: inv.ID}).AsSelector().String()
+	for time.Now().Before(inv.Deadline) {
+		podList, err := jm.kubernetesClient.CoreV1().Pods(jm.namespace).List(metav1.ListOptions{
+			LabelSelector: selector,
+		})
+		if err != nil {
+			return nil, err
+		}
+		for i := range podList.Items {
+			pod := &podList.Items[i]
+			if tried[pod.ObjectMeta.UID] || pod.ObjectMeta.DeletionTimestamp != nil {
+				continue
+			}
+			if pod.Status.Phase == apiv1.PodFailed ||
+				(len(pod.Status.PodIP) > 0 && fission.IsReadyPod(pod)) {
+				return pod, nil
+			}
+		}
+		time.Sleep(time.Second)
+	}
+	return nil, errors.New("deadline exceeded waiting for the function's pod")
+}
+
+// invokePod passes the request to the function in the pod and returns its
+// response.
+func (jm *JobManager) invokePod(pod *apiv1.Pod, req *InvocationRequest, deadline time.Time) (int, []byte, error) {
+	method := req.Method
+	if len(method) == 0 {
+		method = http.MethodPost
+	}
+	httpReq, err := http.NewRequest(method, fmt.Sprintf("http://%v:8888/", pod.Status.PodIP), bytes.NewReader(req.Body))
+	if err != nil {
+		return 0, nil, err
+	}
+	for k, v := range req.Header {
+		httpReq.Header[k] = v
+	}
+
+	// a zero timeout would mean none at all
+	timeout := deadline.Sub(time.Now())
+	if timeout <= 0 {
+		return 0, nil, errors.New("deadline exceeded before invoking the function")
+	}
+	client := &http.Client{Timeout: timeout}
+	resp, err := client.Do(httpReq)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return 0, nil, err
+	}
+	return resp.StatusCode, body, nil
+}
+
+// podOutput returns the tail of the function container's log and its exit
+// code, if it terminated.  The runtime server keeps running after serving
+// the request, so the exit code is only known if the container crashed.
+func (jm *JobManager) podOutput(pod *apiv1.Pod, containerName string) (string, *int32) {
+	tailLines := logTailLines
+	logs, err := jm.kubernetesClient.CoreV1().Pods(pod.ObjectMeta.Namespace).GetLogs(pod.ObjectMeta.Name,
+		&apiv1.PodLogOptions{Container: containerName, TailLines: &tailLines}).Do().Raw()
+	if err != nil {
+		log.Printf("Error getting logs of pod %v: %v", pod.ObjectMeta.Name, err)
+	}
+
+	var exitCode *int32
+	current, err := jm.kubernetesClient.CoreV1().Pods(pod.ObjectMeta.Namespace).Get(pod.ObjectMeta.Name, metav1.GetOptions{})
+	if err == nil {
+		pod = current
+	}
+	for _, status := range pod.Status.ContainerStatuses {
+		if status.Name != containerName {
+			continue
+		}
+		if status.State.Terminated != nil {
+			exitCode = &status.State.Terminated.ExitCode
+		} else if status.LastTerminationState.Terminated != nil {
+			exitCode = &status.LastTerminationState.Terminated.ExitCode
+		}
+	}
+	return truncateOutput(logs), exitCode
+}
+
+func (jm *JobManager) deletePod(pod *apiv1.Pod) {
+	err := jm.kubernetesClient.CoreV1().Pods(pod.ObjectMeta.Namespace).Delete(pod.ObjectMeta.Name, nil)
+	if err != nil {
+		log.Printf("Error deleting pod %v: %v", pod.ObjectMeta.Name, err)
+	}
+}
+
+func (jm *JobManager) deleteJob(name string) {
+	err := jm.kubernetesClient.BatchV1().Jobs(jm.namespace).Delete(name, &delOpt)
+	if err != nil {
+		log.Printf("Error deleting job %v: %v", name, err)
+	}
+}
+
+// Run is a no-op; the job manager has no controllers, invocations are
+// driven by Invoke.
+func (jm *JobManager) Run(ctx context.Context) {}
+
+// StartLeading is a no-op; the leader's only duty, reaping, is driven
+// through ReapIdle.
+func (jm *JobManager) StartLeading() {}
+
+// GetFuncSvc fails: job functions have no service, each invocation runs in
+// a job of its own.
+func (jm *JobManager) GetFuncSvc(m *metav1.ObjectMeta, fn *crd.Function, env *crd.Environment) (*fscache.FuncSvc, error) {
+	return nil, fission.MakeError(fission.ErrorInvalidArgument,
+		fmt.Sprintf("function %v uses the %v executor and can only be invoked asynchronously", m.Name, fission.ExecutorTypeJob))
+}
+
+// IsValid returns false, since the job manager never caches services.
+func (jm *JobManager) IsValid(fsvc *fscache.FuncSvc) bool {
+	return false
+}
+
+// Evict is a no-op, since the job manager never caches services.
+func (jm *JobManager) Evict(fsvc *fscache.FuncSvc) error {
+	return nil
+}
+
+// CleanupOldObjects does nothing; the jobs of invocations that previous
+// executor runs left unfinished are deleted by ReapIdle once they're past
+// their deadline.
+func (jm *JobManager) CleanupOldObjects(startTime time.Time) {
+}
+
+// ReapIdle deletes the records of invocations finished longer than
+// invocationRetention ago, and fails the invocations that are still
+// unfinished past their deadline because the executor replica running them
+// went away, deleting their jobs.  It returns the number of jobs deleted.
+func (jm *JobManager) ReapIdle() int {
+	selector := labels.Set(map[string]string{"executorType": fission.ExecutorTypeJob}).AsSelector().String()
+	cmList, err := jm.kubernetesClient.CoreV1().ConfigMaps(jm.namespace).List(metav1.ListOptions{
+		LabelSelector: selector,
+	})
+	if err != nil {
+		log.Printf("Error listing invocations: %v", err)
+		return 0
+	}
+
+	reaped := 0
+	for i := range cmList.Items {
+		cm := &cmList.Items[i]
+		inv, err := decodeInvocation(cm)
+		if err != nil {
+			log.Printf("Error decoding invocation %v: %v", cm.ObjectMeta.Name, err)
+			continue
+		}
+
+		if inv.finished() {
+			if inv.CompletionTime != nil && time.Since(*inv.CompletionTime) > invocationRetention {
+				err = jm.kubernetesClient.CoreV1().ConfigMaps(jm.namespace).Delete(cm.ObjectMeta.Name, nil)
+				if err != nil {
+					log.Printf("Error deleting invocation %v: %v", inv.ID, err)
+				}
+			}
+			continue
+		}
+
+		if time.Since(inv.Deadline) < abandonedGracePeriod {
+			continue
+		}
+		log.Printf("[%v] Failing abandoned invocation %v", inv.Function.Name, inv.ID)
+		inv.complete(InvocationFailed, "invocation was abandoned by the executor")
+		err = jm.saveInvocation(inv, false)
+		if err != nil {
+			log.Printf("Error saving invocation %v: %v", inv.ID, err)
+		}
+		jm.deleteJob(inv.JobName)
+		reaped++
+	}
+	return reaped
+}
@@ -0,0 +1,55 @@
+package jobmgr
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	apiv1 "k8s.io/client-go/pkg/api/v1"
+
+	"github.com/fission/fission"
+)
+
+func TestJobName(t *testing.T) {
+	name := jobName("hello", "abc")
+	if name != "hello-abc" {
+		t.Errorf("expected hello-abc, got %v", name)
+	}
+
+	// the job-name label of the job's pods must stay within 63 characters
+	name = jobName(strings.Repeat("a", 39)+"-"+strings.Repeat("b", 23), "0123456789")
+	if len(name) > 63 {
+		t.Errorf("job name %v is too long", name)
+	}
+	if strings.Contains(name, "--") {
+		t.Errorf("job name %v has an empty segment", name)
+	}
+}
+
+func TestActiveDeadline(t *testing.T) {
+	d := activeDeadline(fission.ExecutionStrategy{})
+	if d != defaultActiveDeadline {
+		t.Errorf("expected default deadline, got %v", d)
+	}
+	d = activeDeadline(fission.ExecutionStrategy{ActiveDeadline: 90})
+	if d != 90*time.Second {
+		t.Errorf("expected 90s, got %v", d)
+	}
+}
+
+func TestTruncateOutput(t *testing.T) {
+	output := []byte(strings.Repeat("x", maxRecordedOutput) + "end")
+	s := truncateOutput(output)
+	if len(s) != maxRecordedOutput || !strings.HasSuffix(s, "end") {
+		t.Errorf("expected the last %v bytes of the output", maxRecordedOutput)
+	}
+}
+
+func TestInvokePodPastDeadline(t *testing.T) {
+	jm := &JobManager{}
+	pod := &apiv1.Pod{Status: apiv1.PodStatus{PodIP: "127.0.0.1"}}
+	_, _, err := jm.invokePod(pod, &InvocationRequest{}, time.Now().Add(-time.Second))
+	if err == nil {
+		t.Errorf("expected an error invoking a function past its deadline")
+	}
+}
@@ -0,0 +1,124 @@
+/*
+Copyright 2018 The Fission Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package executor
+
+import (
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/dchest/uniuri"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	corev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	apiv1 "k8s.io/client-go/pkg/api/v1"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+	"k8s.io/client-go/tools/record"
+)
+
+const (
+	// config map holding the instance id shared by all executor replicas
+	executorConfigMapName = "fission-executor"
+	instanceIdKey         = "instanceId"
+
+	// config map used as the leader election lock
+	executorLeaderLockName = "fission-executor-leader"
+)
+
+// getExecutorInstanceId returns the instance id shared by all executor
+// replicas, creating it if this is the first replica to start. The id
+// outlives the replicas, so it doesn't tell leftovers of previous runs
+// apart; see cleanupObjects.
+func getExecutorInstanceId(kubeClient *kubernetes.Clientset, namespace string) (string, error) {
+	for {
+		cm, err := kubeClient.CoreV1().ConfigMaps(namespace).Get(executorConfigMapName, metav1.GetOptions{})
+		if err == nil {
+			if id, ok := cm.Data[instanceIdKey]; ok {
+				return id, nil
+			}
+		}
+		if err != nil && !k8serrors.IsNotFound(err) {
+			return "", err
+		}
+
+		cm = &apiv1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: executorConfigMapName,
+			},
+			Data: map[string]string{
+				instanceIdKey: strings.ToLower(uniuri.NewLen(8)),
+			},
+		}
+		cm, err = kubeClient.CoreV1().ConfigMaps(namespace).Create(cm)
+		if err == nil {
+			return cm.Data[instanceIdKey], nil
+		}
+		if !k8serrors.IsAlreadyExists(err) {
+			return "", err
+		}
+		// another replica won the race, use its id
+	}
+}
+
+// runLeaderElection blocks campaigning for leadership among the executor
+// replicas, and calls onStartedLeading once this replica is elected.
+// A replica that loses leadership exits, so that it restarts as a
+// follower.
+func runLeaderElection(kubeClient *kubernetes.Clientset, namespace string, onStartedLeading func()) {
+	identity, err := os.Hostname()
+	if err != nil {
+		log.Fatalf("Error getting hostname for leader election: %v", err)
+	}
+
+	broadcaster := record.NewBroadcaster()
+	broadcaster.StartRecordingToSink(&corev1.EventSinkImpl{
+		Interface: kubeClient.CoreV1().Events(namespace),
+	})
+	recorder := broadcaster.NewRecorder(scheme.Scheme, apiv1.EventSource{Component: "executor"})
+
+	lock := &resourcelock.ConfigMapLock{
+		ConfigMapMeta: metav1.ObjectMeta{
+			Namespace: namespace,
+			Name:      executorLeaderLockName,
+		},
+		Client: kubeClient.CoreV1(),
+		LockConfig: resourcelock.ResourceLockConfig{
+			Identity:      identity,
+			EventRecorder: recorder,
+		},
+	}
+
+	leaderelection.RunOrDie(leaderelection.LeaderElectionConfig{
+		Lock:          lock,
+		LeaseDuration: 15 * time.Second,
+		RenewDeadline: 10 * time.Second,
+		RetryPeriod:   2 * time.Second,
+		Callbacks: leaderelection.LeaderCallbacks{
+			OnStartedLeading: func(stop <-chan struct{}) {
+				log.Printf("Executor %v elected leader", identity)
+				onStartedLeading()
+			},
+			OnStoppedLeading: func() {
+				log.Fatalf("Executor %v lost leadership", identity)
+			},
+		},
+	})
+}
@@ -0,0 +1,60 @@
+/*
+Copyright 2018 The Fission Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package executor
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	// function service cache lookups by getServiceForFunction; the hit
+	// ratio is hit / (hit + miss)
+	funcSvcCacheRequests = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "fission",
+			Subsystem: "executor",
+			Name:      "function_service_cache_requests_total",
+			Help:      "Function service cache lookups, by result (hit or miss).",
+		},
+		[]string{"result"},
+	)
+	// requests waiting on another request to create the same function service
+	funcSvcCreateWaiters = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: "fission",
+			Subsystem: "executor",
+			Name:      "function_service_create_waiters",
+			Help:      "Requests currently waiting for a concurrent request to create the same function service.",
+		},
+	)
+	// function services deleted by the idle object reaper
+	idleReaperDeletions = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "fission",
+			Subsystem: "executor",
+			Name:      "idle_reaper_deletions_total",
+			Help:      "Function services deleted by the idle object reaper, by executor type.",
+		},
+		[]string{"executor"},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(funcSvcCacheRequests)
+	prometheus.MustRegister(funcSvcCreateWaiters)
+	prometheus.MustRegister(idleReaperDeletions)
+}
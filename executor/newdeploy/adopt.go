@@ -0,0 +1,154 @@
+/*
+Copyright 2018 The Fission Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package newdeploy
+
+import (
+	"fmt"
+	"log"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/pkg/api"
+	apiv1 "k8s.io/client-go/pkg/api/v1"
+	asv1 "k8s.io/client-go/pkg/apis/autoscaling/v1"
+	"k8s.io/client-go/pkg/apis/extensions/v1beta1"
+
+	"github.com/fission/fission"
+	"github.com/fission/fission/crd"
+	"github.com/fission/fission/executor/fscache"
+)
+
+// adoptExistingResources adds the deployments, services and HPAs created
+// for newdeploy functions by a previous executor instance back to the
+// function service cache, as long as their function hasn't changed since.
+// It returns references to the adopted objects; everything else is left
+// for cleanup.
+//
+// This must run before the function informer starts.
+func (deploy *NewDeploy) adoptExistingResources() []api.ObjectReference {
+	adopted := make([]api.ObjectReference, 0)
+
+	fns, err := deploy.fissionClient.Functions(metav1.NamespaceAll).List(metav1.ListOptions{})
+	if err != nil {
+		log.Printf("Error listing functions, not adopting existing deployments: %v", err)
+		return adopted
+	}
+	fnByUID := make(map[types.UID]*crd.Function)
+	for i := range fns.Items {
+		fnByUID[fns.Items[i].Metadata.UID] = &fns.Items[i]
+	}
+
+	deplList, err := deploy.kubernetesClient.ExtensionsV1beta1().Deployments(deploy.namespace).List(
+		metav1.ListOptions{
+			LabelSelector: labels.Set(map[string]string{
+				"executorType": fission.ExecutorTypeNewdeploy,
+			}).AsSelector().String(),
+		})
+	if err != nil {
+		log.Printf("Error listing deployments, not adopting them: %v", err)
+		return adopted
+	}
+
+	for i := range deplList.Items {
+		depl := &deplList.Items[i]
+
+		fn, ok := fnByUID[types.UID(depl.ObjectMeta.Labels["functionUid"])]
+		if !ok || fn.Spec.InvokeStrategy.ExecutionStrategy.ExecutorType != fission.ExecutorTypeNewdeploy ||
+			depl.ObjectMeta.Annotations[fission.FUNCTION_RESOURCEVERSION_ANNOTATION] != fn.Metadata.ResourceVersion {
+			continue
+		}
+		if _, err := deploy.fsCache.GetByFunctionUID(fn.Metadata.UID); err == nil {
+			continue
+		}
+
+		env, err := deploy.fissionClient.
+			Environments(fn.Spec.Environment.Namespace).
+			Get(fn.Spec.Environment.Name)
+		if err != nil {
+			continue
+		}
+
+		// the service and HPA are named after the deployment
+		objName := depl.ObjectMeta.Name
+		svc, err := deploy.kubernetesClient.CoreV1().Services(deploy.namespace).Get(objName, metav1.GetOptions{})
+		if err != nil {
+			continue
+		}
+		var hpa *asv1.HorizontalPodAutoscaler
+		if !isRequestScaled(fn.Spec.InvokeStrategy.ExecutionStrategy) {
+			hpa, err = deploy.kubernetesClient.AutoscalingV1().HorizontalPodAutoscalers(deploy.namespace).Get(objName, metav1.GetOptions{})
+			if err != nil {
+				continue
+			}
+		}
+
+		fsvc := &fscache.FuncSvc{
+			Name:              objName,
+			Function:          &fn.Metadata,
+			Environment:       env,
+			Address:           fmt.Sprintf("%v.%v", svc.Name, svc.Namespace),
+			KubernetesObjects: kubeObjRefs(depl, svc, hpa),
+			Executor:          fscache.NEWDEPLOY,
+		}
+		_, err = deploy.fsCache.Add(*fsvc)
+		if err != nil {
+			log.Printf("Error adopting deployment %v: %v", objName, err)
+			continue
+		}
+
+		log.Printf("Adopted deployment %v for function %v", objName, fn.Metadata.Name)
+		adopted = append(adopted, fsvc.KubernetesObjects...)
+	}
+
+	return adopted
+}
+
+// kubeObjRefs returns references to the objects backing a newdeploy
+// function service.  Functions scaled on requests have no HPA.
+func kubeObjRefs(depl *v1beta1.Deployment, svc *apiv1.Service, hpa *asv1.HorizontalPodAutoscaler) []api.ObjectReference {
+	refs := []api.ObjectReference{
+		{
+			//obj.TypeMeta.Kind does not work hence this, needs investigationa and a fix
+			Kind:            "deployment",
+			Name:            depl.ObjectMeta.Name,
+			APIVersion:      depl.TypeMeta.APIVersion,
+			Namespace:       depl.ObjectMeta.Namespace,
+			ResourceVersion: depl.ObjectMeta.ResourceVersion,
+			UID:             depl.ObjectMeta.UID,
+		},
+		{
+			Kind:            "service",
+			Name:            svc.ObjectMeta.Name,
+			APIVersion:      svc.TypeMeta.APIVersion,
+			Namespace:       svc.ObjectMeta.Namespace,
+			ResourceVersion: svc.ObjectMeta.ResourceVersion,
+			UID:             svc.ObjectMeta.UID,
+		},
+	}
+	if hpa != nil {
+		refs = append(refs, api.ObjectReference{
+			Kind:            "horizontalpodautoscaler",
+			Name:            hpa.ObjectMeta.Name,
+			APIVersion:      hpa.TypeMeta.APIVersion,
+			Namespace:       hpa.ObjectMeta.Namespace,
+			ResourceVersion: hpa.ObjectMeta.ResourceVersion,
+			UID:             hpa.ObjectMeta.UID,
+		})
+	}
+	return refs
+}
@@ -0,0 +1,119 @@
+/*
+Copyright 2018 The Fission Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package newdeploy
+
+import (
+	"log"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/fission/fission"
+	"github.com/fission/fission/crd"
+	"github.com/fission/fission/executor/util"
+)
+
+// CleanupOldObjects deletes the services, HPAs and deployments of newdeploy
+// functions that previous executor runs left behind, except the adopted
+// ones.  The pods of deleted deployments go with them.
+func (deploy *NewDeploy) CleanupOldObjects(startTime time.Time) {
+	adopted := make(map[types.UID]bool)
+	for _, obj := range deploy.adoptedObjects {
+		adopted[obj.UID] = true
+	}
+	listOpts := metav1.ListOptions{
+		LabelSelector: labels.Set(map[string]string{
+			"executorType": fission.ExecutorTypeNewdeploy,
+		}).AsSelector().String(),
+	}
+
+	deplList, err := deploy.kubernetesClient.ExtensionsV1beta1().Deployments(deploy.namespace).List(listOpts)
+	if err != nil {
+		log.Printf("Failed to cleanup deployments: %v", err)
+		return
+	}
+	fns, err := deploy.fissionClient.Functions(metav1.NamespaceAll).List(metav1.ListOptions{})
+	if err != nil {
+		log.Printf("Failed to cleanup deployments: %v", err)
+		return
+	}
+	fnByUID := make(map[types.UID]*crd.Function)
+	for i := range fns.Items {
+		fnByUID[fns.Items[i].Metadata.UID] = &fns.Items[i]
+	}
+
+	// Deployments scaled to zero while idle aren't adopted, since they
+	// have no instances to cache, but they're kept along with their
+	// service for as long as their function is unchanged.
+	scaledToZero := make(map[string]bool)
+	for i := range deplList.Items {
+		depl := &deplList.Items[i]
+		fn, ok := fnByUID[types.UID(depl.ObjectMeta.Labels["functionUid"])]
+		if ok && isScaledToZero(depl) &&
+			depl.ObjectMeta.Annotations[fission.FUNCTION_RESOURCEVERSION_ANNOTATION] == fn.Metadata.ResourceVersion {
+			scaledToZero[depl.ObjectMeta.Name] = true
+		}
+	}
+
+	isLeftover := func(obj *metav1.ObjectMeta) bool {
+		return !adopted[obj.UID] && !scaledToZero[obj.Name] && util.IsLeftover(obj, startTime)
+	}
+
+	svcList, err := deploy.kubernetesClient.CoreV1().Services(deploy.namespace).List(listOpts)
+	if err != nil {
+		log.Printf("Failed to cleanup services: %v", err)
+		return
+	}
+	for _, svc := range svcList.Items {
+		if isLeftover(&svc.ObjectMeta) {
+			log.Printf("Cleaning up svc %v", svc.ObjectMeta.Name)
+			err := deploy.kubernetesClient.CoreV1().Services(deploy.namespace).Delete(svc.ObjectMeta.Name, nil)
+			if err != nil {
+				log.Printf("Error cleaning up service %v: %v", svc.ObjectMeta.Name, err)
+			}
+		}
+	}
+
+	hpaList, err := deploy.kubernetesClient.AutoscalingV1().HorizontalPodAutoscalers(deploy.namespace).List(listOpts)
+	if err != nil {
+		log.Printf("Failed to cleanup HPAs: %v", err)
+		return
+	}
+	for _, hpa := range hpaList.Items {
+		if isLeftover(&hpa.ObjectMeta) {
+			log.Printf("Cleaning up HPA %v", hpa.ObjectMeta.Name)
+			err := deploy.kubernetesClient.AutoscalingV1().HorizontalPodAutoscalers(deploy.namespace).Delete(hpa.ObjectMeta.Name, nil)
+			if err != nil {
+				log.Printf("Error cleaning up HPA %v: %v", hpa.ObjectMeta.Name, err)
+			}
+		}
+	}
+
+	deletePropagation := metav1.DeletePropagationBackground
+	delOpt := metav1.DeleteOptions{PropagationPolicy: &deletePropagation}
+	for _, depl := range deplList.Items {
+		if isLeftover(&depl.ObjectMeta) {
+			log.Printf("Cleaning up deployment %v", depl.ObjectMeta.Name)
+			err := deploy.kubernetesClient.ExtensionsV1beta1().Deployments(deploy.namespace).Delete(depl.ObjectMeta.Name, &delOpt)
+			if err != nil {
+				log.Printf("Error cleaning up deployment %v: %v", depl.ObjectMeta.Name, err)
+			}
+		}
+	}
+}
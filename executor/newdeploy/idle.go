@@ -0,0 +1,202 @@
+/*
+Copyright 2018 The Fission Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package newdeploy
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	k8s_err "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/pkg/apis/extensions/v1beta1"
+
+	"github.com/fission/fission"
+	"github.com/fission/fission/crd"
+	"github.com/fission/fission/executor/fscache"
+)
+
+// Deployments of functions with a MinScale of 0 are scaled to zero replicas
+// once they've been idle for the function's idle timeout.  The deployment
+// and service are kept, so that the next request only has to wait for the
+// deployment to be scaled back up.
+
+// DefaultIdleTimeout is how long a newdeploy function may stay unused before
+// its deployment is scaled to zero, unless the function or its environment
+// set an IdleTimeout.
+const DefaultIdleTimeout = 5 * time.Minute
+
+// idleTimeout returns how long the function's deployment may stay unused.
+func idleTimeout(fn *crd.Function, env *crd.Environment) time.Duration {
+	if fn.Spec.InvokeStrategy.ExecutionStrategy.IdleTimeout > 0 {
+		return time.Duration(fn.Spec.InvokeStrategy.ExecutionStrategy.IdleTimeout) * time.Second
+	}
+	if env.Spec.IdleTimeout > 0 {
+		return time.Duration(env.Spec.IdleTimeout) * time.Second
+	}
+	return DefaultIdleTimeout
+}
+
+// deploymentLastAccessTime returns when a function's deployment was last
+// used, as recorded on it by the executor replicas.
+func deploymentLastAccessTime(depl *v1beta1.Deployment) time.Time {
+	t, err := time.Parse(time.RFC3339, depl.ObjectMeta.Annotations[fission.LAST_ACCESS_TIME_ANNOTATION])
+	if err != nil {
+		return depl.ObjectMeta.CreationTimestamp.Time
+	}
+	return t
+}
+
+// deploymentIdleTimeout returns how long a function's deployment may stay
+// unused.
+func deploymentIdleTimeout(depl *v1beta1.Deployment) time.Duration {
+	d, err := time.ParseDuration(depl.ObjectMeta.Annotations[fission.IDLE_TIMEOUT_ANNOTATION])
+	if err != nil || d <= 0 {
+		return DefaultIdleTimeout
+	}
+	return d
+}
+
+// isScaledToZero returns true if the deployment was scaled down while idle.
+func isScaledToZero(depl *v1beta1.Deployment) bool {
+	return depl.Spec.Replicas != nil && *depl.Spec.Replicas == 0
+}
+
+// ReapIdle scales the deployments of functions with a MinScale of 0 to zero
+// replicas once they've been idle for their idle timeout, and deletes their
+// HPA.  It runs on the leader replica only, so it goes by the access times
+// recorded on the deployments by every replica.
+func (deploy *NewDeploy) ReapIdle() int {
+	if !deploy.funcController.HasSynced() {
+		// the MinScale of functions isn't known yet
+		return 0
+	}
+
+	deplList, err := deploy.kubernetesClient.ExtensionsV1beta1().Deployments(deploy.namespace).List(
+		metav1.ListOptions{
+			LabelSelector: labels.Set(map[string]string{
+				"executorType": fission.ExecutorTypeNewdeploy,
+			}).AsSelector().String(),
+		})
+	if err != nil {
+		log.Printf("Error listing deployments to reap: %v", err)
+		return 0
+	}
+
+	reaped := 0
+	for i := range deplList.Items {
+		depl := &deplList.Items[i]
+		if isScaledToZero(depl) {
+			continue
+		}
+		fn := deploy.getFunction(&metav1.ObjectMeta{
+			Name:      depl.ObjectMeta.Labels["functionName"],
+			Namespace: depl.ObjectMeta.Namespace,
+		})
+		if fn == nil || string(fn.Metadata.UID) != depl.ObjectMeta.Labels["functionUid"] ||
+			fn.Spec.InvokeStrategy.ExecutionStrategy.MinScale > 0 {
+			continue
+		}
+
+		// this replica's own accesses may not have been recorded yet;
+		// the cache is listed, since getting an entry counts as an
+		// access
+		lastAccess := deploymentLastAccessTime(depl)
+		fsvcs, _ := deploy.fsCache.ListByFunctionUID(fn.Metadata.UID)
+		for _, fsvc := range fsvcs {
+			if fsvc.Atime.After(lastAccess) {
+				lastAccess = fsvc.Atime
+			}
+		}
+		if time.Since(lastAccess) < deploymentIdleTimeout(depl) {
+			continue
+		}
+
+		// the HPA goes first, or it would scale the deployment right
+		// back up
+		err := deploy.deleteHpa(deploy.namespace, depl.ObjectMeta.Name)
+		if err != nil && !k8s_err.IsNotFound(err) {
+			log.Printf("Error deleting the HPA of idle deployment %v: %v", depl.ObjectMeta.Name, err)
+			continue
+		}
+
+		log.Printf("[%v] Scaling deployment %v idle since %v to zero", fn.Metadata.Name, depl.ObjectMeta.Name, lastAccess)
+		err = deploy.scaleDeployment(depl.ObjectMeta.Name, 0)
+		if err != nil {
+			log.Printf("Error scaling deployment %v to zero: %v", depl.ObjectMeta.Name, err)
+			continue
+		}
+		reaped++
+
+		for _, fsvc := range fsvcs {
+			deploy.fsCache.DeleteEntry(fsvc)
+		}
+	}
+	return reaped
+}
+
+// scaleDeployment sets the replicas of a function's deployment.  The access
+// time is recorded along, so that a deployment scaled up isn't reaped
+// before its first request.
+func (deploy *NewDeploy) scaleDeployment(name string, replicas int32) error {
+	patch := fmt.Sprintf(`{"metadata":{"annotations":{%q:%q}},"spec":{"replicas":%d}}`,
+		fission.LAST_ACCESS_TIME_ANNOTATION, time.Now().UTC().Format(time.RFC3339), replicas)
+	_, err := deploy.kubernetesClient.ExtensionsV1beta1().Deployments(deploy.namespace).Patch(
+		name, types.StrategicMergePatchType, []byte(patch))
+	return err
+}
+
+// accessTimeSyncer periodically records on their deployments when the
+// function services in this replica's cache were last used, so that the
+// reaper on the leader replica sees accesses made through any replica.
+func (deploy *NewDeploy) accessTimeSyncer(ctx context.Context) {
+	ticker := time.NewTicker(15 * time.Second)
+	defer ticker.Stop()
+
+	lastSync := time.Now()
+	for {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+		syncTime := time.Now()
+
+		fsvcs, err := deploy.fsCache.List()
+		if err != nil {
+			log.Printf("Error syncing access times: %v", err)
+			continue
+		}
+
+		for _, fsvc := range fsvcs {
+			if fsvc.Executor != fscache.NEWDEPLOY || !fsvc.Atime.After(lastSync) {
+				continue
+			}
+			patch := fmt.Sprintf(`{"metadata":{"annotations":{%q:%q}}}`,
+				fission.LAST_ACCESS_TIME_ANNOTATION, fsvc.Atime.UTC().Format(time.RFC3339))
+			_, err := deploy.kubernetesClient.ExtensionsV1beta1().Deployments(deploy.namespace).Patch(
+				fsvc.Name, types.StrategicMergePatchType, []byte(patch))
+			if err != nil {
+				log.Printf("Error recording access time of deployment %v: %v", fsvc.Name, err)
+			}
+		}
+		lastSync = syncTime
+	}
+}
@@ -0,0 +1,139 @@
+package newdeploy
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/pkg/apis/extensions/v1beta1"
+	k8stesting "k8s.io/client-go/testing"
+	k8sCache "k8s.io/client-go/tools/cache"
+
+	"github.com/fission/fission"
+	"github.com/fission/fission/crd"
+	"github.com/fission/fission/executor/fscache"
+)
+
+func TestIdleTimeout(t *testing.T) {
+	fn := &crd.Function{}
+	env := &crd.Environment{}
+	if d := idleTimeout(fn, env); d != DefaultIdleTimeout {
+		t.Errorf("expected the default idle timeout, got %v", d)
+	}
+	env.Spec.IdleTimeout = 60
+	if d := idleTimeout(fn, env); d != time.Minute {
+		t.Errorf("expected the environment's idle timeout, got %v", d)
+	}
+	fn.Spec.InvokeStrategy.ExecutionStrategy.IdleTimeout = 30
+	if d := idleTimeout(fn, env); d != 30*time.Second {
+		t.Errorf("expected the function's idle timeout, got %v", d)
+	}
+}
+
+func TestDeploymentIdleState(t *testing.T) {
+	created := time.Now().Add(-time.Hour).Truncate(time.Second)
+	depl := &v1beta1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			CreationTimestamp: metav1.NewTime(created),
+			Annotations:       map[string]string{},
+		},
+	}
+	if !deploymentLastAccessTime(depl).Equal(created) {
+		t.Errorf("expected a deployment never accessed to be idle since its creation")
+	}
+	if d := deploymentIdleTimeout(depl); d != DefaultIdleTimeout {
+		t.Errorf("expected the default idle timeout, got %v", d)
+	}
+	if isScaledToZero(depl) {
+		t.Errorf("expected a deployment without replicas set not to be scaled to zero")
+	}
+
+	accessed := time.Now().Truncate(time.Second)
+	depl.ObjectMeta.Annotations[fission.LAST_ACCESS_TIME_ANNOTATION] = accessed.UTC().Format(time.RFC3339)
+	depl.ObjectMeta.Annotations[fission.IDLE_TIMEOUT_ANNOTATION] = (90 * time.Second).String()
+	replicas := int32(0)
+	depl.Spec.Replicas = &replicas
+	if !deploymentLastAccessTime(depl).Equal(accessed) {
+		t.Errorf("expected the recorded access time, got %v", deploymentLastAccessTime(depl))
+	}
+	if d := deploymentIdleTimeout(depl); d != 90*time.Second {
+		t.Errorf("expected the recorded idle timeout, got %v", d)
+	}
+	if !isScaledToZero(depl) {
+		t.Errorf("expected the deployment to be scaled to zero")
+	}
+}
+
+// syncedController is a function controller that has synced.
+type syncedController struct{}
+
+func (syncedController) Run(stopCh <-chan struct{})      {}
+func (syncedController) HasSynced() bool                 { return true }
+func (syncedController) LastSyncResourceVersion() string { return "" }
+
+func TestReapIdle(t *testing.T) {
+	const namespace = "fission-function"
+	fn := &crd.Function{
+		Metadata: metav1.ObjectMeta{Name: "hello", Namespace: "default", UID: types.UID("fn"), ResourceVersion: "1"},
+	}
+	replicas := int32(1)
+	depl := &v1beta1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "hello-1",
+			Namespace: namespace,
+			Labels: map[string]string{
+				"executorType": fission.ExecutorTypeNewdeploy,
+				"functionName": "hello",
+				"functionUid":  "fn",
+			},
+			// recorded by a replica a while ago
+			Annotations: map[string]string{
+				fission.LAST_ACCESS_TIME_ANNOTATION: time.Now().Add(-time.Hour).UTC().Format(time.RFC3339),
+			},
+		},
+		Spec: v1beta1.DeploymentSpec{Replicas: &replicas},
+	}
+	client := fake.NewSimpleClientset(depl)
+	var patch string
+	client.PrependReactor("patch", "deployments", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		patch = string(action.(k8stesting.PatchAction).GetPatch())
+		return true, depl, nil
+	})
+
+	funcStore := k8sCache.NewStore(func(obj interface{}) (string, error) {
+		m := obj.(*crd.Function).Metadata
+		return m.Namespace + "/" + m.Name, nil
+	})
+	funcStore.Add(fn)
+	deploy := &NewDeploy{
+		kubernetesClient: client,
+		namespace:        namespace,
+		fsCache:          fscache.MakeFunctionServiceCache(),
+		funcStore:        funcStore,
+		funcController:   syncedController{},
+	}
+	_, err := deploy.fsCache.Add(fscache.FuncSvc{
+		Name:     depl.ObjectMeta.Name,
+		Function: &fn.Metadata,
+		Address:  "hello-1." + namespace,
+		Executor: fscache.NEWDEPLOY,
+		Atime:    time.Now().Add(-time.Hour),
+	})
+	if err != nil {
+		t.Fatalf("error caching function service: %v", err)
+	}
+
+	if n := deploy.ReapIdle(); n != 1 {
+		t.Fatalf("expected the idle deployment to be reaped, got %v reaped", n)
+	}
+	if !strings.Contains(patch, `"replicas":0`) {
+		t.Errorf("expected the deployment to be scaled to zero, got patch %v", patch)
+	}
+	if _, err := deploy.fsCache.ListByFunctionUID(fn.Metadata.UID); err == nil {
+		t.Errorf("expected the cache entry of the reaped deployment to be deleted")
+	}
+}
@@ -0,0 +1,97 @@
+/*
+Copyright 2018 The Fission Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package newdeploy
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	apiv1 "k8s.io/client-go/pkg/api/v1"
+
+	"github.com/fission/fission/crd"
+)
+
+// Functions with an Image run in a container of that image, which serves
+// them over HTTP on the function's port.  There's no package to fetch and
+// no specialization, so their pods have neither the fetcher nor its
+// volumes.
+
+// defaultFunctionPort is the port the runtime of an environment serves the
+// function on, and the port of an Image unless the function sets one.
+const defaultFunctionPort = 8888
+
+// functionPort returns the port the function's pods serve it on.
+func functionPort(fn *crd.Function) int {
+	if len(fn.Spec.Image) > 0 && fn.Spec.Port > 0 {
+		return fn.Spec.Port
+	}
+	return defaultFunctionPort
+}
+
+// getImagePodSpec returns the spec of the pods of a function with an Image.
+// Unlike the runtime of an environment, the image may not have a shell to
+// run a preStop hook in, so connections are drained within the termination
+// grace period only.
+func getImagePodSpec(fn *crd.Function, env []apiv1.EnvVar, resources apiv1.ResourceRequirements,
+	gracePeriodSeconds int64) apiv1.PodSpec {
+
+	port := functionPort(fn)
+	return apiv1.PodSpec{
+		Containers: []apiv1.Container{
+			{
+				Name:                   fn.Metadata.Name,
+				Image:                  fn.Spec.Image,
+				ImagePullPolicy:        apiv1.PullIfNotPresent,
+				TerminationMessagePath: "/dev/termination-log",
+				Ports: []apiv1.ContainerPort{
+					{
+						Name:          "http",
+						ContainerPort: int32(port),
+					},
+				},
+				Env:       env,
+				Resources: resources,
+				ReadinessProbe: &apiv1.Probe{
+					InitialDelaySeconds: 1,
+					PeriodSeconds:       1,
+					FailureThreshold:    30,
+					Handler: apiv1.Handler{
+						TCPSocket: &apiv1.TCPSocketAction{
+							Port: intstr.FromInt(port),
+						},
+					},
+				},
+			},
+		},
+		TerminationGracePeriodSeconds: &gracePeriodSeconds,
+	}
+}
+
+// updateSvcTargetPort points the function's service at the port its pods
+// now serve it on.
+func (deploy *NewDeploy) updateSvcTargetPort(svcName string, port int) error {
+	svc, err := deploy.kubernetesClient.CoreV1().Services(deploy.namespace).Get(svcName, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	for i := range svc.Spec.Ports {
+		if svc.Spec.Ports[i].Name == runtimePortName {
+			svc.Spec.Ports[i].TargetPort = intstr.FromInt(port)
+		}
+	}
+	_, err = deploy.kubernetesClient.CoreV1().Services(deploy.namespace).Update(svc)
+	return err
+}
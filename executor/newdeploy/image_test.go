@@ -0,0 +1,51 @@
+package newdeploy
+
+import (
+	"testing"
+
+	apiv1 "k8s.io/client-go/pkg/api/v1"
+
+	"github.com/fission/fission/crd"
+)
+
+func TestFunctionPort(t *testing.T) {
+	fn := &crd.Function{}
+	fn.Spec.Port = 8080
+	if p := functionPort(fn); p != defaultFunctionPort {
+		t.Errorf("expected the runtime's port for a function without an image, got %v", p)
+	}
+	fn.Spec.Image = "example/fn"
+	if p := functionPort(fn); p != 8080 {
+		t.Errorf("expected the function's port, got %v", p)
+	}
+	fn.Spec.Port = 0
+	if p := functionPort(fn); p != defaultFunctionPort {
+		t.Errorf("expected the default port, got %v", p)
+	}
+}
+
+func TestGetImagePodSpec(t *testing.T) {
+	fn := &crd.Function{}
+	fn.Metadata.Name = "hello"
+	fn.Spec.Image = "example/hello"
+	fn.Spec.Port = 8080
+	env := []apiv1.EnvVar{{Name: "GREETING", Value: "hi"}}
+
+	spec := getImagePodSpec(fn, env, apiv1.ResourceRequirements{}, 30)
+	if len(spec.Containers) != 1 {
+		t.Fatalf("expected only the function's container, got %v", len(spec.Containers))
+	}
+	c := spec.Containers[0]
+	if c.Name != "hello" || c.Image != "example/hello" {
+		t.Errorf("unexpected container %v running %v", c.Name, c.Image)
+	}
+	if c.ReadinessProbe.TCPSocket.Port.IntValue() != 8080 {
+		t.Errorf("expected the readiness probe on the function's port, got %v", c.ReadinessProbe.TCPSocket.Port.IntValue())
+	}
+	if len(c.Env) != 1 || c.Env[0] != env[0] {
+		t.Errorf("expected the function's environment, got %v", c.Env)
+	}
+	if len(spec.Volumes) != 0 || len(spec.ServiceAccountName) != 0 {
+		t.Errorf("expected no fetcher volumes or service account")
+	}
+}
@@ -0,0 +1,38 @@
+/*
+Copyright 2018 The Fission Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package newdeploy
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	// time from creating a function deployment until it has ready replicas
+	deploymentCreationDuration = prometheus.NewHistogram(
+		prometheus.HistogramOpts{
+			Namespace: "fission",
+			Subsystem: "executor",
+			Name:      "newdeploy_deployment_creation_duration_seconds",
+			Help:      "Time taken to create a newdeploy function deployment and wait for it to be ready.",
+			Buckets:   []float64{1, 2.5, 5, 10, 20, 30, 60, 90, 120},
+		},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(deploymentCreationDuration)
+}
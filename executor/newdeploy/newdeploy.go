@@ -26,10 +26,9 @@ import (
 	"time"
 
 	k8s_err "k8s.io/apimachinery/pkg/api/errors"
-	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/util/intstr"
-	"k8s.io/client-go/pkg/api/v1"
 	apiv1 "k8s.io/client-go/pkg/api/v1"
 	asv1 "k8s.io/client-go/pkg/apis/autoscaling/v1"
 	"k8s.io/client-go/pkg/apis/extensions/v1beta1"
@@ -47,8 +46,25 @@ const (
 
 const (
 	envVersion = "ENV_VERSION"
+
+	// runtimePortName is the name of the port of a function's service
+	// that requests to the function are sent to.
+	runtimePortName = "runtime-env-port"
+
+	// DefaultDeployReadyTimeout is how long to wait for a function's
+	// deployment to be ready when its environment doesn't set a timeout.
+	DefaultDeployReadyTimeout = 2 * time.Minute
 )
 
+// deployReadyTimeout returns how long to wait for a deployment of the
+// environment to be ready.
+func deployReadyTimeout(env *crd.Environment) time.Duration {
+	if env.Spec.PodReadyTimeout > 0 {
+		return time.Duration(env.Spec.PodReadyTimeout) * time.Second
+	}
+	return DefaultDeployReadyTimeout
+}
+
 func (deploy *NewDeploy) createOrGetDeployment(fn *crd.Function, env *crd.Environment,
 	deployName string, deployLabels map[string]string) (*v1beta1.Deployment, error) {
 
@@ -59,8 +75,15 @@ func (deploy *NewDeploy) createOrGetDeployment(fn *crd.Function, env *crd.Enviro
 
 	existingDepl, err := deploy.kubernetesClient.ExtensionsV1beta1().Deployments(deploy.namespace).Get(deployName, metav1.GetOptions{})
 	if err == nil {
+		if isScaledToZero(existingDepl) {
+			log.Printf("Scaling idle deployment %v back up to %v replicas", deployName, replicas)
+			err = deploy.scaleDeployment(deployName, replicas)
+			if err != nil {
+				return nil, err
+			}
+		}
 		if existingDepl.Status.ReadyReplicas < replicas {
-			existingDepl, err = deploy.waitForDeploy(existingDepl, replicas)
+			existingDepl, err = deploy.waitForDeploy(existingDepl, replicas, deployReadyTimeout(env))
 		}
 		return existingDepl, err
 	}
@@ -72,13 +95,22 @@ func (deploy *NewDeploy) createOrGetDeployment(fn *crd.Function, env *crd.Enviro
 			return nil, err
 		}
 
+		startTime := time.Now()
 		depl, err := deploy.kubernetesClient.ExtensionsV1beta1().Deployments(deploy.namespace).Create(deployment)
 		if err != nil {
 			log.Printf("Error while creating deployment: %v", err)
 			return nil, err
 		}
+		err = deploy.setEnvOwner(depl)
+		if err != nil {
+			return nil, err
+		}
 
-		return deploy.waitForDeploy(depl, replicas)
+		depl, err = deploy.waitForDeploy(depl, replicas, deployReadyTimeout(env))
+		if err == nil {
+			deploymentCreationDuration.Observe(time.Since(startTime).Seconds())
+		}
+		return depl, err
 	}
 
 	return nil, err
@@ -95,6 +127,17 @@ func (deploy *NewDeploy) updateDeployment(deployment *v1beta1.Deployment) error
 	return err
 }
 
+// setEnvOwner makes the deployment own the copy of its function's secret
+// and configmap keys, if any, so that it's deleted with the deployment.
+func (deploy *NewDeploy) setEnvOwner(depl *v1beta1.Deployment) error {
+	return util.SetFunctionEnvOwner(deploy.kubernetesClient, deploy.namespace, depl.ObjectMeta.Name, metav1.OwnerReference{
+		APIVersion: DeploymentVersion,
+		Kind:       DeploymentKind,
+		Name:       depl.ObjectMeta.Name,
+		UID:        depl.ObjectMeta.UID,
+	})
+}
+
 func (deploy *NewDeploy) deleteDeployment(ns string, name string) error {
 	// DeletePropagationBackground deletes the object immediately and dependent are deleted later
 	// DeletePropagationForeground not advisable; it markes for deleteion and API can still serve those objects
@@ -132,6 +175,14 @@ func (deploy *NewDeploy) getDeploymentSpec(fn *crd.Function, env *crd.Environmen
 		ConfigMaps: fn.Spec.ConfigMaps,
 	}
 
+	// the function's environment is set on its container, and isn't
+	// passed in the load request, which ends up on the fetcher's command
+	// line
+	fnEnv, envChecksum, err := util.GetFunctionEnv(deploy.kubernetesClient, fn, deploy.namespace, deployName)
+	if err != nil {
+		return nil, err
+	}
+
 	loadReq := fission.FunctionLoadRequest{
 		FilePath:         filepath.Join(deploy.sharedMountPath, targetFilename),
 		FunctionName:     fn.Spec.Package.FunctionName,
@@ -157,12 +208,19 @@ func (deploy *NewDeploy) getDeploymentSpec(fn *crd.Function, env *crd.Environmen
 	if deploy.useIstio && env.Spec.AllowAccessToExternalNetwork {
 		podAnnotation["sidecar.istio.io/inject"] = "false"
 	}
-	resources := deploy.getResources(env, fn)
+	if len(envChecksum) > 0 {
+		podAnnotation[util.FunctionEnvChecksumAnnotation] = envChecksum
+	}
+	resources := util.GetFunctionResources(env, fn)
 
 	deployment := &v1beta1.Deployment{
 		ObjectMeta: metav1.ObjectMeta{
 			Labels: deployLabels,
 			Name:   deployName,
+			Annotations: map[string]string{
+				fission.FUNCTION_RESOURCEVERSION_ANNOTATION: fn.Metadata.ResourceVersion,
+				fission.IDLE_TIMEOUT_ANNOTATION:             idleTimeout(fn, env).String(),
+			},
 		},
 		Spec: v1beta1.DeploymentSpec{
 			Replicas: &replicas,
@@ -226,6 +284,7 @@ func (deploy *NewDeploy) getDeploymentSpec(fn *crd.Function, env *crd.Environmen
 								},
 							},
 							Resources: resources,
+							Env:       fnEnv,
 						}, env.Spec.Runtime.Container),
 						{
 							Name:                   "fetcher",
@@ -305,41 +364,12 @@ func (deploy *NewDeploy) getDeploymentSpec(fn *crd.Function, env *crd.Environmen
 		},
 	}
 
-	return deployment, nil
-}
-
-// getResources overrides only the resources which are overridden at function level otherwise
-// default to resources specified at environment level
-func (deploy *NewDeploy) getResources(env *crd.Environment, fn *crd.Function) v1.ResourceRequirements {
-	resources := env.Spec.Resources
-	if resources.Requests == nil {
-		resources.Requests = make(map[v1.ResourceName]resource.Quantity)
-	}
-	if resources.Limits == nil {
-		resources.Limits = make(map[v1.ResourceName]resource.Quantity)
-	}
-	// Only override the once specified at function, rest default to values from env.
-	_, ok := fn.Spec.Resources.Requests[v1.ResourceCPU]
-	if ok {
-		resources.Requests[v1.ResourceCPU] = fn.Spec.Resources.Requests[v1.ResourceCPU]
-	}
-
-	_, ok = fn.Spec.Resources.Requests[v1.ResourceMemory]
-	if ok {
-		resources.Requests[v1.ResourceMemory] = fn.Spec.Resources.Requests[v1.ResourceMemory]
+	if len(fn.Spec.Image) > 0 {
+		deployment.Spec.Template.Spec = getImagePodSpec(fn, fnEnv, resources, gracePeriodSeconds)
 	}
+	deployment.Spec.Template.Spec = util.MergeFunctionPodSpec(&deployment.Spec.Template.Spec, env, fn)
 
-	_, ok = fn.Spec.Resources.Limits[v1.ResourceCPU]
-	if ok {
-		resources.Limits[v1.ResourceCPU] = fn.Spec.Resources.Limits[v1.ResourceCPU]
-	}
-
-	_, ok = fn.Spec.Resources.Limits[v1.ResourceMemory]
-	if ok {
-		resources.Limits[v1.ResourceMemory] = fn.Spec.Resources.Limits[v1.ResourceMemory]
-	}
-
-	return resources
+	return deployment, nil
 }
 
 func (deploy *NewDeploy) createOrGetHpa(hpaName string, execStrategy *fission.ExecutionStrategy, depl *v1beta1.Deployment) (*asv1.HorizontalPodAutoscaler, error) {
@@ -348,7 +378,11 @@ func (deploy *NewDeploy) createOrGetHpa(hpaName string, execStrategy *fission.Ex
 	if minRepl == 0 {
 		minRepl = 1
 	}
+	// at most the scale counted against the namespace's quota
 	maxRepl := int32(execStrategy.MaxScale)
+	if maxRepl < minRepl {
+		maxRepl = minRepl
+	}
 	targetCPU := int32(execStrategy.TargetCPUPercent)
 
 	existingHpa, err := deploy.kubernetesClient.AutoscalingV1().HorizontalPodAutoscalers(deploy.namespace).Get(hpaName, metav1.GetOptions{})
@@ -405,7 +439,7 @@ func (deploy *NewDeploy) deleteHpa(ns string, name string) error {
 	return err
 }
 
-func (deploy *NewDeploy) createOrGetSvc(deployLabels map[string]string, svcName string) (*apiv1.Service, error) {
+func (deploy *NewDeploy) createOrGetSvc(deployLabels map[string]string, svcName string, targetPort int) (*apiv1.Service, error) {
 
 	existingSvc, err := deploy.kubernetesClient.CoreV1().Services(deploy.namespace).Get(svcName, metav1.GetOptions{})
 	if err == nil {
@@ -421,9 +455,9 @@ func (deploy *NewDeploy) createOrGetSvc(deployLabels map[string]string, svcName
 			Spec: apiv1.ServiceSpec{
 				Ports: []apiv1.ServicePort{
 					{
-						Name:       "runtime-env-port",
+						Name:       runtimePortName,
 						Port:       int32(80),
-						TargetPort: intstr.FromInt(8888),
+						TargetPort: intstr.FromInt(targetPort),
 					},
 					{
 						Name:       "fetcher-port",
@@ -455,16 +489,32 @@ func (deploy *NewDeploy) deleteSvc(ns string, name string) error {
 	return nil
 }
 
-func (deploy *NewDeploy) waitForDeploy(depl *v1beta1.Deployment, replicas int32) (*v1beta1.Deployment, error) {
-	for i := 0; i < 120; i++ {
+// waitForDeploy waits for the deployment to have the given number of ready
+// replicas.  If a pod's image can't be pulled or it keeps crashing, it
+// fails right away with the reason, as a specialization failure.
+func (deploy *NewDeploy) waitForDeploy(depl *v1beta1.Deployment, replicas int32, timeout time.Duration) (*v1beta1.Deployment, error) {
+	selector := labels.Set(depl.Spec.Selector.MatchLabels).AsSelector().String()
+	startTime := time.Now()
+	for time.Since(startTime) < timeout {
 		latestDepl, err := deploy.kubernetesClient.ExtensionsV1beta1().Deployments(deploy.namespace).Get(depl.Name, metav1.GetOptions{})
 		if err != nil {
 			return nil, err
 		}
-		//TODO check for imagePullerror
 		if latestDepl.Status.ReadyReplicas >= replicas {
 			return latestDepl, err
 		}
+
+		podList, err := deploy.kubernetesClient.CoreV1().Pods(deploy.namespace).List(metav1.ListOptions{
+			LabelSelector: selector,
+		})
+		if err == nil {
+			for i := range podList.Items {
+				if failure := util.PodFailureError(&podList.Items[i]); failure != nil {
+					log.Printf("Deployment %v can't get ready: %v", depl.Name, failure)
+					return nil, util.MakeSpecializationError(failure)
+				}
+			}
+		}
 		time.Sleep(time.Second)
 	}
 	return nil, errors.New("failed to create deployment within timeout window")
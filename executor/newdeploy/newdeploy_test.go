@@ -0,0 +1,19 @@
+package newdeploy
+
+import (
+	"testing"
+	"time"
+
+	"github.com/fission/fission/crd"
+)
+
+func TestDeployReadyTimeout(t *testing.T) {
+	env := &crd.Environment{}
+	if d := deployReadyTimeout(env); d != DefaultDeployReadyTimeout {
+		t.Errorf("expected the default timeout, got %v", d)
+	}
+	env.Spec.PodReadyTimeout = 30
+	if d := deployReadyTimeout(env); d != 30*time.Second {
+		t.Errorf("expected the environment's timeout, got %v", d)
+	}
+}
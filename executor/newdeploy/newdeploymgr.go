@@ -21,16 +21,23 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"reflect"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/pkg/errors"
+	k8s_err "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/pkg/api"
 	apiv1 "k8s.io/client-go/pkg/api/v1"
+	asv1 "k8s.io/client-go/pkg/apis/autoscaling/v1"
 	"k8s.io/client-go/rest"
 	k8sCache "k8s.io/client-go/tools/cache"
 
@@ -62,6 +69,13 @@ type (
 		functions      []crd.Function
 		funcStore      k8sCache.Store
 		funcController k8sCache.Controller
+
+		adoptedObjects []api.ObjectReference // objects of a previous executor instance taken over at startup
+		replicaName    string                // name of this executor replica, for its load records
+		leader         int32                 // 1 once this executor replica is the leader; accessed atomically
+
+		failureLock sync.Mutex
+		failures    map[types.UID]*fnFailure // last failure to deploy or update, by function
 	}
 
 	fnRequest struct {
@@ -102,6 +116,12 @@ func MakeNewDeploy(
 		fetcherImagePullPolicy = "IfNotPresent"
 	}
 
+	replicaName, err := os.Hostname()
+	if err != nil {
+		log.Printf("Error getting hostname: %v", err)
+		replicaName = instanceID
+	}
+
 	enableIstio := false
 	if len(os.Getenv("ENABLE_ISTIO")) > 0 {
 		istio, err := strconv.ParseBool(os.Getenv("ENABLE_ISTIO"))
@@ -126,10 +146,14 @@ func MakeNewDeploy(
 		sharedSecretPath:       "/secrets",
 		sharedCfgMapPath:       "/configs",
 		useIstio:               enableIstio,
+		replicaName:            replicaName,
 
 		requestChannel: make(chan *fnRequest),
+		failures:       make(map[types.UID]*fnFailure),
 	}
 
+	nd.adoptedObjects = nd.adoptExistingResources()
+
 	if nd.crdClient != nil {
 		fnStore, fnController := nd.initFuncController()
 		nd.funcStore = fnStore
@@ -141,6 +165,19 @@ func MakeNewDeploy(
 
 func (deploy *NewDeploy) Run(ctx context.Context) {
 	go deploy.funcController.Run(ctx.Done())
+	go deploy.requestScaler(ctx)
+	go deploy.accessTimeSyncer(ctx)
+}
+
+// StartLeading makes this replica resize the deployments of functions
+// scaled on requests.  Every replica watches functions and keeps their
+// deployments in sync otherwise, as those operations are idempotent.
+func (deploy *NewDeploy) StartLeading() {
+	atomic.StoreInt32(&deploy.leader, 1)
+}
+
+func (deploy *NewDeploy) IsLeader() bool {
+	return atomic.LoadInt32(&deploy.leader) == 1
 }
 
 func (deploy *NewDeploy) initFuncController() (k8sCache.Store, k8sCache.Controller) {
@@ -170,6 +207,9 @@ func (deploy *NewDeploy) service() {
 		switch req.reqType {
 		case FnCreate:
 			fsvc, err := deploy.fnCreate(req.fn)
+			if err == nil {
+				deploy.clearFailure(req.fn)
+			}
 			req.responseChannel <- &fnResponse{
 				error: err,
 				fSvc:  fsvc,
@@ -187,13 +227,10 @@ func (deploy *NewDeploy) service() {
 	}
 }
 
-func (deploy *NewDeploy) GetFuncSvc(metadata *metav1.ObjectMeta) (*fscache.FuncSvc, error) {
+// GetFuncSvc returns the service of the function's deployment, creating
+// the deployment if there's none.
+func (deploy *NewDeploy) GetFuncSvc(metadata *metav1.ObjectMeta, fn *crd.Function, env *crd.Environment) (*fscache.FuncSvc, error) {
 	c := make(chan *fnResponse)
-	fn, err := deploy.fissionClient.Functions(metadata.Namespace).Get(metadata.Name)
-	if err != nil {
-		return nil, err
-	}
-
 	fsvc, err := deploy.fsCache.GetByFunctionUID(metadata.UID)
 
 	// If the function service cache exists, means
@@ -238,7 +275,7 @@ func (deploy *NewDeploy) createFunction(fn *crd.Function) {
 	}
 	resp := <-c
 	if resp.error != nil {
-		log.Printf("Error eager creating function: %v", resp.error)
+		deploy.updateStatus(fn, resp.error, "error eager creating function")
 	}
 }
 
@@ -256,6 +293,7 @@ func (deploy *NewDeploy) updateFunction(fn *crd.Function) {
 }
 
 func (deploy *NewDeploy) deleteFunction(fn *crd.Function) {
+	deploy.clearFailure(fn)
 	if fn.Spec.InvokeStrategy.ExecutionStrategy.ExecutorType == fission.ExecutorTypeNewdeploy {
 		c := make(chan *fnResponse)
 		deploy.requestChannel <- &fnRequest{
@@ -292,7 +330,7 @@ func (deploy *NewDeploy) fnCreate(fn *crd.Function) (*fscache.FuncSvc, error) {
 	// Since newdeploy waits for pods of deployment to be ready,
 	// change the order of kubeObject creation (create service first,
 	// then deployment) to take advantage of waiting time.
-	svc, err := deploy.createOrGetSvc(deployLabels, objName)
+	svc, err := deploy.createOrGetSvc(deployLabels, objName, functionPort(fn))
 	if err != nil {
 		log.Printf("Error creating the service %v: %v", objName, err)
 		return fsvc, err
@@ -305,37 +343,13 @@ func (deploy *NewDeploy) fnCreate(fn *crd.Function) (*fscache.FuncSvc, error) {
 		return fsvc, err
 	}
 
-	hpa, err := deploy.createOrGetHpa(objName, &fn.Spec.InvokeStrategy.ExecutionStrategy, depl)
-	if err != nil {
-		return fsvc, errors.Wrap(err, fmt.Sprintf("error creating the HPA %v:", objName))
-	}
-
-	kubeObjRefs := []api.ObjectReference{
-		{
-			//obj.TypeMeta.Kind does not work hence this, needs investigationa and a fix
-			Kind:            "deployment",
-			Name:            depl.ObjectMeta.Name,
-			APIVersion:      depl.TypeMeta.APIVersion,
-			Namespace:       depl.ObjectMeta.Namespace,
-			ResourceVersion: depl.ObjectMeta.ResourceVersion,
-			UID:             depl.ObjectMeta.UID,
-		},
-		{
-			Kind:            "service",
-			Name:            svc.ObjectMeta.Name,
-			APIVersion:      svc.TypeMeta.APIVersion,
-			Namespace:       svc.ObjectMeta.Namespace,
-			ResourceVersion: svc.ObjectMeta.ResourceVersion,
-			UID:             svc.ObjectMeta.UID,
-		},
-		{
-			Kind:            "horizontalpodautoscaler",
-			Name:            hpa.ObjectMeta.Name,
-			APIVersion:      hpa.TypeMeta.APIVersion,
-			Namespace:       hpa.ObjectMeta.Namespace,
-			ResourceVersion: hpa.ObjectMeta.ResourceVersion,
-			UID:             hpa.ObjectMeta.UID,
-		},
+	// functions scaled on requests are resized by the executor instead
+	var hpa *asv1.HorizontalPodAutoscaler
+	if !isRequestScaled(fn.Spec.InvokeStrategy.ExecutionStrategy) {
+		hpa, err = deploy.createOrGetHpa(objName, &fn.Spec.InvokeStrategy.ExecutionStrategy, depl)
+		if err != nil {
+			return fsvc, errors.Wrap(err, fmt.Sprintf("error creating the HPA %v:", objName))
+		}
 	}
 
 	fsvc = &fscache.FuncSvc{
@@ -343,7 +357,7 @@ func (deploy *NewDeploy) fnCreate(fn *crd.Function) (*fscache.FuncSvc, error) {
 		Function:          &fn.Metadata,
 		Environment:       env,
 		Address:           svcAddress,
-		KubernetesObjects: kubeObjRefs,
+		KubernetesObjects: kubeObjRefs(depl, svc, hpa),
 		Executor:          fscache.NEWDEPLOY,
 	}
 
@@ -367,6 +381,9 @@ func (deploy *NewDeploy) fnUpdate(oldFn *crd.Function, newFn *crd.Function) {
 		return
 	}
 
+	// failures of the previous version no longer apply
+	deploy.clearFailure(oldFn)
+
 	deployChanged := false
 
 	if oldFn.Spec.InvokeStrategy != newFn.Spec.InvokeStrategy {
@@ -386,48 +403,40 @@ func (deploy *NewDeploy) fnUpdate(oldFn *crd.Function, newFn *crd.Function) {
 			log.Printf("function type changed to new deployment, creating resources: %v", newFn)
 			_, err := deploy.fnCreate(newFn)
 			if err != nil {
-				updateStatus(oldFn, err, "error changing the function's type to newdeploy")
+				deploy.updateStatus(oldFn, err, "error changing the function's type to newdeploy")
 			}
 			return
 		}
 
-		hpa, err := deploy.getHpa(newFn)
+		var err error
+		if isRequestScaled(oldFn.Spec.InvokeStrategy.ExecutionStrategy) ||
+			isRequestScaled(newFn.Spec.InvokeStrategy.ExecutionStrategy) {
+			err = deploy.updateRequestScaling(oldFn, newFn)
+		} else {
+			err = deploy.updateHpaScaling(oldFn, newFn)
+		}
 		if err != nil {
-			updateStatus(oldFn, err, "error getting HPA while updating function")
+			deploy.updateStatus(oldFn, err, "error updating the function's scaling")
 			return
 		}
 
-		hpaChanged := false
-
 		if newFn.Spec.InvokeStrategy.ExecutionStrategy.MinScale != oldFn.Spec.InvokeStrategy.ExecutionStrategy.MinScale {
-			replicas := int32(newFn.Spec.InvokeStrategy.ExecutionStrategy.MinScale)
-			hpa.Spec.MinReplicas = &replicas
 			deployChanged = true
-			hpaChanged = true
-		}
-
-		if newFn.Spec.InvokeStrategy.ExecutionStrategy.MaxScale != oldFn.Spec.InvokeStrategy.ExecutionStrategy.MaxScale {
-			hpa.Spec.MaxReplicas = int32(newFn.Spec.InvokeStrategy.ExecutionStrategy.MaxScale)
-			hpaChanged = true
-		}
-
-		if newFn.Spec.InvokeStrategy.ExecutionStrategy.TargetCPUPercent != oldFn.Spec.InvokeStrategy.ExecutionStrategy.TargetCPUPercent {
-			targetCpupercent := int32(newFn.Spec.InvokeStrategy.ExecutionStrategy.TargetCPUPercent)
-			hpa.Spec.TargetCPUUtilizationPercentage = &targetCpupercent
-			hpaChanged = true
-		}
-
-		if hpaChanged {
-			err := deploy.updateHpa(hpa)
-			if err != nil {
-				updateStatus(oldFn, err, "error updating HPA while updating function")
-				return
-			}
 		}
 	}
 
 	if oldFn.Spec.Environment != newFn.Spec.Environment ||
-		oldFn.Spec.Package.PackageRef != newFn.Spec.Package.PackageRef {
+		oldFn.Spec.Package.PackageRef != newFn.Spec.Package.PackageRef ||
+		oldFn.Spec.Image != newFn.Spec.Image {
+		deployChanged = true
+	}
+
+	if functionPort(oldFn) != functionPort(newFn) {
+		err := deploy.updateSvcTargetPort(deploy.getObjName(oldFn), functionPort(newFn))
+		if err != nil {
+			deploy.updateStatus(oldFn, err, "failed to update service while updating function")
+			return
+		}
 		deployChanged = true
 	}
 
@@ -452,30 +461,89 @@ func (deploy *NewDeploy) fnUpdate(oldFn *crd.Function, newFn *crd.Function) {
 			}
 		}
 	}
+	if !reflect.DeepEqual(oldFn.Spec.Env, newFn.Spec.Env) ||
+		!reflect.DeepEqual(oldFn.Spec.PodSpec, newFn.Spec.PodSpec) {
+		deployChanged = true
+	}
 
 	if deployChanged == true {
 		env, err := deploy.fissionClient.Environments(newFn.Spec.Environment.Namespace).
 			Get(newFn.Spec.Environment.Name)
 		if err != nil {
-			updateStatus(oldFn, err, "failed to get environment while updating function")
+			deploy.updateStatus(oldFn, err, "failed to get environment while updating function")
 			return
 		}
 		deployName := deploy.getObjName(oldFn)
-		deployLabels := deploy.getDeployLabels(oldFn, env)
+		existingDepl, err := deploy.getDeployment(oldFn)
+		if err != nil {
+			deploy.updateStatus(oldFn, err, "failed to get deployment while updating function")
+			return
+		}
+		// keep the existing labels, they're the deployment's and
+		// the service's selector
+		deployLabels := existingDepl.Spec.Selector.MatchLabels
 		log.Printf("updating deployment due to function update")
 		newDeployment, err := deploy.getDeploymentSpec(newFn, env, deployName, deployLabels)
 		if err != nil {
-			updateStatus(oldFn, err, "failed to get new deployment spec while updating function")
+			deploy.updateStatus(oldFn, err, "failed to get new deployment spec while updating function")
 			return
 		}
+		if isScaledToZero(existingDepl) && newFn.Spec.InvokeStrategy.ExecutionStrategy.MinScale == 0 {
+			// leave it to the next request to scale it back up
+			newDeployment.Spec.Replicas = existingDepl.Spec.Replicas
+		}
 		err = deploy.updateDeployment(newDeployment)
 		if err != nil {
-			updateStatus(oldFn, err, "failed to update deployment while updating function")
+			deploy.updateStatus(oldFn, err, "failed to update deployment while updating function")
+			return
+		}
+		err = deploy.setEnvOwner(existingDepl)
+		if err != nil {
+			deploy.updateStatus(oldFn, err, "failed to update the function's environment while updating function")
 			return
 		}
 	}
 }
 
+// updateHpaScaling updates the HPA of the function's deployment to the
+// function's new execution strategy.  Deployments scaled to zero have no
+// HPA; it's created from the new strategy when they're scaled back up.
+func (deploy *NewDeploy) updateHpaScaling(oldFn *crd.Function, newFn *crd.Function) error {
+	hpa, err := deploy.getHpa(newFn)
+	if k8s_err.IsNotFound(err) {
+		return nil
+	} else if err != nil {
+		return errors.Wrap(err, "error getting HPA while updating function")
+	}
+
+	hpaChanged := false
+
+	if newFn.Spec.InvokeStrategy.ExecutionStrategy.MinScale != oldFn.Spec.InvokeStrategy.ExecutionStrategy.MinScale {
+		replicas := int32(newFn.Spec.InvokeStrategy.ExecutionStrategy.MinScale)
+		hpa.Spec.MinReplicas = &replicas
+		hpaChanged = true
+	}
+
+	if newFn.Spec.InvokeStrategy.ExecutionStrategy.MaxScale != oldFn.Spec.InvokeStrategy.ExecutionStrategy.MaxScale {
+		hpa.Spec.MaxReplicas = int32(fission.FunctionScale(&newFn.Spec))
+		hpaChanged = true
+	}
+
+	if newFn.Spec.InvokeStrategy.ExecutionStrategy.TargetCPUPercent != oldFn.Spec.InvokeStrategy.ExecutionStrategy.TargetCPUPercent {
+		targetCpupercent := int32(newFn.Spec.InvokeStrategy.ExecutionStrategy.TargetCPUPercent)
+		hpa.Spec.TargetCPUUtilizationPercentage = &targetCpupercent
+		hpaChanged = true
+	}
+
+	if hpaChanged {
+		err := deploy.updateHpa(hpa)
+		if err != nil {
+			return errors.Wrap(err, "error updating HPA while updating function")
+		}
+	}
+	return nil
+}
+
 func (deploy *NewDeploy) fnDelete(fn *crd.Function) (*fscache.FuncSvc, error) {
 
 	var delError error
@@ -511,8 +579,9 @@ func (deploy *NewDeploy) fnDelete(fn *crd.Function) (*fscache.FuncSvc, error) {
 		delError = err
 	}
 
+	// functions scaled on requests have no HPA
 	err = deploy.deleteHpa(deploy.namespace, objName)
-	if err != nil {
+	if err != nil && !k8s_err.IsNotFound(err) {
 		log.Printf("Error deleting the HPA: %v", objName)
 		delError = err
 	}
@@ -524,6 +593,22 @@ func (deploy *NewDeploy) fnDelete(fn *crd.Function) (*fscache.FuncSvc, error) {
 }
 
 func (deploy *NewDeploy) getObjName(fn *crd.Function) string {
+	// objects adopted from a previous executor instance keep their names
+	fsvc, err := deploy.fsCache.GetByFunctionUID(fn.Metadata.UID)
+	if err == nil && fsvc.Executor == fscache.NEWDEPLOY {
+		return fsvc.Name
+	}
+	// so do deployments scaled to zero, which have no cache entry
+	deplList, err := deploy.kubernetesClient.ExtensionsV1beta1().Deployments(deploy.namespace).List(
+		metav1.ListOptions{
+			LabelSelector: labels.Set(map[string]string{
+				"functionUid":  string(fn.Metadata.UID),
+				"executorType": fission.ExecutorTypeNewdeploy,
+			}).AsSelector().String(),
+		})
+	if err == nil && len(deplList.Items) > 0 {
+		return deplList.Items[0].ObjectMeta.Name
+	}
 	return fmt.Sprintf("%v-%v",
 		fn.Metadata.Name,
 		deploy.instanceID)
@@ -554,14 +639,28 @@ func (deploy *NewDeploy) updateKubeObjRefRV(fsvc *fscache.FuncSvc, objKind strin
 	return errors.New(fmt.Sprintf("error finding kubernetes object reference with kind: %v", objKind))
 }
 
-// updateStatus is a function which updates status of update.
-// Current implementation only logs messages, in future it will update function status
-func updateStatus(fn *crd.Function, err error, message string) {
-	log.Printf(message, err)
+// IsValid checks that the service of the function's deployment still exists.
+// Deployments scaled to zero while idle keep their service, but their
+// cache entry is deleted along.
+func (deploy *NewDeploy) IsValid(fsvc *fscache.FuncSvc) bool {
+	return deploy.isValidService(fsvc.Address)
+}
+
+// Evict deletes the function's deployment, service and HPA, along with its
+// cache entry; the next request for the function creates them again.
+func (deploy *NewDeploy) Evict(fsvc *fscache.FuncSvc) error {
+	c := make(chan *fnResponse)
+	deploy.requestChannel <- &fnRequest{
+		fn:              &crd.Function{Metadata: *fsvc.Function},
+		reqType:         FnDelete,
+		responseChannel: c,
+	}
+	resp := <-c
+	return resp.error
 }
 
-// IsValidService does a get on the service address to ensure it's a valid service. returns true if it is, else false.
-func (deploy *NewDeploy) IsValidService(svc string) bool {
+// isValidService does a get on the service address to ensure it's a valid service. returns true if it is, else false.
+func (deploy *NewDeploy) isValidService(svc string) bool {
 	service := strings.Split(svc, ".")
 	if len(service) == 0 {
 		return false
@@ -0,0 +1,336 @@
+/*
+Copyright 2018 The Fission Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package newdeploy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"strings"
+	"time"
+
+	k8s_err "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/pkg/apis/extensions/v1beta1"
+
+	"github.com/fission/fission"
+	"github.com/fission/fission/crd"
+	"github.com/fission/fission/executor/fscache"
+)
+
+// Functions that set TargetConcurrency or TargetRPS are scaled on the
+// requests the routers report to the executor, rather than by an HPA on
+// CPU.  Routers report to any executor replica, so every replica records
+// the load it saw on the function's deployment, and the leader sizes the
+// deployment on the sum of the replicas' recent records.
+
+const (
+	// requestLoadAnnotationPrefix, followed by the name of an executor
+	// replica, is the deployment annotation the replica records the
+	// function's load in.
+	requestLoadAnnotationPrefix = "requestLoad."
+
+	// how often load is recorded and deployments are resized
+	requestScalingInterval = 15 * time.Second
+
+	// records older than this are of replicas that saw no requests since,
+	// or that are gone; the leader removes them
+	requestLoadMaxAge = 2 * requestScalingInterval
+
+	// how long the load must stay low before a deployment is shrunk
+	scaleDownDelay = 2 * time.Minute
+)
+
+type (
+	// requestLoad is the load on a function's deployment seen by one
+	// executor replica.
+	requestLoad struct {
+		Concurrency int       `json:"concurrency"`
+		RPS         float64   `json:"rps"`
+		Time        time.Time `json:"time"`
+	}
+)
+
+// isRequestScaled returns true if a newdeploy function's deployment is
+// scaled on requests instead of CPU.
+func isRequestScaled(strategy fission.ExecutionStrategy) bool {
+	return strategy.TargetConcurrency > 0 || strategy.TargetRPS > 0
+}
+
+// desiredReplicas returns the replicas that bring the load per pod down to
+// the function's targets, between MinScale and MaxScale.  At least one pod
+// is kept; idle deployments are scaled to zero by ReapIdle instead.
+func desiredReplicas(load requestLoad, strategy fission.ExecutionStrategy) int32 {
+	replicas := 0
+	if strategy.TargetConcurrency > 0 {
+		replicas = int(math.Ceil(float64(load.Concurrency) / float64(strategy.TargetConcurrency)))
+	}
+	if strategy.TargetRPS > 0 {
+		r := int(math.Ceil(load.RPS / float64(strategy.TargetRPS)))
+		if r > replicas {
+			replicas = r
+		}
+	}
+
+	min := strategy.MinScale
+	if min < 1 {
+		min = 1
+	}
+	max := strategy.MaxScale
+	if max < min {
+		max = min
+	}
+	if replicas < min {
+		replicas = min
+	}
+	if replicas > max {
+		replicas = max
+	}
+	return int32(replicas)
+}
+
+// totalLoad sums the recent load records of all replicas on a deployment.
+func totalLoad(annotations map[string]string, now time.Time) requestLoad {
+	total := requestLoad{Time: now}
+	for key, value := range annotations {
+		if !strings.HasPrefix(key, requestLoadAnnotationPrefix) {
+			continue
+		}
+		var load requestLoad
+		err := json.Unmarshal([]byte(value), &load)
+		if err != nil || now.Sub(load.Time) > requestLoadMaxAge {
+			continue
+		}
+		total.Concurrency += load.Concurrency
+		total.RPS += load.RPS
+	}
+	return total
+}
+
+// staleLoadRecords returns the annotations holding load records older than
+// requestLoadMaxAge.
+func staleLoadRecords(annotations map[string]string, now time.Time) []string {
+	stale := make([]string, 0)
+	for key, value := range annotations {
+		if !strings.HasPrefix(key, requestLoadAnnotationPrefix) {
+			continue
+		}
+		var load requestLoad
+		err := json.Unmarshal([]byte(value), &load)
+		if err != nil || now.Sub(load.Time) > requestLoadMaxAge {
+			stale = append(stale, key)
+		}
+	}
+	return stale
+}
+
+// pruneLoadRecords removes the stale load records from the deployment, so
+// that records of executor replicas that are gone don't pile up on it.  It
+// returns the updated deployment.
+func (deploy *NewDeploy) pruneLoadRecords(depl *v1beta1.Deployment, now time.Time) *v1beta1.Deployment {
+	stale := staleLoadRecords(depl.ObjectMeta.Annotations, now)
+	if len(stale) == 0 {
+		return depl
+	}
+	annotations := make(map[string]interface{}, len(stale))
+	for _, key := range stale {
+		annotations[key] = nil
+	}
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{"annotations": annotations},
+	})
+	if err != nil {
+		return depl
+	}
+	updated, err := deploy.kubernetesClient.ExtensionsV1beta1().Deployments(deploy.namespace).Patch(
+		depl.ObjectMeta.Name, types.MergePatchType, patch)
+	if err != nil {
+		log.Printf("Error removing stale load records from deployment %v: %v", depl.ObjectMeta.Name, err)
+		return depl
+	}
+	return updated
+}
+
+// requestScaler records this replica's load on request scaled functions,
+// and on the leader, resizes their deployments.
+func (deploy *NewDeploy) requestScaler(ctx context.Context) {
+	ticker := time.NewTicker(requestScalingInterval)
+	defer ticker.Stop()
+
+	reported := make(map[string]int64)     // requests of each function service at the last report
+	lastBusy := make(map[string]time.Time) // when each deployment last needed its replicas
+	lastReport := time.Now()
+	for {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+		now := time.Now()
+		reported = deploy.recordLoad(reported, now.Sub(lastReport))
+		lastReport = now
+
+		if deploy.IsLeader() {
+			deploy.scaleDeployments(lastBusy)
+		}
+	}
+}
+
+// getFunction returns the watched function, or nil if it's unknown.
+func (deploy *NewDeploy) getFunction(m *metav1.ObjectMeta) *crd.Function {
+	obj, exists, err := deploy.funcStore.GetByKey(fmt.Sprintf("%v/%v", m.Namespace, m.Name))
+	if err != nil || !exists {
+		return nil
+	}
+	return obj.(*crd.Function)
+}
+
+// recordLoad records the load this replica saw on each request scaled
+// function since the last report on its deployment.  Replicas that saw
+// no requests leave their record to expire.  It returns the request
+// counts of the function services to diff the next report against.
+func (deploy *NewDeploy) recordLoad(reported map[string]int64, elapsed time.Duration) map[string]int64 {
+	fsvcs, err := deploy.fsCache.List()
+	if err != nil {
+		return reported
+	}
+
+	requests := make(map[string]int64)
+	for _, fsvc := range fsvcs {
+		if fsvc.Executor != fscache.NEWDEPLOY {
+			continue
+		}
+		requests[fsvc.Address] = fsvc.Requests
+		fn := deploy.getFunction(fsvc.Function)
+		if fn == nil || !isRequestScaled(fn.Spec.InvokeStrategy.ExecutionStrategy) {
+			continue
+		}
+
+		count := fsvc.Requests - reported[fsvc.Address]
+		if count <= 0 || elapsed <= 0 {
+			continue
+		}
+		load := requestLoad{
+			Concurrency: fsvc.Concurrency,
+			RPS:         float64(count) / elapsed.Seconds(),
+			Time:        time.Now(),
+		}
+		value, err := json.Marshal(load)
+		if err != nil {
+			continue
+		}
+		patch := fmt.Sprintf(`{"metadata":{"annotations":{%q:%q}}}`,
+			requestLoadAnnotationPrefix+deploy.replicaName, string(value))
+		_, err = deploy.kubernetesClient.ExtensionsV1beta1().Deployments(deploy.namespace).Patch(
+			fsvc.Name, types.StrategicMergePatchType, []byte(patch))
+		if err != nil {
+			log.Printf("Error recording request load on deployment %v: %v", fsvc.Name, err)
+		}
+	}
+	return requests
+}
+
+// scaleDeployments resizes the deployments of request scaled functions to
+// the load recorded on them.  They're grown right away, but only shrunk
+// once the load has been low for scaleDownDelay.
+func (deploy *NewDeploy) scaleDeployments(lastBusy map[string]time.Time) {
+	deplList, err := deploy.kubernetesClient.ExtensionsV1beta1().Deployments(deploy.namespace).List(
+		metav1.ListOptions{
+			LabelSelector: labels.Set(map[string]string{
+				"executorType": fission.ExecutorTypeNewdeploy,
+			}).AsSelector().String(),
+		})
+	if err != nil {
+		log.Printf("Error listing deployments to scale: %v", err)
+		return
+	}
+
+	now := time.Now()
+	for i := range deplList.Items {
+		depl := &deplList.Items[i]
+		fn := deploy.getFunction(&metav1.ObjectMeta{
+			Name:      depl.ObjectMeta.Labels["functionName"],
+			Namespace: depl.ObjectMeta.Namespace,
+		})
+		if fn == nil || string(fn.Metadata.UID) != depl.ObjectMeta.Labels["functionUid"] ||
+			!isRequestScaled(fn.Spec.InvokeStrategy.ExecutionStrategy) {
+			continue
+		}
+
+		if isScaledToZero(depl) {
+			// scaled back up on demand
+			continue
+		}
+		depl = deploy.pruneLoadRecords(depl, now)
+		current := int32(1)
+		if depl.Spec.Replicas != nil {
+			current = *depl.Spec.Replicas
+		}
+		desired := desiredReplicas(totalLoad(depl.ObjectMeta.Annotations, now), fn.Spec.InvokeStrategy.ExecutionStrategy)
+		busy, seen := lastBusy[depl.ObjectMeta.Name]
+		if desired >= current || !seen {
+			busy = now
+			lastBusy[depl.ObjectMeta.Name] = busy
+		}
+		if desired == current || (desired < current && now.Sub(busy) < scaleDownDelay) {
+			continue
+		}
+
+		depl.Spec.Replicas = &desired
+		_, err := deploy.kubernetesClient.ExtensionsV1beta1().Deployments(deploy.namespace).Update(depl)
+		if err != nil {
+			log.Printf("Error scaling deployment %v: %v", depl.ObjectMeta.Name, err)
+			continue
+		}
+		log.Printf("[%v] Scaled deployment %v from %v to %v replicas", fn.Metadata.Name, depl.ObjectMeta.Name, current, desired)
+	}
+}
+
+// updateRequestScaling hands the function's deployment over between the
+// HPA and the request scaler, when the function starts or stops being
+// scaled on requests.
+func (deploy *NewDeploy) updateRequestScaling(oldFn *crd.Function, newFn *crd.Function) error {
+	wasRequestScaled := isRequestScaled(oldFn.Spec.InvokeStrategy.ExecutionStrategy)
+	requestScaled := isRequestScaled(newFn.Spec.InvokeStrategy.ExecutionStrategy)
+	if wasRequestScaled == requestScaled {
+		return nil
+	}
+
+	objName := deploy.getObjName(oldFn)
+	if requestScaled {
+		err := deploy.deleteHpa(deploy.namespace, objName)
+		if err != nil && !k8s_err.IsNotFound(err) {
+			return err
+		}
+		return nil
+	}
+
+	depl, err := deploy.getDeployment(oldFn)
+	if err != nil {
+		return err
+	}
+	if isScaledToZero(depl) {
+		// the HPA is created when it's scaled back up
+		return nil
+	}
+	_, err = deploy.createOrGetHpa(objName, &newFn.Spec.InvokeStrategy.ExecutionStrategy, depl)
+	return err
+}
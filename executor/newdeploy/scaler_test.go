@@ -0,0 +1,67 @@
+package newdeploy
+
+import (
+	"encoding/json"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/fission/fission"
+)
+
+func TestDesiredReplicas(t *testing.T) {
+	strategy := fission.ExecutionStrategy{
+		MinScale:          1,
+		MaxScale:          5,
+		TargetConcurrency: 4,
+		TargetRPS:         10,
+	}
+	tests := []struct {
+		load     requestLoad
+		expected int32
+	}{
+		{requestLoad{}, 1},
+		{requestLoad{Concurrency: 9, RPS: 5}, 3},
+		{requestLoad{Concurrency: 2, RPS: 35}, 4},
+		{requestLoad{Concurrency: 100, RPS: 1000}, 5},
+	}
+	for _, test := range tests {
+		if n := desiredReplicas(test.load, strategy); n != test.expected {
+			t.Errorf("expected %v replicas for %+v, got %v", test.expected, test.load, n)
+		}
+	}
+
+	if n := desiredReplicas(requestLoad{}, fission.ExecutionStrategy{TargetRPS: 10}); n != 1 {
+		t.Errorf("expected at least 1 replica, got %v", n)
+	}
+}
+
+func TestTotalLoad(t *testing.T) {
+	now := time.Now()
+	record := func(concurrency int, rps float64, age time.Duration) string {
+		value, err := json.Marshal(requestLoad{Concurrency: concurrency, RPS: rps, Time: now.Add(-age)})
+		if err != nil {
+			t.Fatalf("error marshaling load: %v", err)
+		}
+		return string(value)
+	}
+	annotations := map[string]string{
+		requestLoadAnnotationPrefix + "executor-a": record(3, 12.5, 10*time.Second),
+		requestLoadAnnotationPrefix + "executor-b": record(2, 7.5, 20*time.Second),
+		requestLoadAnnotationPrefix + "executor-c": record(50, 500, 2*requestLoadMaxAge),
+		requestLoadAnnotationPrefix + "executor-d": "garbage",
+		"unrelated": record(50, 500, 0),
+	}
+
+	load := totalLoad(annotations, now)
+	if load.Concurrency != 5 || load.RPS != 20 {
+		t.Errorf("expected concurrency 5 and 20 rps, got %+v", load)
+	}
+
+	stale := staleLoadRecords(annotations, now)
+	sort.Strings(stale)
+	if len(stale) != 2 || stale[0] != requestLoadAnnotationPrefix+"executor-c" ||
+		stale[1] != requestLoadAnnotationPrefix+"executor-d" {
+		t.Errorf("expected the old and unreadable records to be stale, got %v", stale)
+	}
+}
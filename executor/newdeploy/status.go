@@ -0,0 +1,120 @@
+/*
+Copyright 2018 The Fission Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package newdeploy
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	apiv1 "k8s.io/client-go/pkg/api/v1"
+
+	"github.com/fission/fission"
+	"github.com/fission/fission/crd"
+	"github.com/fission/fission/executor/util"
+)
+
+type (
+	// fnFailure is the last failure to deploy or update a function.
+	fnFailure struct {
+		err  string
+		time time.Time
+	}
+)
+
+// updateStatus logs a failure to deploy or update the function, and
+// records it for the function's status.
+func (deploy *NewDeploy) updateStatus(fn *crd.Function, err error, message string) {
+	log.Printf("%v: %v", message, err)
+
+	deploy.failureLock.Lock()
+	defer deploy.failureLock.Unlock()
+	deploy.failures[fn.Metadata.UID] = &fnFailure{
+		err:  fmt.Sprintf("%v: %v", message, err),
+		time: time.Now(),
+	}
+}
+
+func (deploy *NewDeploy) clearFailure(fn *crd.Function) {
+	deploy.failureLock.Lock()
+	defer deploy.failureLock.Unlock()
+	delete(deploy.failures, fn.Metadata.UID)
+}
+
+// FunctionStatus reports the ready pods of the function's deployment, or
+// why it has none.
+func (deploy *NewDeploy) FunctionStatus(fn *crd.Function, env *crd.Environment, status *fission.FunctionStatus) {
+	deploy.failureLock.Lock()
+	failure, ok := deploy.failures[fn.Metadata.UID]
+	deploy.failureLock.Unlock()
+	if ok && (status.LastErrorTime == nil || status.LastErrorTime.Time.Before(failure.time)) {
+		t := metav1.NewTime(failure.time)
+		status.LastError = failure.err
+		status.LastErrorTime = &t
+	}
+
+	selector := labels.Set(map[string]string{
+		"functionUid":  string(fn.Metadata.UID),
+		"executorType": fission.ExecutorTypeNewdeploy,
+	}).AsSelector().String()
+
+	deplList, err := deploy.kubernetesClient.ExtensionsV1beta1().Deployments(deploy.namespace).List(
+		metav1.ListOptions{LabelSelector: selector})
+	if err != nil {
+		return
+	}
+	if len(deplList.Items) == 0 {
+		if fn.Spec.InvokeStrategy.ExecutionStrategy.MinScale > 0 {
+			status.SetCondition(fission.FunctionDeployed, apiv1.ConditionFalse, "NotDeployed",
+				"the function has no deployment")
+		} else {
+			status.SetCondition(fission.FunctionDeployed, apiv1.ConditionFalse, fission.FunctionReasonIdle,
+				"the function is deployed on its first request")
+		}
+		return
+	}
+
+	depl := &deplList.Items[0]
+	status.ReadyReplicas = int(depl.Status.ReadyReplicas)
+	if isScaledToZero(depl) {
+		status.SetCondition(fission.FunctionDeployed, apiv1.ConditionTrue, "ScaledToZero",
+			"the deployment is scaled back up on the next request")
+		return
+	}
+	if depl.Status.ReadyReplicas > 0 {
+		status.SetCondition(fission.FunctionDeployed, apiv1.ConditionTrue, "",
+			fmt.Sprintf("%v of %v pods ready", depl.Status.ReadyReplicas, depl.Status.Replicas))
+		return
+	}
+
+	podList, err := deploy.kubernetesClient.CoreV1().Pods(deploy.namespace).List(
+		metav1.ListOptions{LabelSelector: selector})
+	if err == nil {
+		for i := range podList.Items {
+			reason, message := util.GetPodFailure(&podList.Items[i])
+			if len(reason) > 0 {
+				status.SetCondition(fission.FunctionDeployed, apiv1.ConditionFalse, reason,
+					fmt.Sprintf("pod %v: %v", podList.Items[i].ObjectMeta.Name, message))
+				return
+			}
+		}
+	}
+	status.SetCondition(fission.FunctionDeployed, apiv1.ConditionFalse, "PodsNotReady",
+		"none of the deployment's pods are ready")
+}
@@ -0,0 +1,299 @@
+/*
+Copyright 2018 The Fission Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package poolmgr
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/pkg/api"
+	apiv1 "k8s.io/client-go/pkg/api/v1"
+	"k8s.io/client-go/pkg/apis/extensions/v1beta1"
+
+	"github.com/fission/fission"
+	"github.com/fission/fission/crd"
+)
+
+// adoptExistingResources rebuilds the generic pools and the function
+// service cache from the pool deployments and specialized pods left behind
+// by a previous executor instance, so that an executor restart doesn't
+// cause cold starts.  Objects are adopted only if their environment (and
+// function) are unchanged since they were created.  It returns references
+// to the adopted objects; everything else is left for cleanup.
+//
+// This must run before the pool manager's goroutines start.
+func (gpm *GenericPoolManager) adoptExistingResources() []api.ObjectReference {
+	adopted := make([]api.ObjectReference, 0)
+
+	envs, err := gpm.fissionClient.Environments(metav1.NamespaceAll).List(metav1.ListOptions{})
+	if err != nil {
+		log.Printf("Error listing environments, not adopting existing pools: %v", err)
+		return adopted
+	}
+	envByUID := make(map[types.UID]*crd.Environment)
+	for i := range envs.Items {
+		envByUID[envs.Items[i].Metadata.UID] = &envs.Items[i]
+	}
+
+	deplList, err := gpm.kubernetesClient.ExtensionsV1beta1().Deployments(gpm.namespace).List(
+		metav1.ListOptions{
+			LabelSelector: labels.Set(map[string]string{
+				"executorType": fission.ExecutorTypePoolmgr,
+			}).AsSelector().String(),
+		})
+	if err != nil {
+		log.Printf("Error listing pool deployments, not adopting existing pools: %v", err)
+		return adopted
+	}
+
+	for i := range deplList.Items {
+		depl := &deplList.Items[i]
+
+		env, ok := envByUID[types.UID(depl.ObjectMeta.Labels["environmentUid"])]
+		if !ok || depl.ObjectMeta.Labels[environmentVersionLabel] != environmentVersion(env) {
+			continue
+		}
+		key := poolKey(env)
+		if _, ok := gpm.pools[key]; ok || gpm.getEnvPoolsize(env) == 0 {
+			continue
+		}
+
+		log.Printf("Adopting pool deployment %v for environment %v", depl.ObjectMeta.Name, env.Metadata.Name)
+		gpm.pools[key] = gpm.adoptGenericPool(env, depl)
+		adopted = append(adopted, api.ObjectReference{
+			Kind:      "deployment",
+			Name:      depl.ObjectMeta.Name,
+			Namespace: depl.ObjectMeta.Namespace,
+			UID:       depl.ObjectMeta.UID,
+		})
+	}
+
+	return append(adopted, gpm.adoptSpecializedPods(envs.Items)...)
+}
+
+// adoptGenericPool makes a pool around an existing pool deployment.
+func (gpm *GenericPoolManager) adoptGenericPool(env *crd.Environment, depl *v1beta1.Deployment) *GenericPool {
+	var replicas int32
+	if depl.Spec.Replicas != nil {
+		replicas = *depl.Spec.Replicas
+	}
+
+	gp := newGenericPool(gpm.fissionClient, gpm.kubernetesClient, env, replicas,
+		gpm.namespace, gpm.fsCache, gpm.instanceId, gpm.enableIstio, gpm.IsLeader, gpm.pods, gpm.evictLRUPod)
+
+	// keep the pool's original labels, since they're part of the
+	// deployment's selector
+	gp.deployment = depl
+	gp.labelsForPool = depl.Spec.Selector.MatchLabels
+
+	gp.start()
+	return gp
+}
+
+// getLeaderPool makes a pool around the deployment the leader executor
+// replica created for the environment.
+func (gpm *GenericPoolManager) getLeaderPool(env *crd.Environment) (*GenericPool, error) {
+	deplList, err := gpm.kubernetesClient.ExtensionsV1beta1().Deployments(gpm.namespace).List(
+		metav1.ListOptions{
+			LabelSelector: labels.Set(map[string]string{
+				"environmentUid": string(env.Metadata.UID),
+				"executorType":   fission.ExecutorTypePoolmgr,
+			}).AsSelector().String(),
+		})
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range deplList.Items {
+		depl := &deplList.Items[i]
+		if depl.ObjectMeta.DeletionTimestamp == nil &&
+			depl.ObjectMeta.Labels[environmentVersionLabel] == environmentVersion(env) {
+			return gpm.adoptGenericPool(env, depl), nil
+		}
+	}
+
+	return nil, fission.MakeError(fission.ErrorNotFound,
+		fmt.Sprintf("no pool for environment %v yet", env.Metadata.Name))
+}
+
+// adoptSpecializedPods adds the ready specialized pods of adopted pools,
+// and the functions loaded into their shared pods, back to the function
+// service cache.
+func (gpm *GenericPoolManager) adoptSpecializedPods(envs []crd.Environment) []api.ObjectReference {
+	adopted := make([]api.ObjectReference, 0)
+
+	fns, err := gpm.fissionClient.Functions(metav1.NamespaceAll).List(metav1.ListOptions{})
+	if err != nil {
+		log.Printf("Error listing functions, not adopting specialized pods: %v", err)
+		return adopted
+	}
+	fnByUID := make(map[types.UID]*crd.Function)
+	for i := range fns.Items {
+		fnByUID[fns.Items[i].Metadata.UID] = &fns.Items[i]
+	}
+
+	podList, err := gpm.kubernetesClient.CoreV1().Pods(gpm.namespace).List(
+		metav1.ListOptions{
+			LabelSelector: labels.Set(map[string]string{
+				"unmanaged": "true",
+			}).AsSelector().String(),
+		})
+	if err != nil {
+		log.Printf("Error listing specialized pods, not adopting them: %v", err)
+		return adopted
+	}
+
+	for i := range podList.Items {
+		pod := &podList.Items[i]
+
+		fn, ok := fnByUID[types.UID(pod.ObjectMeta.Labels["functionUid"])]
+		if !ok || pod.ObjectMeta.Annotations[fission.FUNCTION_RESOURCEVERSION_ANNOTATION] != fn.Metadata.ResourceVersion {
+			continue
+		}
+		if pod.ObjectMeta.DeletionTimestamp != nil || len(pod.Status.PodIP) == 0 || !fission.IsReadyPod(pod) {
+			continue
+		}
+
+		// the function's environment must have an adopted pool
+		pool := gpm.adoptedPoolOf(envs, fn)
+		if pool == nil || isOutdatedPod(pod, environmentVersion(pool.env)) {
+			continue
+		}
+
+		m := fn.Metadata
+		fsvc := pool.makeFuncSvc(&m, pod, fn.Spec.InvokeStrategy.ExecutionStrategy)
+		_, err := gpm.fsCache.Add(*fsvc)
+		if err != nil {
+			log.Printf("Error adopting specialized pod %v: %v", pod.ObjectMeta.Name, err)
+			continue
+		}
+
+		log.Printf("Adopted specialized pod %v for function %v", pod.ObjectMeta.Name, fn.Metadata.Name)
+		adopted = append(adopted, fsvc.KubernetesObjects...)
+
+		// pods specialized before they were labeled with their
+		// environment get its labels, so that they count towards
+		// MaxSpecializedPods and may be evicted.  Only the leader
+		// labels them, see labelAdoptedPods.
+		if _, ok := pod.ObjectMeta.Labels["environmentUid"]; !ok {
+			gpm.unlabeledPods = append(gpm.unlabeledPods, podLabels{
+				pod:    pod,
+				labels: pool.labelsForFunction(&m),
+			})
+		}
+	}
+
+	return append(adopted, gpm.adoptSharedPods(envs, fnByUID)...)
+}
+
+// adoptSharedPods adds the functions loaded into the ready shared pods of
+// adopted pools back to the function service cache.
+func (gpm *GenericPoolManager) adoptSharedPods(envs []crd.Environment, fnByUID map[types.UID]*crd.Function) []api.ObjectReference {
+	adopted := make([]api.ObjectReference, 0)
+
+	podList, err := gpm.kubernetesClient.CoreV1().Pods(gpm.namespace).List(
+		metav1.ListOptions{
+			LabelSelector: labels.Set(map[string]string{
+				"executorType": fission.ExecutorTypePoolmgr,
+			}).AsSelector().String(),
+		})
+	if err != nil {
+		log.Printf("Error listing shared pods, not adopting them: %v", err)
+		return adopted
+	}
+
+	for i := range podList.Items {
+		pod := &podList.Items[i]
+		if !isSharedPod(pod) || pod.ObjectMeta.DeletionTimestamp != nil ||
+			len(pod.Status.PodIP) == 0 || !fission.IsReadyPod(pod) {
+			continue
+		}
+
+		for uid, rv := range loadedFunctions(pod) {
+			fn, ok := fnByUID[uid]
+			if !ok || fn.Metadata.ResourceVersion != rv {
+				continue
+			}
+			pool := gpm.adoptedPoolOf(envs, fn)
+			if pool == nil || !sharesPods(pool.env) ||
+				pod.ObjectMeta.Labels["environmentUid"] != string(pool.env.Metadata.UID) ||
+				isOutdatedPod(pod, environmentVersion(pool.env)) {
+				continue
+			}
+
+			m := fn.Metadata
+			fsvc := pool.makeFuncSvc(&m, pod, fn.Spec.InvokeStrategy.ExecutionStrategy)
+			fsvc.Atime = podLastAccessTime(pod)
+			_, err := gpm.fsCache.Add(*fsvc)
+			if err != nil {
+				log.Printf("Error adopting function %v of shared pod %v: %v", fn.Metadata.Name, pod.ObjectMeta.Name, err)
+				continue
+			}
+
+			log.Printf("Adopted function %v of shared pod %v", fn.Metadata.Name, pod.ObjectMeta.Name)
+			adopted = append(adopted, fsvc.KubernetesObjects...)
+		}
+	}
+
+	return adopted
+}
+
+// adoptedPoolOf returns the adopted pool of the function's environment, or
+// nil if it has none.
+func (gpm *GenericPoolManager) adoptedPoolOf(envs []crd.Environment, fn *crd.Function) *GenericPool {
+	if fn.Spec.InvokeStrategy.ExecutionStrategy.ExecutorType == fission.ExecutorTypeNewdeploy {
+		return nil
+	}
+	for j := range envs {
+		if envs[j].Metadata.Name == fn.Spec.Environment.Name &&
+			envs[j].Metadata.Namespace == fn.Spec.Environment.Namespace {
+			return gpm.pools[poolKey(&envs[j])]
+		}
+	}
+	return nil
+}
+
+// labelAdoptedPods gives the adopted pods that lack them the labels of
+// their pool.  It's called once this replica is elected leader.
+func (gpm *GenericPoolManager) labelAdoptedPods() {
+	for _, pl := range gpm.unlabeledPods {
+		err := gpm.relabelPod(pl.pod, pl.labels)
+		if err != nil {
+			log.Printf("Error labeling adopted pod %v: %v", pl.pod.ObjectMeta.Name, err)
+		}
+	}
+	gpm.unlabeledPods = nil
+}
+
+// relabelPod adds the labels to the pod.
+func (gpm *GenericPoolManager) relabelPod(pod *apiv1.Pod, podLabels map[string]string) error {
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"labels": podLabels,
+		},
+	})
+	if err != nil {
+		return err
+	}
+	_, err = gpm.kubernetesClient.CoreV1().Pods(pod.ObjectMeta.Namespace).Patch(
+		pod.ObjectMeta.Name, types.StrategicMergePatchType, patch)
+	return err
+}
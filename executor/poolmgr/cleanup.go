@@ -0,0 +1,134 @@
+/*
+Copyright 2018 The Fission Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package poolmgr
+
+import (
+	"log"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/fission/fission"
+	"github.com/fission/fission/executor/util"
+)
+
+// isPoolmgrObject returns true for pool deployments, pool pods and
+// specialized pods, including those of executors that predate the
+// executorType label.
+func isPoolmgrObject(obj *metav1.ObjectMeta) bool {
+	if _, ok := obj.Labels[fission.POOLMGR_INSTANCEID_LABEL]; ok {
+		return true
+	}
+	return obj.Labels["executorType"] == fission.ExecutorTypePoolmgr || obj.Labels["unmanaged"] == "true"
+}
+
+// CleanupOldObjects deletes the pool deployments and pods that previous
+// executor runs left behind, except the adopted ones.
+func (gpm *GenericPoolManager) CleanupOldObjects(startTime time.Time) {
+	adopted := make(map[types.UID]bool)
+	for _, obj := range gpm.adoptedObjects {
+		adopted[obj.UID] = true
+	}
+
+	// Deployments that weren't adopted are idle pools and can be
+	// cleaned up immediately.
+	adoptedSelectors, err := gpm.cleanupDeployments(startTime, adopted)
+	if err != nil {
+		log.Printf("Failed to cleanup pool deployments: %v", err)
+		return
+	}
+
+	// Pods might still be running user functions, so we give them
+	// a few minutes before terminating them.  This time is the
+	// maximum function runtime, plus the time a router might
+	// still route to an old instance, i.e. router cache expiry
+	// time.
+	time.Sleep(6 * time.Minute)
+
+	err = gpm.cleanupPods(startTime, adopted, adoptedSelectors)
+	if err != nil {
+		log.Printf("Failed to cleanup pods: %v", err)
+	}
+}
+
+// cleanupDeployments deletes the leftover pool deployments that weren't
+// adopted, and returns the pod selectors of the adopted ones.
+func (gpm *GenericPoolManager) cleanupDeployments(startTime time.Time, adopted map[types.UID]bool) ([]labels.Selector, error) {
+	deplList, err := gpm.kubernetesClient.ExtensionsV1beta1().Deployments(gpm.namespace).List(metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	deletePropagation := metav1.DeletePropagationBackground
+	delOpt := metav1.DeleteOptions{PropagationPolicy: &deletePropagation}
+
+	adoptedSelectors := make([]labels.Selector, 0)
+	for _, depl := range deplList.Items {
+		if !isPoolmgrObject(&depl.ObjectMeta) {
+			continue
+		}
+		if adopted[depl.ObjectMeta.UID] {
+			adoptedSelectors = append(adoptedSelectors,
+				labels.SelectorFromSet(depl.Spec.Selector.MatchLabels))
+			continue
+		}
+		if util.IsLeftover(&depl.ObjectMeta, startTime) {
+			log.Printf("Cleaning up deployment %v", depl.ObjectMeta.Name)
+			err := gpm.kubernetesClient.ExtensionsV1beta1().Deployments(gpm.namespace).Delete(depl.ObjectMeta.Name, &delOpt)
+			if err != nil {
+				log.Printf("Error cleaning up deployment %v: %v", depl.ObjectMeta.Name, err)
+			}
+		}
+	}
+	return adoptedSelectors, nil
+}
+
+// cleanupPods deletes the leftover pool and specialized pods, except
+// adopted pods and pods of adopted pools.
+func (gpm *GenericPoolManager) cleanupPods(startTime time.Time, adopted map[types.UID]bool, adoptedSelectors []labels.Selector) error {
+	podList, err := gpm.kubernetesClient.CoreV1().Pods(gpm.namespace).List(metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+	for _, pod := range podList.Items {
+		if !isPoolmgrObject(&pod.ObjectMeta) || adopted[pod.ObjectMeta.UID] {
+			continue
+		}
+		if matchesAny(adoptedSelectors, pod.ObjectMeta.Labels) {
+			continue
+		}
+		if util.IsLeftover(&pod.ObjectMeta, startTime) {
+			log.Printf("Cleaning up pod %v", pod.ObjectMeta.Name)
+			err := gpm.kubernetesClient.CoreV1().Pods(gpm.namespace).Delete(pod.ObjectMeta.Name, nil)
+			if err != nil {
+				log.Printf("Error cleaning up pod %v: %v", pod.ObjectMeta.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+func matchesAny(selectors []labels.Selector, podLabels map[string]string) bool {
+	for _, sel := range selectors {
+		if sel.Matches(labels.Set(podLabels)) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,233 @@
+/*
+Copyright 2018 The Fission Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package poolmgr
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	apiv1 "k8s.io/client-go/pkg/api/v1"
+
+	"github.com/fission/fission"
+)
+
+// Specialized pods keep their resources until they're reaped, which may
+// leave no room in the cluster for the pods of the functions being called.
+// The least recently used specialized pod is evicted when an environment
+// reaches its MaxSpecializedPods, when a namespace reaches the
+// MaxSpecializedPods of its FissionQuota, or when a pool can't get a warm
+// pod because the cluster is full.
+
+const (
+	// minEvictionIdleTime is how long a specialized pod must have been
+	// unused before it may be evicted, so that pods serving requests
+	// aren't.
+	minEvictionIdleTime = 30 * time.Second
+
+	// evictionInterval is how often a pool waiting for a warm pod that
+	// can't be scheduled evicts a specialized pod.
+	evictionInterval = 10 * time.Second
+
+	// reason of the PodScheduled condition of pods that don't fit on any
+	// node
+	podReasonUnschedulable = "Unschedulable"
+)
+
+type (
+	// evictionCandidate is a specialized pod with the time it was last
+	// used, as recorded on it or as known to this replica.
+	evictionCandidate struct {
+		pod        *apiv1.Pod
+		lastAccess time.Time
+	}
+)
+
+// evictLRUPod deletes the least recently used of the specialized pods with
+// the given labels, and returns false if none of them may be evicted.  Pods
+// used in the last minEvictionIdleTime are left alone, and functions aren't
+// taken below their MinScale.
+func (gpm *GenericPoolManager) evictLRUPod(podLabels map[string]string) bool {
+	sel := labels.Set{"unmanaged": "true"}
+	for k, v := range podLabels {
+		sel[k] = v
+	}
+	podList, err := gpm.kubernetesClient.CoreV1().Pods(gpm.namespace).List(metav1.ListOptions{
+		LabelSelector: sel.AsSelector().String(),
+	})
+	if err != nil {
+		log.Printf("Error listing specialized pods to evict: %v", err)
+		return false
+	}
+	funcSvcByPod, err := gpm.funcSvcsByPod()
+	if err != nil {
+		log.Printf("Error listing function services to evict: %v", err)
+		return false
+	}
+
+	pods := make([]*apiv1.Pod, 0, len(podList.Items))
+	candidates := make([]evictionCandidate, 0, len(podList.Items))
+	for i := range podList.Items {
+		pod := &podList.Items[i]
+		pods = append(pods, pod)
+		if pod.ObjectMeta.DeletionTimestamp != nil {
+			continue
+		}
+		lastAccess := podLastAccessTime(pod)
+		if fsvc, ok := funcSvcByPod[pod.ObjectMeta.UID]; ok && fsvc.Atime.After(lastAccess) {
+			lastAccess = fsvc.Atime
+		}
+		if time.Since(lastAccess) < minEvictionIdleTime {
+			continue
+		}
+		candidates = append(candidates, evictionCandidate{pod: pod, lastAccess: lastAccess})
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].lastAccess.Before(candidates[j].lastAccess)
+	})
+
+	guard := makeMinScaleGuard(gpm.listFunctions(), pods)
+	for _, c := range candidates {
+		if !guard.mayReap(c.pod) {
+			continue
+		}
+		log.Printf("Evicting pod %v of function %v, unused since %v",
+			c.pod.ObjectMeta.Name, c.pod.ObjectMeta.Labels["functionName"], c.lastAccess)
+		if fsvc, ok := funcSvcByPod[c.pod.ObjectMeta.UID]; ok {
+			gpm.fsCache.DeleteEntry(fsvc)
+		}
+		gpm.deletePod(c.pod.ObjectMeta.Namespace, c.pod.ObjectMeta.Name)
+		return true
+	}
+	return false
+}
+
+// specializedPodLabels returns the labels of the pods specialized from the
+// pool.
+func (gp *GenericPool) specializedPodLabels() map[string]string {
+	return map[string]string{
+		"environmentUid": string(gp.env.Metadata.UID),
+		"unmanaged":      "true",
+	}
+}
+
+// makeRoom evicts the least recently used pods specialized for the
+// environment until it's below its MaxSpecializedPods.  It's called
+// serially by choosePodService, so that specializations don't overshoot
+// the cap together.
+func (gp *GenericPool) makeRoom() error {
+	maxPods := gp.env.Spec.MaxSpecializedPods
+	if maxPods <= 0 || sharesPods(gp.env) {
+		return nil
+	}
+
+	podList, err := gp.kubernetesClient.CoreV1().Pods(gp.namespace).List(metav1.ListOptions{
+		LabelSelector: labels.Set(gp.specializedPodLabels()).AsSelector().String(),
+	})
+	if err != nil {
+		return err
+	}
+	specialized := 0
+	for i := range podList.Items {
+		if podList.Items[i].ObjectMeta.DeletionTimestamp == nil {
+			specialized++
+		}
+	}
+
+	for ; specialized >= maxPods; specialized-- {
+		if !gp.evictPod(gp.specializedPodLabels()) {
+			return fission.MakeError(fission.ErrorNoSpace,
+				fmt.Sprintf("environment %v has %v specialized pods, its maximum, and none of them may be evicted",
+					gp.env.Metadata.Name, specialized))
+		}
+	}
+	return nil
+}
+
+// makeQuotaRoom evicts the least recently used pods specialized for
+// functions of the namespace until it's below the MaxSpecializedPods of
+// its FissionQuotas.  Like makeRoom, it's called serially by
+// choosePodService; pools of different environments may still overshoot
+// the quota together, by at most one pod each.
+func (gp *GenericPool) makeQuotaRoom(ns string) error {
+	if len(ns) == 0 || sharesPods(gp.env) {
+		return nil
+	}
+
+	quotas, err := gp.fissionClient.FissionQuotas(ns).List(metav1.ListOptions{})
+	if kerrors.IsNotFound(err) {
+		// the FissionQuota CRD isn't installed yet
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	maxPods := 0
+	for _, q := range quotas.Items {
+		if q.Spec.MaxSpecializedPods > 0 && (maxPods == 0 || q.Spec.MaxSpecializedPods < maxPods) {
+			maxPods = q.Spec.MaxSpecializedPods
+		}
+	}
+	if maxPods == 0 {
+		return nil
+	}
+
+	podLabels := map[string]string{
+		"functionNamespace": ns,
+		"unmanaged":         "true",
+	}
+	podList, err := gp.kubernetesClient.CoreV1().Pods(gp.namespace).List(metav1.ListOptions{
+		LabelSelector: labels.Set(podLabels).AsSelector().String(),
+	})
+	if err != nil {
+		return err
+	}
+	specialized := 0
+	for i := range podList.Items {
+		if podList.Items[i].ObjectMeta.DeletionTimestamp == nil {
+			specialized++
+		}
+	}
+
+	for ; specialized >= maxPods; specialized-- {
+		if !gp.evictPod(podLabels) {
+			return fission.MakeError(fission.ErrorNoSpace,
+				fmt.Sprintf("quota exceeded: namespace %v has %v specialized pods, its maximum, and none of them may be evicted",
+					ns, specialized))
+		}
+	}
+	return nil
+}
+
+// isUnschedulable returns true if the scheduler couldn't find a node for
+// the pod, usually because the cluster is out of resources.
+func isUnschedulable(pod *apiv1.Pod) bool {
+	if pod.Status.Phase != apiv1.PodPending {
+		return false
+	}
+	for _, cond := range pod.Status.Conditions {
+		if cond.Type == apiv1.PodScheduled && cond.Status == apiv1.ConditionFalse &&
+			cond.Reason == podReasonUnschedulable {
+			return true
+		}
+	}
+	return false
+}
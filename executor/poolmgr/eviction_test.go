@@ -0,0 +1,199 @@
+package poolmgr
+
+import (
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+	apiv1 "k8s.io/client-go/pkg/api/v1"
+	k8sCache "k8s.io/client-go/tools/cache"
+
+	"github.com/fission/fission"
+	"github.com/fission/fission/crd"
+	"github.com/fission/fission/executor/fscache"
+)
+
+func TestIsUnschedulable(t *testing.T) {
+	pod := func(phase apiv1.PodPhase, status apiv1.ConditionStatus, reason string) *apiv1.Pod {
+		return &apiv1.Pod{
+			Status: apiv1.PodStatus{
+				Phase: phase,
+				Conditions: []apiv1.PodCondition{
+					{Type: apiv1.PodScheduled, Status: status, Reason: reason},
+				},
+			},
+		}
+	}
+
+	if !isUnschedulable(pod(apiv1.PodPending, apiv1.ConditionFalse, podReasonUnschedulable)) {
+		t.Errorf("expected a pending pod the scheduler found no node for to be unschedulable")
+	}
+	if isUnschedulable(pod(apiv1.PodPending, apiv1.ConditionTrue, "")) {
+		t.Errorf("expected a scheduled pod to be schedulable")
+	}
+	if isUnschedulable(pod(apiv1.PodRunning, apiv1.ConditionFalse, podReasonUnschedulable)) {
+		t.Errorf("expected a running pod to be schedulable")
+	}
+	if isUnschedulable(&apiv1.Pod{Status: apiv1.PodStatus{Phase: apiv1.PodPending}}) {
+		t.Errorf("expected a pod without conditions to be schedulable")
+	}
+}
+
+const evictionTestNamespace = "fission-function"
+
+// makeEvictionTestPool returns a pool of an environment with the given
+// MaxSpecializedPods, on a fake clientset holding the pods, with the
+// functions watched by its pool manager.
+func makeEvictionTestPool(maxPods int, fns []*crd.Function, pods ...*apiv1.Pod) (*GenericPool, *fake.Clientset) {
+	objs := make([]runtime.Object, 0, len(pods))
+	for _, pod := range pods {
+		objs = append(objs, pod)
+	}
+	client := fake.NewSimpleClientset(objs...)
+
+	funcStore := k8sCache.NewStore(func(obj interface{}) (string, error) {
+		return string(obj.(*crd.Function).Metadata.UID), nil
+	})
+	for _, fn := range fns {
+		funcStore.Add(fn)
+	}
+	gpm := &GenericPoolManager{
+		kubernetesClient: client,
+		namespace:        evictionTestNamespace,
+		fsCache:          fscache.MakeFunctionServiceCache(),
+		funcStore:        funcStore,
+	}
+
+	env := &crd.Environment{
+		Metadata: metav1.ObjectMeta{Name: "nodejs", Namespace: "default", UID: types.UID("env")},
+	}
+	env.Spec.MaxSpecializedPods = maxPods
+	gp := &GenericPool{
+		env:              env,
+		kubernetesClient: client,
+		namespace:        evictionTestNamespace,
+		evictPod:         gpm.evictLRUPod,
+	}
+	return gp, client
+}
+
+// specializedPod returns a pod of the environment specialized for the
+// function, last used idle ago.
+func specializedPod(name string, fnUID string, idle time.Duration) *apiv1.Pod {
+	return &apiv1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: evictionTestNamespace,
+			Labels: map[string]string{
+				"functionName":      fnUID,
+				"functionUid":       fnUID,
+				"functionNamespace": "default",
+				"environmentUid":    "env",
+				"unmanaged":         "true",
+			},
+			Annotations: map[string]string{
+				fission.FUNCTION_RESOURCEVERSION_ANNOTATION: "1",
+				fission.LAST_ACCESS_TIME_ANNOTATION:         time.Now().Add(-idle).UTC().Format(time.RFC3339),
+			},
+		},
+	}
+}
+
+func listPodNames(t *testing.T, client *fake.Clientset) map[string]bool {
+	podList, err := client.CoreV1().Pods(evictionTestNamespace).List(metav1.ListOptions{})
+	if err != nil {
+		t.Fatalf("error listing pods: %v", err)
+	}
+	names := make(map[string]bool)
+	for _, pod := range podList.Items {
+		names[pod.ObjectMeta.Name] = true
+	}
+	return names
+}
+
+func TestEvictLRUPod(t *testing.T) {
+	gp, client := makeEvictionTestPool(0, nil,
+		specializedPod("recent", "a", 10*time.Second),
+		specializedPod("oldest", "b", 5*time.Minute),
+		specializedPod("older", "c", time.Minute))
+
+	if !gp.evictPod(gp.specializedPodLabels()) {
+		t.Fatalf("expected a pod to be evicted")
+	}
+	if names := listPodNames(t, client); names["oldest"] || len(names) != 2 {
+		t.Errorf("expected the least recently used pod to be evicted first, left %v", names)
+	}
+	if !gp.evictPod(gp.specializedPodLabels()) {
+		t.Fatalf("expected a second pod to be evicted")
+	}
+	if names := listPodNames(t, client); names["older"] || len(names) != 1 {
+		t.Errorf("expected the next least recently used pod to be evicted, left %v", names)
+	}
+
+	// pods used in the last minEvictionIdleTime may be serving requests
+	if gp.evictPod(gp.specializedPodLabels()) {
+		t.Errorf("expected a recently used pod not to be evicted")
+	}
+	if names := listPodNames(t, client); !names["recent"] {
+		t.Errorf("expected the recently used pod to be left, left %v", names)
+	}
+}
+
+func TestEvictLRUPodMinScale(t *testing.T) {
+	fn := &crd.Function{
+		Metadata: metav1.ObjectMeta{Name: "a", Namespace: "default", UID: types.UID("a"), ResourceVersion: "1"},
+	}
+	fn.Spec.InvokeStrategy.ExecutionStrategy = fission.ExecutionStrategy{
+		ExecutorType: fission.ExecutorTypePoolmgr,
+		MinScale:     1,
+		MaxScale:     1,
+	}
+	gp, client := makeEvictionTestPool(0, []*crd.Function{fn},
+		specializedPod("minscale", "a", 10*time.Minute),
+		specializedPod("other", "b", time.Minute))
+
+	if !gp.evictPod(gp.specializedPodLabels()) {
+		t.Fatalf("expected a pod to be evicted")
+	}
+	if names := listPodNames(t, client); !names["minscale"] || names["other"] {
+		t.Errorf("expected the pod keeping a function at its MinScale to be left, left %v", names)
+	}
+	if gp.evictPod(gp.specializedPodLabels()) {
+		t.Errorf("expected a function not to be taken below its MinScale")
+	}
+}
+
+func TestMakeRoom(t *testing.T) {
+	gp, client := makeEvictionTestPool(2, nil,
+		specializedPod("a", "a", 3*time.Minute),
+		specializedPod("b", "b", 2*time.Minute),
+		specializedPod("c", "c", time.Minute))
+
+	// room is made for the pod about to be specialized
+	err := gp.makeRoom()
+	if err != nil {
+		t.Fatalf("expected room to be made, got %v", err)
+	}
+	if names := listPodNames(t, client); len(names) != 1 || !names["c"] {
+		t.Errorf("expected the two least recently used pods to be evicted, left %v", names)
+	}
+
+	gp, _ = makeEvictionTestPool(2, nil,
+		specializedPod("a", "a", 10*time.Second),
+		specializedPod("b", "b", 20*time.Second))
+	err = gp.makeRoom()
+	if fe, ok := err.(fission.Error); !ok || fe.Code != fission.ErrorNoSpace {
+		t.Errorf("expected no space when no pod may be evicted, got %v", err)
+	}
+
+	gp, client = makeEvictionTestPool(0, nil,
+		specializedPod("a", "a", 3*time.Minute),
+		specializedPod("b", "b", 2*time.Minute))
+	err = gp.makeRoom()
+	if names := listPodNames(t, client); err != nil || len(names) != 2 {
+		t.Errorf("expected no pods to be evicted without a cap, left %v (%v)", names, err)
+	}
+}
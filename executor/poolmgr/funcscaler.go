@@ -0,0 +1,123 @@
+/*
+Copyright 2018 The Fission Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package poolmgr
+
+import (
+	"log"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/fission/fission"
+	"github.com/fission/fission/crd"
+	"github.com/fission/fission/executor/fscache"
+)
+
+// defaultTargetConcurrency is the number of concurrent requests per
+// instance above which another pod is specialized for a function that
+// doesn't set its own target.
+const defaultTargetConcurrency = 10
+
+// maxInstances returns how many pods may be specialized for a function.
+func maxInstances(strategy fission.ExecutionStrategy) int {
+	if strategy.MaxScale < 1 {
+		return 1
+	}
+	return strategy.MaxScale
+}
+
+// needsInstance returns true if every instance of a function's service is
+// over the function's concurrency or latency target, and the function may
+// have another instance.
+func needsInstance(instances []*fscache.FuncSvc, strategy fission.ExecutionStrategy) bool {
+	if len(instances) == 0 || len(instances) >= maxInstances(strategy) {
+		return false
+	}
+
+	targetConcurrency := strategy.TargetConcurrency
+	if targetConcurrency <= 0 {
+		targetConcurrency = defaultTargetConcurrency
+	}
+	targetLatency := time.Duration(strategy.TargetLatency) * time.Millisecond
+
+	for _, fsvc := range instances {
+		overConcurrency := fsvc.Concurrency > targetConcurrency
+		overLatency := targetLatency > 0 && fsvc.Latency > targetLatency
+		if !overConcurrency && !overLatency {
+			return false
+		}
+	}
+	return true
+}
+
+// canScaleFunctions returns true if the pool can specialize more than one
+// pod per function.  Istio routes through one service per function, and
+// shared pods aren't specialized
+// for a single function.
+func (gp *GenericPool) canScaleFunctions() bool {
+	return !gp.useIstio &&
+		!sharesPods(gp.env)
+}
+
+// ScaleFunction specializes another pod for the function in the
+// background, if all of its instances are busier than the function's
+// targets.  Only one pod is specialized for a function at a time.
+func (gp *GenericPool) ScaleFunction(m *metav1.ObjectMeta) {
+	if !gp.canScaleFunctions() {
+		return
+	}
+
+	instances, err := gp.fsCache.ListByFunction(m)
+	if err != nil {
+		return
+	}
+	strategy := instances[0].ExecutionStrategy
+	if !needsInstance(instances, strategy) {
+		return
+	}
+
+	if gp.specializeInBackground(m, strategy) {
+		log.Printf("[%v] All %v instances are over their targets, specializing another pod", m.Name, len(instances))
+	}
+}
+
+// specializeInBackground specializes another pod for the function, and
+// returns false if one is being specialized for it already.
+func (gp *GenericPool) specializeInBackground(m *metav1.ObjectMeta, strategy fission.ExecutionStrategy) bool {
+	key := crd.CacheKey(m)
+	gp.scaleLock.Lock()
+	if gp.scalingFunctions[key] {
+		gp.scaleLock.Unlock()
+		return false
+	}
+	gp.scalingFunctions[key] = true
+	gp.scaleLock.Unlock()
+
+	go func() {
+		defer func() {
+			gp.scaleLock.Lock()
+			delete(gp.scalingFunctions, key)
+			gp.scaleLock.Unlock()
+		}()
+
+		_, err := gp.GetFuncSvc(m, strategy)
+		if err != nil {
+			log.Printf("[%v] Error specializing another pod: %v", m.Name, err)
+		}
+	}()
+	return true
+}
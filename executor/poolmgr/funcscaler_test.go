@@ -0,0 +1,59 @@
+package poolmgr
+
+import (
+	"testing"
+	"time"
+
+	"github.com/fission/fission"
+	"github.com/fission/fission/executor/fscache"
+)
+
+func TestNeedsInstance(t *testing.T) {
+	instance := func(concurrency int, latency time.Duration) *fscache.FuncSvc {
+		return &fscache.FuncSvc{Concurrency: concurrency, Latency: latency}
+	}
+
+	tests := []struct {
+		instances []*fscache.FuncSvc
+		strategy  fission.ExecutionStrategy
+		expected  bool
+	}{
+		// single instance functions never scale
+		{
+			instances: []*fscache.FuncSvc{instance(100, 0)},
+			strategy:  fission.ExecutionStrategy{MaxScale: 1},
+			expected:  false,
+		},
+		// over the default concurrency target
+		{
+			instances: []*fscache.FuncSvc{instance(defaultTargetConcurrency+1, 0)},
+			strategy:  fission.ExecutionStrategy{MaxScale: 3},
+			expected:  true,
+		},
+		// one instance still has room
+		{
+			instances: []*fscache.FuncSvc{instance(5, 0), instance(1, 0)},
+			strategy:  fission.ExecutionStrategy{MaxScale: 3, TargetConcurrency: 2},
+			expected:  false,
+		},
+		// over the latency target
+		{
+			instances: []*fscache.FuncSvc{instance(1, 300*time.Millisecond)},
+			strategy:  fission.ExecutionStrategy{MaxScale: 3, TargetLatency: 200},
+			expected:  true,
+		},
+		// capped by MaxScale
+		{
+			instances: []*fscache.FuncSvc{instance(5, 0), instance(5, 0)},
+			strategy:  fission.ExecutionStrategy{MaxScale: 2, TargetConcurrency: 2},
+			expected:  false,
+		},
+	}
+
+	for i, test := range tests {
+		got := needsInstance(test.instances, test.strategy)
+		if got != test.expected {
+			t.Errorf("case %v: expected %v, got %v", i, test.expected, got)
+		}
+	}
+}
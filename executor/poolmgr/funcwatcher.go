@@ -40,7 +40,7 @@ func getIstioServiceLabels(fnName string) map[string]string {
 }
 
 func makeFuncIstioServiceRegister(crdClient *rest.RESTClient,
-	kubernetesClient *kubernetes.Clientset, fnNamespace string) k8sCache.Controller {
+	kubernetesClient kubernetes.Interface, fnNamespace string) k8sCache.Controller {
 
 	resyncPeriod := 30 * time.Second
 	lw := k8sCache.NewListWatchFromClient(crdClient, "functions", metav1.NamespaceDefault, fields.Everything())
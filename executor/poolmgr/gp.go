@@ -29,11 +29,13 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/dchest/uniuri"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/pkg/api"
@@ -50,6 +52,20 @@ import (
 
 const POD_PHASE_RUNNING string = "Running"
 
+// DefaultIdlePodReapTime is how long a specialized pod may stay unused
+// when neither its function nor its environment set an idle timeout.
+const DefaultIdlePodReapTime = 2 * time.Minute
+
+const (
+	// DefaultPodReadyTimeout is how long to wait for a pool pod to be
+	// ready when the environment doesn't set a timeout.
+	DefaultPodReadyTimeout = 5 * time.Minute
+
+	// DefaultSpecializationTimeout is how long loading a function into
+	// a pod may take when the environment doesn't set a timeout.
+	DefaultSpecializationTimeout = 3 * time.Minute
+)
+
 type (
 	GenericPool struct {
 		env                    *crd.Environment
@@ -57,6 +73,7 @@ type (
 		deployment             *v1beta1.Deployment           // kubernetes deployment
 		namespace              string                        // namespace to keep our resources
 		podReadyTimeout        time.Duration                 // timeout for generic pods to become ready
+		specializationTimeout  time.Duration                 // timeout for loading a function into a pod
 		idlePodReapTime        time.Duration                 // pods unused for idlePodReapTime are deleted
 		fsCache                *fscache.FunctionServiceCache // cache funcSvc's by function, address and podname
 		useSvc                 bool                          // create k8s service for specialized pods
@@ -65,7 +82,7 @@ type (
 		fetcherImage           string
 		fetcherImagePullPolicy apiv1.PullPolicy
 		runtimeImagePullPolicy apiv1.PullPolicy // pull policy for generic pool to created env deployment
-		kubernetesClient       *kubernetes.Clientset
+		kubernetesClient       kubernetes.Interface
 		fissionClient          *crd.FissionClient
 		instanceId             string // poolmgr instance id
 		labelsForPool          map[string]string
@@ -73,6 +90,14 @@ type (
 		sharedMountPath        string // used by generic pool when creating env deployment to specify the share volume path for fetcher & env
 		sharedSecretPath       string
 		sharedCfgMapPath       string
+		autoscale              bool            // resize the pool between env MinPoolsize and MaxPoolsize
+		scaleLock              sync.Mutex      // protects replicas, specializations and scalingFunctions
+		specializations        []time.Time     // recent pods taken from the pool, for autoscaling
+		scalingFunctions       map[string]bool // functions getting another instance specialized
+		stopCh                 chan struct{}
+		isLeader               func() bool                            // whether this executor replica manages the pool deployments
+		pods                   *podInformer                           // watched pods of the function namespace
+		evictPod               func(podLabels map[string]string) bool // evicts the least recently used specialized pod with the labels
 	}
 
 	// serialize the choosing of pods so that choices don't conflict
@@ -99,16 +124,49 @@ func getImagePullPolicy(policy string) apiv1.PullPolicy {
 
 func MakeGenericPool(
 	fissionClient *crd.FissionClient,
-	kubernetesClient *kubernetes.Clientset,
+	kubernetesClient kubernetes.Interface,
 	env *crd.Environment,
 	initialReplicas int32,
 	namespace string,
 	fsCache *fscache.FunctionServiceCache,
 	instanceId string,
-	enableIstio bool) (*GenericPool, error) {
+	enableIstio bool,
+	isLeader func() bool,
+	pods *podInformer,
+	evictPod func(podLabels map[string]string) bool) (*GenericPool, error) {
 
 	log.Printf("Creating pool for environment %v", env.Metadata)
 
+	gp := newGenericPool(fissionClient, kubernetesClient, env, initialReplicas,
+		namespace, fsCache, instanceId, enableIstio, isLeader, pods, evictPod)
+
+	// create the pool
+	err := gp.createPool()
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("[%v] Deployment created", env.Metadata)
+
+	gp.start()
+
+	return gp, nil
+}
+
+// newGenericPool sets up a pool for the environment without creating or
+// adopting its deployment.
+func newGenericPool(
+	fissionClient *crd.FissionClient,
+	kubernetesClient kubernetes.Interface,
+	env *crd.Environment,
+	initialReplicas int32,
+	namespace string,
+	fsCache *fscache.FunctionServiceCache,
+	instanceId string,
+	enableIstio bool,
+	isLeader func() bool,
+	pods *podInformer,
+	evictPod func(podLabels map[string]string) bool) *GenericPool {
+
 	fetcherImage := os.Getenv("FETCHER_IMAGE")
 	if len(fetcherImage) == 0 {
 		fetcherImage = "fission/fetcher"
@@ -122,26 +180,43 @@ func MakeGenericPool(
 		runtimeImagePullPolicy = "IfNotPresent"
 	}
 
-	// TODO: in general we need to provide the user a way to configure pools.  Initial
-	// replicas, autoscaling params, various timeouts, etc.
 	gp := &GenericPool{
-		env:              env,
-		replicas:         initialReplicas, // TODO make this an env param instead?
-		requestChannel:   make(chan *choosePodRequest),
-		fissionClient:    fissionClient,
-		kubernetesClient: kubernetesClient,
-		namespace:        namespace,
-		podReadyTimeout:  5 * time.Minute, // TODO make this an env param?
-		idlePodReapTime:  3 * time.Minute, // TODO make this configurable
-		fsCache:          fsCache,
-		poolInstanceId:   uniuri.NewLen(8),
-		instanceId:       instanceId,
-		fetcherImage:     fetcherImage,
-		useSvc:           false,       // defaults off -- svc takes a second or more to become routable, slowing cold start
-		useIstio:         enableIstio, // defaults off -- istio integration requires pod relabeling and it takes a second or more to become routable, slowing cold start
-		sharedMountPath:  "/userfunc", // change this may break v1 compatibility, since most of the v1 environments have hard-coded "/userfunc" in loading path
-		sharedSecretPath: "/secrets",
-		sharedCfgMapPath: "/configs",
+		env:                   env,
+		replicas:              initialReplicas,
+		requestChannel:        make(chan *choosePodRequest),
+		fissionClient:         fissionClient,
+		kubernetesClient:      kubernetesClient,
+		namespace:             namespace,
+		podReadyTimeout:       DefaultPodReadyTimeout,
+		specializationTimeout: DefaultSpecializationTimeout,
+		idlePodReapTime:       DefaultIdlePodReapTime,
+		fsCache:               fsCache,
+		poolInstanceId:        uniuri.NewLen(8),
+		instanceId:            instanceId,
+		fetcherImage:          fetcherImage,
+		useSvc:                false,       // defaults off -- svc takes a second or more to become routable, slowing cold start
+		useIstio:              enableIstio, // defaults off -- istio integration requires pod relabeling and it takes a second or more to become routable, slowing cold start
+		sharedMountPath:       "/userfunc", // change this may break v1 compatibility, since most of the v1 environments have hard-coded "/userfunc" in loading path
+		sharedSecretPath:      "/secrets",
+		sharedCfgMapPath:      "/configs",
+		stopCh:                make(chan struct{}),
+		isLeader:              isLeader,
+		scalingFunctions:      make(map[string]bool),
+		pods:                  pods,
+		evictPod:              evictPod,
+	}
+
+	gp.autoscale = isAutoscaled(env) &&
+		!sharesPods(env)
+
+	if env.Spec.IdleTimeout > 0 {
+		gp.idlePodReapTime = time.Duration(env.Spec.IdleTimeout) * time.Second
+	}
+	if env.Spec.PodReadyTimeout > 0 {
+		gp.podReadyTimeout = time.Duration(env.Spec.PodReadyTimeout) * time.Second
+	}
+	if env.Spec.SpecializationTimeout > 0 {
+		gp.specializationTimeout = time.Duration(env.Spec.SpecializationTimeout) * time.Second
 	}
 
 	gp.runtimeImagePullPolicy = getImagePullPolicy(runtimeImagePullPolicy)
@@ -153,20 +228,21 @@ func MakeGenericPool(
 	gp.labelsForPool = map[string]string{
 		"environmentName":                 gp.env.Metadata.Name,
 		"environmentUid":                  string(gp.env.Metadata.UID),
+		environmentVersionLabel:           environmentVersion(gp.env),
 		fission.EXECUTOR_INSTANCEID_LABEL: gp.instanceId,
 		"executorType":                    fission.ExecutorTypePoolmgr,
 	}
 
-	// create the pool
-	err := gp.createPool()
-	if err != nil {
-		return nil, err
-	}
-	log.Printf("[%v] Deployment created", env.Metadata)
+	return gp
+}
 
+// start runs the pool's goroutines once its deployment exists.
+func (gp *GenericPool) start() {
 	go gp.choosePodService()
 
-	return gp, nil
+	if gp.autoscale {
+		go gp.poolAutoscaler(gp.deployment.ObjectMeta.Name)
+	}
 }
 
 // choosePodService serializes the choosing of pods
@@ -180,6 +256,8 @@ func (gp *GenericPool) choosePodService() {
 				continue
 			}
 			req.responseChannel <- &choosePodResponse{pod: pod}
+		case <-gp.stopCh:
+			return
 		}
 	}
 }
@@ -191,7 +269,11 @@ func (gp *GenericPool) choosePod(newLabels map[string]string) (*apiv1.Pod, error
 		newLabels:       newLabels,
 		responseChannel: make(chan *choosePodResponse),
 	}
-	gp.requestChannel <- req
+	select {
+	case gp.requestChannel <- req:
+	case <-gp.stopCh:
+		return nil, errors.New("pool is stopped")
+	}
 	resp := <-req.responseChannel
 	return resp.pod, resp.error
 }
@@ -199,6 +281,16 @@ func (gp *GenericPool) choosePod(newLabels map[string]string) (*apiv1.Pod, error
 // _choosePod is called serially by choosePodService
 func (gp *GenericPool) _choosePod(newLabels map[string]string) (*apiv1.Pod, error) {
 	startTime := time.Now()
+
+	err := gp.makeRoom()
+	if err != nil {
+		return nil, err
+	}
+	err = gp.makeQuotaRoom(newLabels["functionNamespace"])
+	if err != nil {
+		return nil, err
+	}
+
 	for {
 		// Retries took too long, error out.
 		if time.Since(startTime) > gp.podReadyTimeout {
@@ -217,28 +309,27 @@ func (gp *GenericPool) _choosePod(newLabels map[string]string) (*apiv1.Pod, erro
 		}
 		readyPods := make([]*apiv1.Pod, 0, len(podList.Items))
 		for i := range podList.Items {
-			pod := podList.Items[i]
-
-			// If a pod has no IP it's not ready
-			if len(pod.Status.PodIP) == 0 || string(pod.Status.Phase) != POD_PHASE_RUNNING {
-				continue
-			}
-
-			// Wait for all containers in the pod to be ready
-			podReady := true
-			for _, cs := range pod.Status.ContainerStatuses {
-				podReady = podReady && cs.Ready
-			}
-
-			// add it to the list of ready pods
-			if podReady {
-				readyPods = append(readyPods, &pod)
+			if isReadyPoolPod(&podList.Items[i]) {
+				readyPods = append(readyPods, &podList.Items[i])
 			}
 		}
 		log.Printf("[%v] found %v ready pods of %v total", newLabels, len(readyPods), len(podList.Items))
 
 		// If there are no ready pods, wait and retry.
 		if len(readyPods) == 0 {
+			// An autoscaled pool may have shrunk to zero; make sure
+			// at least one pod is on its way.
+			if gp.autoscale {
+				gp.scaleLock.Lock()
+				replicas := gp.replicas
+				gp.scaleLock.Unlock()
+				if replicas == 0 {
+					err = gp.scalePool(gp.deployment.ObjectMeta.Name, 1)
+					if err != nil {
+						return nil, err
+					}
+				}
+			}
 			err = gp.waitForReadyPod()
 			if err != nil {
 				return nil, err
@@ -246,32 +337,61 @@ func (gp *GenericPool) _choosePod(newLabels map[string]string) (*apiv1.Pod, erro
 			continue
 		}
 
+		if sharesPods(gp.env) {
+			// pack functions into the same shared pod
+			chosenPod := oldestPod(readyPods)
+			log.Printf("Chosen shared pod: %v (in %v)", chosenPod.ObjectMeta.Name, time.Since(startTime))
+			return chosenPod, nil
+		}
+
 		// Pick a ready pod.  For now just choose randomly;
 		// ideally we'd care about which node it's running on,
 		// and make a good scheduling decision.
 		chosenPod := readyPods[rand.Intn(len(readyPods))]
 
-		if gp.env.Spec.AllowedFunctionsPerContainer != fission.AllowedFunctionsPerContainerInfinite {
-			// Relabel.  If the pod already got picked and
-			// modified, this should fail; in that case just
-			// retry.
-			chosenPod.ObjectMeta.Labels = newLabels
-			log.Printf("relabeling pod: [%v]", chosenPod.ObjectMeta.Name)
-			_, err = gp.kubernetesClient.CoreV1().Pods(gp.namespace).Update(chosenPod)
-			if err != nil {
-				log.Printf("failed to relabel pod [%v]: %v", chosenPod.ObjectMeta.Name, err)
-				continue
-			}
+		// Relabel.  If the pod already got picked and
+		// modified, this should fail; in that case just
+		// retry.
+		chosenPod.ObjectMeta.Labels = newLabels
+		if chosenPod.ObjectMeta.Annotations == nil {
+			chosenPod.ObjectMeta.Annotations = make(map[string]string)
+		}
+		chosenPod.ObjectMeta.Annotations[fission.LAST_ACCESS_TIME_ANNOTATION] = time.Now().UTC().Format(time.RFC3339)
+		log.Printf("relabeling pod: [%v]", chosenPod.ObjectMeta.Name)
+		_, err = gp.kubernetesClient.CoreV1().Pods(gp.namespace).Update(chosenPod)
+		if err != nil {
+			log.Printf("failed to relabel pod [%v]: %v", chosenPod.ObjectMeta.Name, err)
+			continue
 		}
 		log.Printf("Chosen pod: %v (in %v)", chosenPod.ObjectMeta.Name, time.Since(startTime))
 		return chosenPod, nil
 	}
 }
 
+// isReadyPoolPod returns true if the pod has an IP, is running, and all
+// its containers are ready.
+func isReadyPoolPod(pod *apiv1.Pod) bool {
+	// If a pod has no IP it's not ready
+	if len(pod.Status.PodIP) == 0 || string(pod.Status.Phase) != POD_PHASE_RUNNING {
+		return false
+	}
+
+	// Wait for all containers in the pod to be ready
+	for _, cs := range pod.Status.ContainerStatuses {
+		if !cs.Ready {
+			return false
+		}
+	}
+	return true
+}
+
 func (gp *GenericPool) labelsForFunction(metadata *metav1.ObjectMeta) map[string]string {
 	return map[string]string{
 		"functionName":                    metadata.Name,
 		"functionUid":                     string(metadata.UID),
+		"functionNamespace":               metadata.Namespace, // counted against the namespace's FissionQuotas
+		"environmentUid":                  string(gp.env.Metadata.UID),
+		environmentVersionLabel:           environmentVersion(gp.env),
 		"unmanaged":                       "true", // this allows us to easily find pods not managed by the deployment
 		fission.EXECUTOR_INSTANCEID_LABEL: gp.instanceId,
 	}
@@ -371,6 +491,25 @@ func (gp *GenericPool) specializePod(pod *apiv1.Pod, metadata *metav1.ObjectMeta
 		targetFilename = string(fn.Metadata.UID)
 	}
 
+	// functions are loaded into shared pods at their own path, with the
+	// v2 specialize API
+	shared := sharesPods(gp.env)
+	if shared {
+		// the environment of the pod is shared by its functions
+		if len(fn.Spec.Env) > 0 {
+			return util.MakeSpecializationError(fission.MakeError(fission.ErrorInvalidArgument,
+				fmt.Sprintf("environment %v loads several functions per container, so functions using it can't set Env",
+					gp.env.Metadata.Name)))
+		}
+		targetFilename = functionFilename(metadata)
+	} else if gp.env.Spec.Version != 2 && len(fn.Spec.Env) > 0 {
+		// the v1 specialize API has no way to pass it
+		return util.MakeSpecializationError(fission.MakeError(fission.ErrorInvalidArgument,
+			fmt.Sprintf("environment %v is a v1 environment, so functions using it can't set Env",
+				gp.env.Metadata.Name)))
+	}
+
+	fetchStartTime := time.Now()
 	err = fetcherClient.MakeClient(fetcherUrl).Fetch(&fetcher.FetchRequest{
 		FetchType: fetcher.FETCH_DEPLOYMENT,
 		Package: metav1.ObjectMeta{
@@ -382,19 +521,33 @@ func (gp *GenericPool) specializePod(pod *apiv1.Pod, metadata *metav1.ObjectMeta
 		ConfigMaps: fn.Spec.ConfigMaps,
 	})
 	if err != nil {
-		return err
+		return util.MakeSpecializationError(err)
 	}
+	specializationDuration.WithLabelValues(gp.env.Metadata.Name, "fetch").Observe(time.Since(fetchStartTime).Seconds())
 
 	// get function run container to specialize
 	log.Printf("[%v] specializing pod", metadata.Name)
 
-	// retry the specialize call a few times in case the env server hasn't come up yet
-	maxRetries := 20
+	// retry the specialize call until the specialization timeout in
+	// case the env server hasn't come up yet
+	deadline := time.Now().Add(gp.specializationTimeout)
+	client := &http.Client{Timeout: gp.specializationTimeout}
+
+	// the pod is specialized over HTTP, so its spec holds none of the
+	// function's values
+	fnEnv, err := util.ResolveFunctionEnv(gp.kubernetesClient, fn)
+	if err != nil {
+		return err
+	}
 
 	loadReq := fission.FunctionLoadRequest{
 		FilePath:         filepath.Join(gp.sharedMountPath, targetFilename),
 		FunctionName:     fn.Spec.Package.FunctionName,
 		FunctionMetadata: &fn.Metadata,
+		EnvVars:          util.EnvVarMap(fnEnv),
+	}
+	if shared {
+		loadReq.URL = functionPath(metadata)
 	}
 
 	body, err := json.Marshal(loadReq)
@@ -402,20 +555,22 @@ func (gp *GenericPool) specializePod(pod *apiv1.Pod, metadata *metav1.ObjectMeta
 		return err
 	}
 
-	for i := 0; i < maxRetries; i++ {
+	specializeStartTime := time.Now()
+	for i := 0; ; i++ {
 		var resp2 *http.Response
-		if gp.env.Spec.Version == 2 {
+		if gp.env.Spec.Version == 2 || shared {
 			specializeUrl := gp.getSpecializeUrl(podIP, 2)
 			log.Printf("specialize url: %v", specializeUrl)
-			resp2, err = http.Post(specializeUrl, "application/json", bytes.NewReader(body))
+			resp2, err = client.Post(specializeUrl, "application/json", bytes.NewReader(body))
 		} else {
 			specializeUrl := gp.getSpecializeUrl(podIP, 1)
-			resp2, err = http.Post(specializeUrl, "text/plain", bytes.NewReader([]byte{}))
+			resp2, err = client.Post(specializeUrl, "text/plain", bytes.NewReader([]byte{}))
 		}
 
 		if err == nil && resp2.StatusCode < 300 {
 			// Success
 			resp2.Body.Close()
+			specializationDuration.WithLabelValues(gp.env.Metadata.Name, "specialize").Observe(time.Since(specializeStartTime).Seconds())
 			return nil
 		}
 
@@ -425,9 +580,7 @@ func (gp *GenericPool) specializePod(pod *apiv1.Pod, metadata *metav1.ObjectMeta
 		if urlErr, ok := err.(*url.Error); ok {
 			if netErr, ok := urlErr.Err.(*net.OpError); ok {
 				if netErr.Op == "dial" {
-					if i < maxRetries-1 {
-						retry = true
-					}
+					retry = true
 				}
 			}
 		}
@@ -443,17 +596,27 @@ func (gp *GenericPool) specializePod(pod *apiv1.Pod, metadata *metav1.ObjectMeta
 			retry = true
 		}
 
-		if retry && i < maxRetries-1 {
-			time.Sleep(500 * time.Duration(2*i) * time.Millisecond)
+		// Don't wait for a server that crashed on start to come up
+		if retry {
+			p, perr := gp.kubernetesClient.CoreV1().Pods(gp.namespace).Get(pod.ObjectMeta.Name, metav1.GetOptions{})
+			if perr == nil {
+				if ferr := util.PodFailureError(p); ferr != nil {
+					log.Printf("Failed to specialize pod: %v", ferr)
+					return util.MakeSpecializationError(ferr)
+				}
+			}
+		}
+
+		backoff := time.Duration(i) * time.Second
+		if retry && time.Now().Add(backoff).Before(deadline) {
+			time.Sleep(backoff)
 			log.Printf("Error connecting to pod (%v), retrying", err)
 			continue
 		}
 
 		log.Printf("Failed to specialize pod: %v", err)
-		return err
+		return util.MakeSpecializationError(err)
 	}
-
-	return nil
 }
 
 // A pool is a deployment of generic containers for an env.  This
@@ -484,6 +647,9 @@ func (gp *GenericPool) createPool() error {
 		ObjectMeta: metav1.ObjectMeta{
 			Name:   poolDeploymentName,
 			Labels: gp.labelsForPool,
+			Annotations: map[string]string{
+				fission.ENVIRONMENT_RESOURCEVERSION_ANNOTATION: gp.env.Metadata.ResourceVersion,
+			},
 		},
 		Spec: v1beta1.DeploymentSpec{
 			Replicas: &gp.replicas,
@@ -633,6 +799,8 @@ func (gp *GenericPool) createPool() error {
 		},
 	}
 
+	deployment.Spec.Template.Spec = fission.MergePodSpecs(&deployment.Spec.Template.Spec, gp.env.Spec.PodSpec)
+
 	depl, err := gp.kubernetesClient.ExtensionsV1beta1().Deployments(gp.namespace).Create(deployment)
 	if err != nil {
 		log.Printf("Error creating deployment for %s in kubernetes, err: %v", deployment.Name, err)
@@ -642,25 +810,46 @@ func (gp *GenericPool) createPool() error {
 	return nil
 }
 
+// waitForReadyPod waits, on the pod informer, for one of the pool's pods
+// to be ready.  While the pool's pods can't be scheduled for lack of
+// resources, idle specialized pods are evicted to make room for them.  If
+// a pod's image can't be pulled or it keeps crashing, it fails right away
+// with the reason.
 func (gp *GenericPool) waitForReadyPod() error {
-	startTime := time.Now()
+	selector := labels.Set(gp.deployment.Spec.Selector.MatchLabels).AsSelector()
+	timeout := time.After(gp.podReadyTimeout)
+	var lastEviction time.Time
 	for {
-		// TODO: for now we just poll; use a watch instead
-		depl, err := gp.kubernetesClient.ExtensionsV1beta1().Deployments(gp.namespace).Get(
-			gp.deployment.ObjectMeta.Name, metav1.GetOptions{})
-		if err != nil {
-			log.Printf("err: %v", err)
-			return err
-		}
-		gp.deployment = depl
-		if gp.deployment.Status.AvailableReplicas > 0 {
-			return nil
+		changed := gp.pods.changes()
+		if gp.pods.hasSynced() {
+			unschedulable := false
+			var failure error
+			for _, pod := range gp.pods.listPods(selector) {
+				if isReadyPoolPod(pod) {
+					return nil
+				}
+				unschedulable = unschedulable || isUnschedulable(pod)
+				if failure == nil {
+					failure = util.PodFailureError(pod)
+				}
+			}
+			if failure != nil {
+				log.Printf("Pool of environment %v can't get a ready pod: %v", gp.env.Metadata.Name, failure)
+				return failure
+			}
+			if unschedulable && time.Since(lastEviction) > evictionInterval {
+				lastEviction = time.Now()
+				gp.evictPod(map[string]string{})
+			}
 		}
 
-		if time.Since(startTime) > gp.podReadyTimeout {
+		select {
+		case <-changed:
+		case <-timeout:
 			return errors.New("timeout: waited too long for pod to be ready")
+		case <-gp.stopCh:
+			return errors.New("pool is stopped")
 		}
-		time.Sleep(1000 * time.Millisecond)
 	}
 }
 
@@ -685,7 +874,26 @@ func (gp *GenericPool) createSvc(name string, labels map[string]string) (*apiv1.
 	return svc, err
 }
 
-func (gp *GenericPool) GetFuncSvc(m *metav1.ObjectMeta) (*fscache.FuncSvc, error) {
+// GetFuncSvc specializes a pod from the pool for the function, as another
+// instance of the function's service. The pod is reaped once it's unused
+// for the strategy's idle timeout, or the environment's idle timeout if
+// the strategy has none.
+func (gp *GenericPool) GetFuncSvc(m *metav1.ObjectMeta, strategy fission.ExecutionStrategy) (*fscache.FuncSvc, error) {
+	// Another executor replica may have specialized a pod for this
+	// function already.
+	fsvc, err := gp.getSpecializedFuncSvc(m, strategy)
+	if err != nil {
+		return nil, err
+	}
+	if fsvc != nil {
+		return fsvc, nil
+	}
+	return gp.specializeFuncSvc(m, strategy)
+}
+
+// specializeFuncSvc specializes a pod from the pool for the function, and
+// adds it to the cache as another instance of the function's service.
+func (gp *GenericPool) specializeFuncSvc(m *metav1.ObjectMeta, strategy fission.ExecutionStrategy) (*fscache.FuncSvc, error) {
 	log.Printf("[%v] Choosing pod from pool", m.Name)
 	newLabels := gp.labelsForFunction(m)
 
@@ -735,6 +943,24 @@ func (gp *GenericPool) GetFuncSvc(m *metav1.ObjectMeta) (*fscache.FuncSvc, error
 		return nil, err
 	}
 
+	if sharesPods(gp.env) {
+		// the pod stays in the pool, serving other functions too
+		err = gp.specializePod(pod, m)
+		if err != nil {
+			return nil, err
+		}
+		log.Printf("Loaded function %v into shared pod %v", m.Name, pod.ObjectMeta.Name)
+		gp.annotateSharedPod(pod, m)
+
+		fsvc := gp.makeFuncSvc(m, pod, strategy)
+		_, err = gp.fsCache.Add(*fsvc)
+		if err != nil {
+			return nil, err
+		}
+		return fsvc, nil
+	}
+	gp.recordSpecialization()
+
 	err = gp.specializePod(pod, m)
 	if err != nil {
 		gp.scheduleDeletePod(pod.ObjectMeta.Name)
@@ -742,13 +968,8 @@ func (gp *GenericPool) GetFuncSvc(m *metav1.ObjectMeta) (*fscache.FuncSvc, error
 	}
 	log.Printf("Specialized pod: %v", pod.ObjectMeta.Name)
 
-	var svcHost string
 	if gp.useSvc && !gp.useIstio {
-		svcName := fmt.Sprintf("svc-%v", m.Name)
-		if len(m.UID) > 0 {
-			svcName = fmt.Sprintf("%s-%v", svcName, m.UID)
-		}
-
+		svcName := gp.svcNameForFunction(m)
 		labels := gp.labelsForFunction(m)
 		svc, err := gp.createSvc(svcName, labels)
 		if err != nil {
@@ -759,10 +980,39 @@ func (gp *GenericPool) GetFuncSvc(m *metav1.ObjectMeta) (*fscache.FuncSvc, error
 			gp.scheduleDeletePod(pod.ObjectMeta.Name)
 			return nil, errors.New(fmt.Sprintf("sanity check failed for svc %v", svc.ObjectMeta.Name))
 		}
+	}
+
+	fsvc := gp.makeFuncSvc(m, pod, strategy)
 
+	// Record the function version and idle timeout on the pod, so that
+	// other executor replicas, or a restarted executor, can use it.
+	// This is off the request path; a pod that misses it is just not
+	// shared.
+	go gp.annotateSpecializedPod(pod, m, fsvc.IdleTimeout)
+
+	_, err = gp.fsCache.Add(*fsvc)
+	if err != nil {
+		return nil, err
+	}
+	return fsvc, nil
+}
+
+func (gp *GenericPool) svcNameForFunction(m *metav1.ObjectMeta) string {
+	svcName := fmt.Sprintf("svc-%v", m.Name)
+	if len(m.UID) > 0 {
+		svcName = fmt.Sprintf("%s-%v", svcName, m.UID)
+	}
+	return svcName
+}
+
+// makeFuncSvc describes the function service of a pod specialized for the
+// function.
+func (gp *GenericPool) makeFuncSvc(m *metav1.ObjectMeta, pod *apiv1.Pod, strategy fission.ExecutionStrategy) *fscache.FuncSvc {
+	var svcHost string
+	if gp.useSvc && !gp.useIstio {
 		// the fission router isn't in the same namespace, so return a
 		// namespace-qualified hostname
-		svcHost = fmt.Sprintf("%v.%v", svcName, gp.namespace)
+		svcHost = fmt.Sprintf("%v.%v", gp.svcNameForFunction(m), gp.namespace)
 	} else if gp.useIstio {
 		svc := fission.GetFunctionIstioServiceName(m.Name, m.Namespace)
 		svcHost = fmt.Sprintf("%v.%v:8888", svc, gp.namespace)
@@ -770,6 +1020,10 @@ func (gp *GenericPool) GetFuncSvc(m *metav1.ObjectMeta) (*fscache.FuncSvc, error
 		log.Printf("Using pod IP for specialized pod")
 		svcHost = fmt.Sprintf("%v:8888", pod.Status.PodIP)
 	}
+	if sharesPods(gp.env) {
+		// the router addresses the function by path in a shared pod
+		svcHost += functionPath(m)
+	}
 
 	kubeObjRefs := []api.ObjectReference{
 		{
@@ -782,26 +1036,89 @@ func (gp *GenericPool) GetFuncSvc(m *metav1.ObjectMeta) (*fscache.FuncSvc, error
 		},
 	}
 
-	fsvc := &fscache.FuncSvc{
+	idleTimeout := time.Duration(strategy.IdleTimeout) * time.Second
+	if idleTimeout <= 0 {
+		idleTimeout = gp.idlePodReapTime
+	}
+
+	return &fscache.FuncSvc{
 		Name:              pod.ObjectMeta.Name,
 		Function:          m,
 		Environment:       gp.env,
 		Address:           svcHost,
 		KubernetesObjects: kubeObjRefs,
 		Executor:          fscache.POOLMGR,
+		IdleTimeout:       idleTimeout,
+		ExecutionStrategy: strategy,
 		Ctime:             time.Now(),
 		Atime:             time.Now(),
 	}
+}
 
-	_, err = gp.fsCache.Add(*fsvc)
+// getSpecializedFuncSvc returns the function service of a ready pod already
+// specialized for this version of the function, but not yet an instance of
+// it in the cache, or nil if there's none.
+func (gp *GenericPool) getSpecializedFuncSvc(m *metav1.ObjectMeta, strategy fission.ExecutionStrategy) (*fscache.FuncSvc, error) {
+	pods := gp.pods.listPods(labels.Set(map[string]string{
+		"functionUid": string(m.UID),
+		"unmanaged":   "true",
+	}).AsSelector())
+
+	cached := make(map[types.UID]bool)
+	if fsvcs, err := gp.fsCache.ListByFunction(m); err == nil {
+		for _, fsvc := range fsvcs {
+			for _, obj := range fsvc.KubernetesObjects {
+				cached[obj.UID] = true
+			}
+		}
+	}
+
+	for _, pod := range pods {
+		if pod.ObjectMeta.Annotations[fission.FUNCTION_RESOURCEVERSION_ANNOTATION] != m.ResourceVersion ||
+			pod.ObjectMeta.DeletionTimestamp != nil || len(pod.Status.PodIP) == 0 || !fission.IsReadyPod(pod) ||
+			cached[pod.ObjectMeta.UID] || isOutdatedPod(pod, environmentVersion(gp.env)) {
+			continue
+		}
+
+		log.Printf("[%v] Using pod %v specialized by another executor", m.Name, pod.ObjectMeta.Name)
+		fsvc := gp.makeFuncSvc(m, pod, strategy)
+		_, err := gp.fsCache.Add(*fsvc)
+		if err != nil {
+			return nil, err
+		}
+		return fsvc, nil
+	}
+	return nil, nil
+}
+
+// annotateSpecializedPod records the version of the function a pod was
+// specialized for, and how long it may stay idle.
+func (gp *GenericPool) annotateSpecializedPod(pod *apiv1.Pod, m *metav1.ObjectMeta, idleTimeout time.Duration) {
+	p, err := gp.kubernetesClient.CoreV1().Pods(gp.namespace).Get(pod.ObjectMeta.Name, metav1.GetOptions{})
 	if err != nil {
-		return nil, err
+		log.Printf("Error getting specialized pod %v: %v", pod.ObjectMeta.Name, err)
+		return
 	}
-	return fsvc, nil
+	if p.ObjectMeta.Annotations == nil {
+		p.ObjectMeta.Annotations = make(map[string]string)
+	}
+	p.ObjectMeta.Annotations[fission.FUNCTION_RESOURCEVERSION_ANNOTATION] = m.ResourceVersion
+	p.ObjectMeta.Annotations[fission.IDLE_TIMEOUT_ANNOTATION] = idleTimeout.String()
+	_, err = gp.kubernetesClient.CoreV1().Pods(gp.namespace).Update(p)
+	if err != nil {
+		log.Printf("Error annotating specialized pod %v: %v", pod.ObjectMeta.Name, err)
+	}
+}
+
+// stop stops managing the pool, leaving its deployment alone.
+func (gp *GenericPool) stop() {
+	close(gp.stopCh)
 }
 
 // destroys the pool -- the deployment, replicaset and pods
 func (gp *GenericPool) destroy() error {
+	gp.stop()
+
 	deletePropagation := metav1.DeletePropagationBackground
 	delOpt := metav1.DeleteOptions{
 		PropagationPolicy: &deletePropagation,
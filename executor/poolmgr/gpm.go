@@ -22,11 +22,15 @@ import (
 	"os"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/pkg/api"
+	apiv1 "k8s.io/client-go/pkg/api/v1"
 	k8sCache "k8s.io/client-go/tools/cache"
 
 	"github.com/fission/fission"
@@ -44,7 +48,9 @@ const (
 type (
 	GenericPoolManager struct {
 		pools            map[string]*GenericPool
-		kubernetesClient *kubernetes.Clientset
+		latestEnvs       map[types.UID]*crd.Environment // latest version of each environment, as of the last cleanup
+		outdatedPools    map[string]types.UID           // environment UIDs, by the pool key of versions superseded by an update
+		kubernetesClient kubernetes.Interface
 		namespace        string
 
 		fissionClient  *crd.FissionClient
@@ -54,6 +60,17 @@ type (
 
 		enableIstio          bool
 		istioServiceRegister k8sCache.Controller
+
+		pods *podInformer // shared watch on the function namespace's pods
+
+		funcStore       k8sCache.Store // watched functions, for their MinScale
+		funcController  k8sCache.Controller
+		minScaleLock    sync.Mutex
+		keepingMinScale map[string]bool // functions getting MinScale pods specialized
+
+		adoptedObjects []api.ObjectReference // objects of a previous executor instance taken over at startup
+		unlabeledPods  []podLabels           // adopted pods the leader labels with their pool's labels
+		leader         int32                 // 1 once this executor replica is the leader; accessed atomically
 	}
 	request struct {
 		requestType
@@ -65,26 +82,33 @@ type (
 		error
 		pool *GenericPool
 	}
+	podLabels struct {
+		pod    *apiv1.Pod
+		labels map[string]string
+	}
 )
 
 func MakeGenericPoolManager(
 	fissionClient *crd.FissionClient,
-	kubernetesClient *kubernetes.Clientset,
+	kubernetesClient kubernetes.Interface,
 	functionNamespace string,
 	fsCache *fscache.FunctionServiceCache,
 	instanceId string) *GenericPoolManager {
 
 	gpm := &GenericPoolManager{
 		pools:            make(map[string]*GenericPool),
+		latestEnvs:       make(map[types.UID]*crd.Environment),
+		outdatedPools:    make(map[string]types.UID),
 		kubernetesClient: kubernetesClient,
 		namespace:        functionNamespace,
 		fissionClient:    fissionClient,
 		fsCache:          fsCache,
 		instanceId:       instanceId,
 		requestChannel:   make(chan *request),
+		keepingMinScale:  make(map[string]bool),
 	}
-	go gpm.service()
-	go gpm.eagerPoolCreator()
+	gpm.pods = makePodInformer(kubernetesClient, functionNamespace, gpm.podChanged)
+	gpm.funcStore, gpm.funcController = gpm.makeFuncController()
 
 	if len(os.Getenv("ENABLE_ISTIO")) > 0 {
 		istio, err := strconv.ParseBool(os.Getenv("ENABLE_ISTIO"))
@@ -92,17 +116,40 @@ func MakeGenericPoolManager(
 			log.Println("Failed to parse ENABLE_ISTIO")
 		}
 		gpm.enableIstio = istio
+	}
 
-		if gpm.enableIstio {
-			gpm.istioServiceRegister = makeFuncIstioServiceRegister(
-				gpm.fissionClient.GetCrdClient(), gpm.kubernetesClient, functionNamespace)
-		}
+	gpm.adoptedObjects = gpm.adoptExistingResources()
+
+	go gpm.service()
+	go gpm.eagerPoolCreator()
+
+	registerPoolCollector(gpm)
+
+	if gpm.enableIstio {
+		gpm.istioServiceRegister = makeFuncIstioServiceRegister(
+			gpm.fissionClient.GetCrdClient(), gpm.kubernetesClient, functionNamespace)
 	}
 
 	return gpm
 }
 
+// StartLeading makes this pool manager create, resize and destroy pool
+// deployments, and keep MinScale pods specialized for functions, once
+// this executor replica is elected leader.  Adopted pods are labeled
+// then too, since every replica adopts them at startup.
+func (gpm *GenericPoolManager) StartLeading() {
+	atomic.StoreInt32(&gpm.leader, 1)
+	gpm.labelAdoptedPods()
+}
+
+func (gpm *GenericPoolManager) IsLeader() bool {
+	return atomic.LoadInt32(&gpm.leader) == 1
+}
+
 func (gpm *GenericPoolManager) Run(ctx context.Context) {
+	go gpm.pods.run(ctx.Done())
+	go gpm.funcController.Run(ctx.Done())
+	go gpm.accessTimeSyncer(ctx)
 	if gpm.enableIstio && gpm.istioServiceRegister != nil {
 		go gpm.istioServiceRegister.Run(ctx.Done())
 	}
@@ -113,50 +160,126 @@ func (gpm *GenericPoolManager) service() {
 		req := <-gpm.requestChannel
 		switch req.requestType {
 		case GET_POOL:
-			var err error
-			pool, ok := gpm.pools[crd.CacheKey(&req.env.Metadata)]
-			if !ok {
-				poolsize := gpm.getEnvPoolsize(req.env)
-				switch req.env.Spec.AllowedFunctionsPerContainer {
-				case fission.AllowedFunctionsPerContainerInfinite:
-					poolsize = 1
-				}
-
-				pool, err = MakeGenericPool(
-					gpm.fissionClient, gpm.kubernetesClient, req.env, poolsize,
-					gpm.namespace, gpm.fsCache, gpm.instanceId, gpm.enableIstio)
-				if err != nil {
-					req.responseChannel <- &response{error: err}
-					continue
-				}
-				gpm.pools[crd.CacheKey(&req.env.Metadata)] = pool
+			pool, err := gpm.getPool(req.env)
+			if err != nil {
+				req.responseChannel <- &response{error: err}
+				continue
 			}
 			req.responseChannel <- &response{pool: pool}
 		case CLEANUP_POOLS:
 			latestEnvSet := make(map[string]bool)
 			latestEnvPoolsize := make(map[string]int)
-			for _, env := range req.envList {
-				latestEnvSet[crd.CacheKey(&env.Metadata)] = true
-				latestEnvPoolsize[crd.CacheKey(&env.Metadata)] = int(gpm.getEnvPoolsize(&env))
+			gpm.latestEnvs = make(map[types.UID]*crd.Environment)
+			for i := range req.envList {
+				env := &req.envList[i]
+				latestEnvSet[poolKey(env)] = true
+				latestEnvPoolsize[poolKey(env)] = int(gpm.getEnvPoolsize(env))
+				gpm.latestEnvs[env.Metadata.UID] = env
+			}
+			for key, uid := range gpm.outdatedPools {
+				if _, ok := gpm.latestEnvs[uid]; !ok {
+					delete(gpm.outdatedPools, key)
+				}
 			}
 			for key, pool := range gpm.pools {
 				_, ok := latestEnvSet[key]
 				poolsize := latestEnvPoolsize[key]
-				if !ok || poolsize == 0 {
-					// Env no longer exists or pool size changed to zero
+				if ok && poolsize > 0 {
+					if env := gpm.latestEnvs[pool.env.Metadata.UID]; env.Metadata.ResourceVersion != pool.env.Metadata.ResourceVersion {
+						// updated without changing its pods
+						gpm.pools[key] = gpm.refreshPool(pool, env)
+					}
+					continue
+				}
+
+				if env, updated := gpm.latestEnvs[pool.env.Metadata.UID]; !ok && updated && gpm.getEnvPoolsize(env) > 0 {
+					// The environment was updated.  The pool of the
+					// previous version is kept alongside the new one
+					// until the new one has a ready pod.
+					gpm.outdatedPools[key] = env.Metadata.UID
+					current, ok := gpm.pools[poolKey(env)]
+					if !ok || !current.hasReadyPod() {
+						continue
+					}
+					log.Printf("Retiring the pool of version %v of environment [%v]",
+						environmentVersion(pool.env), env.Metadata.Name)
+				}
+
+				// Env no longer exists, pool size changed to zero,
+				// or the pool is outdated
 
-					log.Printf("Destroying generic pool for environment [%v]", key)
-					delete(gpm.pools, key)
+				delete(gpm.pools, key)
 
-					// and delete the pool asynchronously.
-					go pool.destroy()
+				if !gpm.IsLeader() {
+					// the leader deletes the deployment
+					pool.stop()
+					continue
 				}
+
+				log.Printf("Destroying generic pool for environment [%v]", key)
+
+				// and delete the pool asynchronously.
+				go pool.destroy()
 			}
 			// no response, caller doesn't wait
 		}
 	}
 }
 
+// getPool returns the pool of the current version of the environment,
+// creating it if needed.  Until it has a ready pod, the pool of a previous
+// version of the environment is returned if there's one, so that an
+// update doesn't hold up specializations.  It's called by service only.
+func (gpm *GenericPoolManager) getPool(env *crd.Environment) (*GenericPool, error) {
+	if _, outdated := gpm.outdatedPools[poolKey(env)]; outdated {
+		// requested with a previous version of the environment
+		if latest, ok := gpm.latestEnvs[env.Metadata.UID]; ok {
+			env = latest
+		}
+	}
+
+	key := poolKey(env)
+	pool, ok := gpm.pools[key]
+	if !ok {
+		var err error
+		if gpm.IsLeader() {
+			pool, err = MakeGenericPool(
+				gpm.fissionClient, gpm.kubernetesClient, env, gpm.getEnvPoolsize(env),
+				gpm.namespace, gpm.fsCache, gpm.instanceId, gpm.enableIstio, gpm.IsLeader, gpm.pods, gpm.evictLRUPod)
+		} else {
+			// only the leader creates pools; other replicas
+			// use the pool deployment it created
+			pool, err = gpm.getLeaderPool(env)
+		}
+		if err != nil {
+			if previous := gpm.previousPool(env); previous != nil {
+				return previous, nil
+			}
+			return nil, err
+		}
+		gpm.pools[key] = pool
+	}
+
+	if !pool.hasReadyPod() {
+		if previous := gpm.previousPool(env); previous != nil {
+			return previous, nil
+		}
+	}
+	return pool, nil
+}
+
+// previousPool returns a pool of a previous version of the environment
+// that has a ready pod, or nil if there's none.
+func (gpm *GenericPoolManager) previousPool(env *crd.Environment) *GenericPool {
+	for key, pool := range gpm.pools {
+		if _, outdated := gpm.outdatedPools[key]; outdated &&
+			pool.env.Metadata.UID == env.Metadata.UID && pool.hasReadyPod() {
+			return pool
+		}
+	}
+	return nil
+}
+
 func (gpm *GenericPoolManager) GetPool(env *crd.Environment) (*GenericPool, error) {
 	c := make(chan *response)
 	gpm.requestChannel <- &request{
@@ -168,6 +291,30 @@ func (gpm *GenericPoolManager) GetPool(env *crd.Environment) (*GenericPool, erro
 	return resp.pool, resp.error
 }
 
+// GetFuncSvc specializes a pod of the pool of the function's environment
+// for the function.
+func (gpm *GenericPoolManager) GetFuncSvc(m *metav1.ObjectMeta, fn *crd.Function, env *crd.Environment) (*fscache.FuncSvc, error) {
+	pool, err := gpm.GetPool(env)
+	if err != nil {
+		return nil, err
+	}
+	// from GenericPool -> get one function container
+	// (this also adds to the cache)
+	log.Printf("[%v] getting function service from pool", m.Name)
+	return pool.GetFuncSvc(m, fn.Spec.InvokeStrategy.ExecutionStrategy)
+}
+
+// ScaleFunction lets the pool of the function with the given instance
+// specialize another instance, if the function's instances are busy.
+func (gpm *GenericPoolManager) ScaleFunction(fsvc *fscache.FuncSvc) {
+	pool, err := gpm.GetPool(fsvc.Environment)
+	if err != nil {
+		log.Printf("[%v] Error getting pool to scale function: %v", fsvc.Function.Name, err)
+		return
+	}
+	pool.ScaleFunction(fsvc.Function)
+}
+
 func (gpm *GenericPoolManager) CleanupPools(envs []crd.Environment) {
 	gpm.requestChannel <- &request{
 		requestType: CLEANUP_POOLS,
@@ -195,8 +342,9 @@ func (gpm *GenericPoolManager) eagerPoolCreator() {
 		// actual function calls.
 		for i := range envs.Items {
 			env := envs.Items[i]
-			// Create pool only if poolsize greater than zero
-			if gpm.getEnvPoolsize(&env) > 0 {
+			// Create pool only if poolsize greater than zero; other
+			// replicas pick up the leader's pools when they need them.
+			if gpm.IsLeader() && gpm.getEnvPoolsize(&env) > 0 {
 				_, err := gpm.GetPool(&envs.Items[i])
 				if err != nil {
 					log.Printf("eager-create pool failed: %v", err)
@@ -204,8 +352,12 @@ func (gpm *GenericPoolManager) eagerPoolCreator() {
 			}
 		}
 
-		// Clean up pools whose env was deleted
+		// Clean up pools whose env was deleted, or was updated
 		gpm.CleanupPools(envs.Items)
+
+		// Move the functions specialized from previous versions of
+		// their environment onto the current version
+		gpm.rollFunctions(envs.Items)
 		time.Sleep(pollSleep)
 	}
 }
@@ -217,20 +369,93 @@ func (gpm *GenericPoolManager) getEnvPoolsize(env *crd.Environment) int32 {
 	} else {
 		poolsize = int32(env.Spec.Poolsize)
 	}
+	if isAutoscaled(env) {
+		// an autoscaled pool is kept even when it may shrink to zero
+		// pods, so start it within [max(min, 1), max]
+		minPoolsize := int32(env.Spec.MinPoolsize)
+		if minPoolsize < 1 {
+			minPoolsize = 1
+		}
+		if poolsize < minPoolsize {
+			poolsize = minPoolsize
+		}
+		if poolsize > int32(env.Spec.MaxPoolsize) {
+			poolsize = int32(env.Spec.MaxPoolsize)
+		}
+	}
 	return poolsize
 }
 
-// IsValidPod checks if pod is not deleted and that it has the address passed as the argument. Also checks that all the
-// containers in it are reporting a ready status for the healthCheck.
-func (gpm *GenericPoolManager) IsValidPod(kubeObjects []api.ObjectReference, podAddress string) bool {
+// IsValid checks if the specialized pod of the function service is still valid.
+func (gpm *GenericPoolManager) IsValid(fsvc *fscache.FuncSvc) bool {
+	return gpm.isValidPod(fsvc.KubernetesObjects, fsvc.Address)
+}
+
+// isValidPod checks if pod is not deleted and that it has the address passed as the argument. Also checks that all the
+// containers in it are reporting a ready status for the healthCheck.  The pod is looked up in the pod informer, falling
+// back to the API server only until the informer has synced.
+func (gpm *GenericPoolManager) isValidPod(kubeObjects []api.ObjectReference, podAddress string) bool {
 	for _, obj := range kubeObjects {
-		if obj.Kind == "pod" {
-			pod, err := gpm.kubernetesClient.CoreV1().Pods(obj.Namespace).Get(obj.Name, metav1.GetOptions{})
-			if err == nil && strings.Contains(podAddress, pod.Status.PodIP) && fission.IsReadyPod(pod) {
-				log.Printf("Valid pod address : %s", podAddress)
-				return true
+		if obj.Kind != "pod" {
+			continue
+		}
+		var pod *apiv1.Pod
+		if gpm.pods.hasSynced() {
+			p, ok := gpm.pods.getPod(obj.Namespace, obj.Name)
+			if !ok {
+				continue
 			}
+			pod = p
+		} else {
+			p, err := gpm.kubernetesClient.CoreV1().Pods(obj.Namespace).Get(obj.Name, metav1.GetOptions{})
+			if err != nil {
+				continue
+			}
+			pod = p
+		}
+		if pod.ObjectMeta.UID == obj.UID && pod.ObjectMeta.DeletionTimestamp == nil &&
+			strings.Contains(podAddress, pod.Status.PodIP) && fission.IsReadyPod(pod) {
+			log.Printf("Valid pod address : %s", podAddress)
+			return true
 		}
 	}
 	return false
 }
+
+// podChanged is called by the pod informer.  When a specialized pod stops
+// being ready, or is deleted, its function services are removed from the
+// cache right away, so that requests aren't routed to it.
+func (gpm *GenericPoolManager) podChanged(pod *apiv1.Pod, deleted bool) {
+	shared := isSharedPod(pod)
+	if pod.ObjectMeta.Labels["unmanaged"] != "true" && !shared {
+		return
+	}
+	if !deleted && pod.ObjectMeta.DeletionTimestamp == nil &&
+		len(pod.Status.PodIP) > 0 && fission.IsReadyPod(pod) {
+		return
+	}
+
+	if shared {
+		for _, fsvc := range gpm.sharedPodFuncSvcs(pod) {
+			log.Printf("Shared pod %v of function %v is no longer ready, removing it from the cache",
+				pod.ObjectMeta.Name, fsvc.Function.Name)
+			gpm.fsCache.DeleteEntry(fsvc)
+		}
+		return
+	}
+
+	fsvcs, err := gpm.fsCache.ListByFunctionUID(types.UID(pod.ObjectMeta.Labels["functionUid"]))
+	if err != nil {
+		return
+	}
+	for _, fsvc := range fsvcs {
+		for _, obj := range fsvc.KubernetesObjects {
+			if obj.Kind == "pod" && obj.UID == pod.ObjectMeta.UID {
+				log.Printf("Pod %v of function %v is no longer ready, removing it from the cache",
+					pod.ObjectMeta.Name, fsvc.Function.Name)
+				gpm.fsCache.DeleteEntry(fsvc)
+				return
+			}
+		}
+	}
+}
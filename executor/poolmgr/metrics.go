@@ -0,0 +1,136 @@
+/*
+Copyright 2018 The Fission Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package poolmgr
+
+import (
+	"log"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+
+	"github.com/fission/fission"
+)
+
+var (
+	// time taken by each phase of pod specialization
+	specializationDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: "fission",
+			Subsystem: "executor",
+			Name:      "specialization_duration_seconds",
+			Help:      "Time taken to specialize a pool pod, by environment and phase (fetch or specialize).",
+			Buckets:   []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60},
+		},
+		[]string{"environment", "phase"},
+	)
+
+	poolSizeDesc = prometheus.NewDesc(
+		"fission_executor_pool_size",
+		"Desired number of warm pods in the generic pool of an environment.",
+		[]string{"environment", "environment_uid"}, nil)
+	poolAvailableDesc = prometheus.NewDesc(
+		"fission_executor_pool_available_pods",
+		"Warm pods available for specialization in the generic pool of an environment.",
+		[]string{"environment", "environment_uid"}, nil)
+)
+
+func init() {
+	prometheus.MustRegister(specializationDuration)
+}
+
+// poolCollector reports the size and the available pods of every generic
+// pool, reading them from the pool deployments at scrape time.  Pools
+// adopted from a previous executor instance keep that instance's id, so
+// deployments are matched by executor type only.
+type poolCollector struct {
+	lock sync.Mutex
+	gpm  *GenericPoolManager
+}
+
+// registerPoolCollector reports the pools of gpm.  The collector is
+// registered once per process; a pool manager made later (e.g. by tests)
+// takes over the registered one.
+func registerPoolCollector(gpm *GenericPoolManager) {
+	err := prometheus.Register(&poolCollector{gpm: gpm})
+	if err == nil {
+		return
+	}
+	are, ok := err.(prometheus.AlreadyRegisteredError)
+	if !ok {
+		log.Printf("Error registering pool metrics: %v", err)
+		return
+	}
+	c := are.ExistingCollector.(*poolCollector)
+	c.lock.Lock()
+	c.gpm = gpm
+	c.lock.Unlock()
+}
+
+func (c *poolCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- poolSizeDesc
+	ch <- poolAvailableDesc
+}
+
+func (c *poolCollector) Collect(ch chan<- prometheus.Metric) {
+	c.lock.Lock()
+	gpm := c.gpm
+	c.lock.Unlock()
+
+	sel := map[string]string{
+		"executorType": fission.ExecutorTypePoolmgr,
+	}
+	deplList, err := gpm.kubernetesClient.ExtensionsV1beta1().Deployments(gpm.namespace).List(
+		metav1.ListOptions{
+			LabelSelector: labels.Set(sel).AsSelector().String(),
+		})
+	if err != nil {
+		log.Printf("Error listing pool deployments for metrics: %v", err)
+		return
+	}
+
+	// an environment being updated has a pool per version
+	type poolSize struct {
+		envName   string
+		replicas  int32
+		available int32
+	}
+	sizes := make(map[string]*poolSize)
+	envUids := make([]string, 0, len(deplList.Items))
+	for _, depl := range deplList.Items {
+		envUid := depl.ObjectMeta.Labels["environmentUid"]
+		size, ok := sizes[envUid]
+		if !ok {
+			size = &poolSize{envName: depl.ObjectMeta.Labels["environmentName"]}
+			sizes[envUid] = size
+			envUids = append(envUids, envUid)
+		}
+		if depl.Spec.Replicas != nil {
+			size.replicas += *depl.Spec.Replicas
+		}
+		size.available += depl.Status.AvailableReplicas
+	}
+
+	for _, envUid := range envUids {
+		size := sizes[envUid]
+		ch <- prometheus.MustNewConstMetric(poolSizeDesc, prometheus.GaugeValue,
+			float64(size.replicas), size.envName, envUid)
+		ch <- prometheus.MustNewConstMetric(poolAvailableDesc, prometheus.GaugeValue,
+			float64(size.available), size.envName, envUid)
+	}
+}
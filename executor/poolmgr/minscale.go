@@ -0,0 +1,210 @@
+/*
+Copyright 2018 The Fission Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package poolmgr
+
+import (
+	"log"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	apiv1 "k8s.io/client-go/pkg/api/v1"
+	k8sCache "k8s.io/client-go/tools/cache"
+
+	"github.com/fission/fission"
+	"github.com/fission/fission/crd"
+)
+
+type (
+	// minScaleGuard counts the up to date specialized pods of functions
+	// with a MinScale, so that the reaper leaves them at least MinScale.
+	minScaleGuard struct {
+		minScale map[types.UID]int
+		version  map[types.UID]string
+		pods     map[types.UID]int
+	}
+)
+
+// minInstances returns how many pods are kept specialized for a function.
+func minInstances(strategy fission.ExecutionStrategy) int {
+	if strategy.MinScale <= 0 {
+		return 0
+	}
+	if strategy.MinScale > maxInstances(strategy) {
+		return maxInstances(strategy)
+	}
+	return strategy.MinScale
+}
+
+func isPoolmgrFunction(fn *crd.Function) bool {
+	executorType := fn.Spec.InvokeStrategy.ExecutionStrategy.ExecutorType
+	return len(executorType) == 0 || executorType == fission.ExecutorTypePoolmgr
+}
+
+// isSpecializedFor returns true if the pod is specialized for the given
+// version of a function and isn't going away.
+func isSpecializedFor(pod *apiv1.Pod, uid types.UID, resourceVersion string) bool {
+	return pod.ObjectMeta.Labels["functionUid"] == string(uid) &&
+		pod.ObjectMeta.Annotations[fission.FUNCTION_RESOURCEVERSION_ANNOTATION] == resourceVersion &&
+		pod.ObjectMeta.DeletionTimestamp == nil
+}
+
+func makeMinScaleGuard(fns []*crd.Function, pods []*apiv1.Pod) *minScaleGuard {
+	g := &minScaleGuard{
+		minScale: make(map[types.UID]int),
+		version:  make(map[types.UID]string),
+		pods:     make(map[types.UID]int),
+	}
+	for _, fn := range fns {
+		if !isPoolmgrFunction(fn) || minInstances(fn.Spec.InvokeStrategy.ExecutionStrategy) == 0 {
+			continue
+		}
+		g.minScale[fn.Metadata.UID] = minInstances(fn.Spec.InvokeStrategy.ExecutionStrategy)
+		g.version[fn.Metadata.UID] = fn.Metadata.ResourceVersion
+	}
+	for _, pod := range pods {
+		uid := types.UID(pod.ObjectMeta.Labels["functionUid"])
+		if _, ok := g.minScale[uid]; ok && isSpecializedFor(pod, uid, g.version[uid]) {
+			g.pods[uid]++
+		}
+	}
+	return g
+}
+
+// mayReap returns true if reaping the pod leaves its function at least
+// MinScale pods, and counts it as reaped.  Pods of older versions of a
+// function don't count towards its MinScale.
+func (g *minScaleGuard) mayReap(pod *apiv1.Pod) bool {
+	uid := types.UID(pod.ObjectMeta.Labels["functionUid"])
+	min, ok := g.minScale[uid]
+	if !ok || !isSpecializedFor(pod, uid, g.version[uid]) {
+		return true
+	}
+	if g.pods[uid] <= min {
+		return false
+	}
+	g.pods[uid]--
+	return true
+}
+
+// makeFuncController watches functions, to keep MinScale pods specialized
+// for poolmgr functions.  The periodic resync of the informer brings
+// functions back to MinScale after their pods go away.
+func (gpm *GenericPoolManager) makeFuncController() (k8sCache.Store, k8sCache.Controller) {
+	resyncPeriod := 30 * time.Second
+	listWatch := k8sCache.NewListWatchFromClient(gpm.fissionClient.GetCrdClient(), "functions", metav1.NamespaceAll, fields.Everything())
+	store, controller := k8sCache.NewInformer(listWatch, &crd.Function{}, resyncPeriod, k8sCache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			gpm.keepMinScale(obj.(*crd.Function))
+		},
+		UpdateFunc: func(oldObj interface{}, newObj interface{}) {
+			gpm.keepMinScale(newObj.(*crd.Function))
+		},
+	})
+	return store, controller
+}
+
+// listFunctions returns the watched functions.
+func (gpm *GenericPoolManager) listFunctions() []*crd.Function {
+	fns := make([]*crd.Function, 0)
+	for _, obj := range gpm.funcStore.List() {
+		fns = append(fns, obj.(*crd.Function))
+	}
+	return fns
+}
+
+// keepMinScale specializes pods for the function in the background until
+// MinScale pods are specialized for its current version.  Only the leader
+// specializes them; the other replicas pick them up as they're requested.
+func (gpm *GenericPoolManager) keepMinScale(fn *crd.Function) {
+	if !gpm.IsLeader() || !isPoolmgrFunction(fn) || !gpm.pods.hasSynced() {
+		return
+	}
+	strategy := fn.Spec.InvokeStrategy.ExecutionStrategy
+	if minInstances(strategy) == 0 {
+		return
+	}
+
+	key := crd.CacheKey(&fn.Metadata)
+	gpm.minScaleLock.Lock()
+	if gpm.keepingMinScale[key] {
+		gpm.minScaleLock.Unlock()
+		return
+	}
+	gpm.keepingMinScale[key] = true
+	gpm.minScaleLock.Unlock()
+
+	go func() {
+		defer func() {
+			gpm.minScaleLock.Lock()
+			delete(gpm.keepingMinScale, key)
+			gpm.minScaleLock.Unlock()
+		}()
+
+		env, err := gpm.fissionClient.Environments(fn.Spec.Environment.Namespace).Get(fn.Spec.Environment.Name)
+		if err != nil {
+			log.Printf("[%v] Error getting environment to keep MinScale pods: %v", fn.Metadata.Name, err)
+			return
+		}
+		pool, err := gpm.GetPool(env)
+		if err != nil {
+			log.Printf("[%v] Error getting pool to keep MinScale pods: %v", fn.Metadata.Name, err)
+			return
+		}
+
+		min := minInstances(strategy)
+		if !pool.canScaleFunctions() {
+			min = 1
+		}
+		count := pool.countInstances(&fn.Metadata)
+		for i := count; i < min; i++ {
+			log.Printf("[%v] %v of MinScale %v pods specialized, specializing another", fn.Metadata.Name, i, min)
+			_, err := pool.specializeFuncSvc(&fn.Metadata, strategy)
+			if err != nil {
+				log.Printf("[%v] Error specializing MinScale pod: %v", fn.Metadata.Name, err)
+				return
+			}
+		}
+	}()
+}
+
+// countInstances returns how many pods are specialized for the version
+// of the function.
+func (gp *GenericPool) countInstances(m *metav1.ObjectMeta) int {
+	if sharesPods(gp.env) {
+		// such pods aren't labeled for a function, only cached
+		fsvcs, err := gp.fsCache.ListByFunction(m)
+		if err != nil {
+			return 0
+		}
+		return len(fsvcs)
+	}
+
+	count := 0
+	pods := gp.pods.listPods(labels.Set(map[string]string{
+		"functionUid": string(m.UID),
+		"unmanaged":   "true",
+	}).AsSelector())
+	for _, pod := range pods {
+		if isSpecializedFor(pod, m.UID, m.ResourceVersion) {
+			count++
+		}
+	}
+	return count
+}
@@ -0,0 +1,72 @@
+package poolmgr
+
+import (
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	apiv1 "k8s.io/client-go/pkg/api/v1"
+
+	"github.com/fission/fission"
+	"github.com/fission/fission/crd"
+)
+
+func TestMinInstances(t *testing.T) {
+	if n := minInstances(fission.ExecutionStrategy{}); n != 0 {
+		t.Errorf("expected no MinScale pods, got %v", n)
+	}
+	if n := minInstances(fission.ExecutionStrategy{MinScale: 2, MaxScale: 3}); n != 2 {
+		t.Errorf("expected 2 MinScale pods, got %v", n)
+	}
+	if n := minInstances(fission.ExecutionStrategy{MinScale: 2}); n != 1 {
+		t.Errorf("expected MinScale to be capped at MaxScale, got %v", n)
+	}
+}
+
+func TestMinScaleGuard(t *testing.T) {
+	function := func(uid string, executorType fission.ExecutorType, minScale int) *crd.Function {
+		fn := &crd.Function{
+			Metadata: metav1.ObjectMeta{Name: uid, UID: types.UID(uid), ResourceVersion: "2"},
+		}
+		fn.Spec.InvokeStrategy.ExecutionStrategy = fission.ExecutionStrategy{
+			ExecutorType: executorType,
+			MinScale:     minScale,
+			MaxScale:     minScale,
+		}
+		return fn
+	}
+	pod := func(uid string, resourceVersion string) *apiv1.Pod {
+		return &apiv1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Labels:      map[string]string{"functionUid": uid, "unmanaged": "true"},
+				Annotations: map[string]string{fission.FUNCTION_RESOURCEVERSION_ANNOTATION: resourceVersion},
+			},
+		}
+	}
+
+	fns := []*crd.Function{
+		function("a", fission.ExecutorTypePoolmgr, 2),
+		function("b", "", 0),
+		function("c", fission.ExecutorTypeNewdeploy, 1),
+	}
+	a1, a2, a3 := pod("a", "2"), pod("a", "2"), pod("a", "2")
+	aOld := pod("a", "1")
+	b, c := pod("b", "2"), pod("c", "2")
+	guard := makeMinScaleGuard(fns, []*apiv1.Pod{a1, a2, a3, aOld, b, c})
+
+	// pods of older versions don't count towards MinScale
+	if !guard.mayReap(aOld) {
+		t.Errorf("expected a pod of an older version to be reapable")
+	}
+	// one of three pods may go, leaving MinScale
+	if !guard.mayReap(a1) {
+		t.Errorf("expected a pod above MinScale to be reapable")
+	}
+	if guard.mayReap(a2) || guard.mayReap(a3) {
+		t.Errorf("expected the MinScale pods to be kept")
+	}
+	// functions without MinScale, and newdeploy functions, aren't protected
+	if !guard.mayReap(b) || !guard.mayReap(c) {
+		t.Errorf("expected pods of functions without a poolmgr MinScale to be reapable")
+	}
+}
@@ -0,0 +1,124 @@
+/*
+Copyright 2018 The Fission Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package poolmgr
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes"
+	apiv1 "k8s.io/client-go/pkg/api/v1"
+	k8sCache "k8s.io/client-go/tools/cache"
+)
+
+// podInformer keeps a watched copy of the pods in the function namespace,
+// so that pools and the pool manager don't have to poll the API server.
+// Anyone can wait for the next pod change through changes().
+type podInformer struct {
+	informer k8sCache.SharedIndexInformer
+
+	lock    sync.Mutex
+	changed chan struct{} // closed, and replaced, on every pod change
+}
+
+// makePodInformer creates the pod informer; onChange is called with every
+// pod added, updated or deleted.
+func makePodInformer(kubernetesClient kubernetes.Interface, namespace string,
+	onChange func(pod *apiv1.Pod, deleted bool)) *podInformer {
+
+	pi := &podInformer{
+		changed: make(chan struct{}),
+	}
+
+	resyncPeriod := 30 * time.Second
+	listWatch := k8sCache.NewListWatchFromClient(kubernetesClient.CoreV1().RESTClient(),
+		"pods", namespace, fields.Everything())
+	pi.informer = k8sCache.NewSharedIndexInformer(listWatch, &apiv1.Pod{}, resyncPeriod, k8sCache.Indexers{})
+	pi.informer.AddEventHandler(k8sCache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			onChange(obj.(*apiv1.Pod), false)
+			pi.notify()
+		},
+		UpdateFunc: func(oldObj interface{}, newObj interface{}) {
+			onChange(newObj.(*apiv1.Pod), false)
+			pi.notify()
+		},
+		DeleteFunc: func(obj interface{}) {
+			pod, ok := obj.(*apiv1.Pod)
+			if !ok {
+				tombstone, ok := obj.(k8sCache.DeletedFinalStateUnknown)
+				if !ok {
+					return
+				}
+				pod, ok = tombstone.Obj.(*apiv1.Pod)
+				if !ok {
+					return
+				}
+			}
+			onChange(pod, true)
+			pi.notify()
+		},
+	})
+	return pi
+}
+
+func (pi *podInformer) run(stopCh <-chan struct{}) {
+	pi.informer.Run(stopCh)
+}
+
+func (pi *podInformer) hasSynced() bool {
+	return pi.informer.HasSynced()
+}
+
+func (pi *podInformer) notify() {
+	pi.lock.Lock()
+	defer pi.lock.Unlock()
+	close(pi.changed)
+	pi.changed = make(chan struct{})
+}
+
+// changes returns a channel that's closed on the next pod change.  Get
+// it before looking at the pods, so that no change is missed.
+func (pi *podInformer) changes() <-chan struct{} {
+	pi.lock.Lock()
+	defer pi.lock.Unlock()
+	return pi.changed
+}
+
+func (pi *podInformer) getPod(namespace string, name string) (*apiv1.Pod, bool) {
+	obj, exists, err := pi.informer.GetStore().GetByKey(fmt.Sprintf("%v/%v", namespace, name))
+	if err != nil || !exists {
+		return nil, false
+	}
+	return obj.(*apiv1.Pod), true
+}
+
+// listPods returns the watched pods matching the selector.  They're shared
+// with the informer and must not be modified.
+func (pi *podInformer) listPods(selector labels.Selector) []*apiv1.Pod {
+	pods := make([]*apiv1.Pod, 0)
+	for _, obj := range pi.informer.GetStore().List() {
+		pod := obj.(*apiv1.Pod)
+		if selector.Matches(labels.Set(pod.ObjectMeta.Labels)) {
+			pods = append(pods, pod)
+		}
+	}
+	return pods
+}
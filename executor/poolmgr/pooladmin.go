@@ -0,0 +1,142 @@
+/*
+Copyright 2018 The Fission Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package poolmgr
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/fission/fission/crd"
+	executorClient "github.com/fission/fission/executor/client"
+	"github.com/fission/fission/executor/fscache"
+)
+
+// recreatedAtAnnotation is set on the pod template of a pool deployment to
+// roll its pods.
+const recreatedAtAnnotation = "poolRecreatedAt"
+
+// Evict removes the function service from the cache and deletes its pod.
+func (gpm *GenericPoolManager) Evict(fsvc *fscache.FuncSvc) error {
+	gpm.fsCache.DeleteEntry(fsvc)
+	for _, kubeobj := range fsvc.KubernetesObjects {
+		if strings.ToLower(kubeobj.Kind) == "pod" {
+			log.Printf("Evicting pod %v of function %v", kubeobj.Name, fsvc.Function.Name)
+			err := gpm.kubernetesClient.CoreV1().Pods(kubeobj.Namespace).Delete(kubeobj.Name, nil)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (gpm *GenericPoolManager) GetPoolInfo(env *crd.Environment) (*executorClient.PoolInfo, error) {
+	pool, err := gpm.GetPool(env)
+	if err != nil {
+		return nil, err
+	}
+	return pool.info(), nil
+}
+
+func (gpm *GenericPoolManager) DrainPool(env *crd.Environment) error {
+	pool, err := gpm.GetPool(env)
+	if err != nil {
+		return err
+	}
+	return pool.drain()
+}
+
+func (gpm *GenericPoolManager) RecreatePool(env *crd.Environment) error {
+	pool, err := gpm.GetPool(env)
+	if err != nil {
+		return err
+	}
+	return pool.recreate()
+}
+
+// info describes the pool.  The idle pods are the pods of the pool's
+// deployment; pods leave it as they're specialized.
+func (gp *GenericPool) info() *executorClient.PoolInfo {
+	gp.scaleLock.Lock()
+	replicas := gp.replicas
+	gp.scaleLock.Unlock()
+
+	info := &executorClient.PoolInfo{
+		Environment: gp.env.Metadata,
+		Deployment:  gp.deployment.ObjectMeta.Name,
+		Replicas:    replicas,
+	}
+
+	pods := gp.pods.listPods(labels.Set(gp.deployment.Spec.Selector.MatchLabels).AsSelector())
+	for _, pod := range pods {
+		if pod.ObjectMeta.DeletionTimestamp != nil {
+			continue
+		}
+		info.Pods++
+		if isReadyPoolPod(pod) {
+			info.ReadyPods++
+		}
+	}
+
+	fsvcs, err := gp.fsCache.List()
+	if err == nil {
+		for _, fsvc := range fsvcs {
+			if fsvc.Executor == fscache.POOLMGR && fsvc.Environment != nil &&
+				fsvc.Environment.Metadata.UID == gp.env.Metadata.UID {
+				info.Specialized++
+			}
+		}
+	}
+	return info
+}
+
+// drain deletes the idle pods of the pool; its deployment replaces them.
+func (gp *GenericPool) drain() error {
+	pods := gp.pods.listPods(labels.Set(gp.deployment.Spec.Selector.MatchLabels).AsSelector())
+	for _, pod := range pods {
+		if pod.ObjectMeta.DeletionTimestamp != nil {
+			continue
+		}
+		err := gp.kubernetesClient.CoreV1().Pods(gp.namespace).Delete(pod.ObjectMeta.Name, nil)
+		if err != nil {
+			return err
+		}
+	}
+	log.Printf("Drained %v idle pods of the pool for environment %v", len(pods), gp.env.Metadata.Name)
+	return nil
+}
+
+// recreate replaces the idle pods of the pool by changing the pod template
+// of its deployment, which rolls them like any update of the deployment.
+// Unlike drain, the pool keeps serving from its old pods until new ones
+// are ready.
+func (gp *GenericPool) recreate() error {
+	patch := fmt.Sprintf(`{"spec":{"template":{"metadata":{"annotations":{%q:%q}}}}}`,
+		recreatedAtAnnotation, time.Now().UTC().Format(time.RFC3339))
+	_, err := gp.kubernetesClient.ExtensionsV1beta1().Deployments(gp.namespace).Patch(
+		gp.deployment.ObjectMeta.Name, types.StrategicMergePatchType, []byte(patch))
+	if err != nil {
+		return err
+	}
+	log.Printf("Recreating the pool for environment %v", gp.env.Metadata.Name)
+	return nil
+}
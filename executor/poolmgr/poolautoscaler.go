@@ -0,0 +1,156 @@
+/*
+Copyright 2018 The Fission Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package poolmgr
+
+import (
+	"log"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/fission/fission/crd"
+)
+
+const (
+	// how often the pool autoscaler re-evaluates the pool size
+	poolAutoscaleInterval = 15 * time.Second
+
+	// specializations older than this don't count towards the pool size
+	poolAutoscaleWindow = time.Minute
+)
+
+// isAutoscaled returns true if the environment asks for pool autoscaling.
+func isAutoscaled(env *crd.Environment) bool {
+	return env.Spec.MaxPoolsize > 0
+}
+
+// desiredPoolsize computes the warm pool size for the number of
+// specializations seen during the last window. The pool grows to absorb a
+// burst of the same size (doubling when it ran dry), and shrinks one pod at
+// a time once demand drops, never leaving [min, max].
+func desiredPoolsize(current, available, recentSpecializations, min, max int32) int32 {
+	desired := recentSpecializations
+	if available == 0 && recentSpecializations > 0 && desired < 2*current {
+		desired = 2 * current
+	}
+
+	if desired < current {
+		// scale down gradually
+		desired = current - 1
+	}
+
+	if desired < min {
+		desired = min
+	}
+	if desired > max {
+		desired = max
+	}
+	return desired
+}
+
+// recordSpecialization notes that a warm pod was taken from the pool.
+func (gp *GenericPool) recordSpecialization() {
+	if !gp.autoscale {
+		return
+	}
+	gp.scaleLock.Lock()
+	defer gp.scaleLock.Unlock()
+	gp.specializations = append(gp.specializations, time.Now())
+}
+
+// recentSpecializations returns the number of specializations within the
+// autoscale window, forgetting older ones.
+func (gp *GenericPool) recentSpecializations() int32 {
+	gp.scaleLock.Lock()
+	defer gp.scaleLock.Unlock()
+
+	i := 0
+	for ; i < len(gp.specializations); i++ {
+		if time.Since(gp.specializations[i]) < poolAutoscaleWindow {
+			break
+		}
+	}
+	gp.specializations = gp.specializations[i:]
+	return int32(len(gp.specializations))
+}
+
+// scalePool sets the number of replicas of the pool deployment.
+func (gp *GenericPool) scalePool(deploymentName string, replicas int32) error {
+	gp.scaleLock.Lock()
+	defer gp.scaleLock.Unlock()
+
+	if replicas == gp.replicas {
+		return nil
+	}
+
+	depl, err := gp.kubernetesClient.ExtensionsV1beta1().Deployments(gp.namespace).Get(
+		deploymentName, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	depl.Spec.Replicas = &replicas
+	_, err = gp.kubernetesClient.ExtensionsV1beta1().Deployments(gp.namespace).Update(depl)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("[%v] Scaled pool from %v to %v pods", gp.env.Metadata.Name, gp.replicas, replicas)
+	gp.replicas = replicas
+	return nil
+}
+
+// poolAutoscaler periodically resizes the pool between the environment's
+// MinPoolsize and MaxPoolsize, until the pool is destroyed.  Only the
+// leader executor replica resizes pools, based on the specializations
+// it has seen itself.
+func (gp *GenericPool) poolAutoscaler(deploymentName string) {
+	ticker := time.NewTicker(poolAutoscaleInterval)
+	defer ticker.Stop()
+
+	minReplicas := int32(gp.env.Spec.MinPoolsize)
+	maxReplicas := int32(gp.env.Spec.MaxPoolsize)
+
+	for {
+		select {
+		case <-gp.stopCh:
+			return
+		case <-ticker.C:
+		}
+
+		if !gp.isLeader() {
+			continue
+		}
+
+		depl, err := gp.kubernetesClient.ExtensionsV1beta1().Deployments(gp.namespace).Get(
+			deploymentName, metav1.GetOptions{})
+		if err != nil {
+			log.Printf("[%v] Error getting pool deployment for autoscaling: %v", gp.env.Metadata.Name, err)
+			continue
+		}
+
+		gp.scaleLock.Lock()
+		current := gp.replicas
+		gp.scaleLock.Unlock()
+
+		desired := desiredPoolsize(current, depl.Status.AvailableReplicas,
+			gp.recentSpecializations(), minReplicas, maxReplicas)
+		err = gp.scalePool(deploymentName, desired)
+		if err != nil {
+			log.Printf("[%v] Error scaling pool to %v pods: %v", gp.env.Metadata.Name, desired, err)
+		}
+	}
+}
@@ -0,0 +1,32 @@
+package poolmgr
+
+import (
+	"testing"
+)
+
+func TestDesiredPoolsize(t *testing.T) {
+	tests := []struct {
+		current, available, recent, min, max int32
+		expected                             int32
+	}{
+		// idle pool stays at min
+		{current: 3, available: 3, recent: 0, min: 3, max: 10, expected: 3},
+		// shrink one pod at a time
+		{current: 6, available: 6, recent: 0, min: 1, max: 10, expected: 5},
+		// grow to absorb the recent burst
+		{current: 3, available: 1, recent: 7, min: 1, max: 10, expected: 7},
+		// pool ran dry, double it
+		{current: 3, available: 0, recent: 2, min: 1, max: 10, expected: 6},
+		// never exceed max
+		{current: 8, available: 0, recent: 20, min: 1, max: 10, expected: 10},
+		// scale down to zero
+		{current: 1, available: 1, recent: 0, min: 0, max: 10, expected: 0},
+	}
+
+	for i, test := range tests {
+		got := desiredPoolsize(test.current, test.available, test.recent, test.min, test.max)
+		if got != test.expected {
+			t.Errorf("case %v: expected pool size %v, got %v", i, test.expected, got)
+		}
+	}
+}
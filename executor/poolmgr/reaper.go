@@ -0,0 +1,206 @@
+/*
+Copyright 2018 The Fission Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package poolmgr
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	apiv1 "k8s.io/client-go/pkg/api/v1"
+
+	"github.com/fission/fission"
+	"github.com/fission/fission/executor/fscache"
+)
+
+// ReapIdle reaps specialized pods after certain idle time. It runs on the
+// leader executor replica only, so it works from the shared state kept on
+// the pods rather than from its own cache: a pod is idle once its last
+// access, as recorded by any replica, is older than its idle timeout, or
+// DefaultIdlePodReapTime if it has none.  Pods specialized from a previous
+// version of their environment are reaped once they're unused for
+// outdatedPodIdleTime.  Functions aren't taken below their MinScale.
+func (gpm *GenericPoolManager) ReapIdle() int {
+	if !gpm.funcController.HasSynced() {
+		// the MinScale of functions isn't known yet
+		return 0
+	}
+
+	// this replica's own accesses may not have been recorded on the pods yet
+	funcSvcByPod, err := gpm.funcSvcsByPod()
+	if err != nil {
+		log.Printf("Error reaping idle pods: %v", err)
+		return 0
+	}
+
+	// Shared pods are never relabeled; they're reaped by
+	// reapIdleSharedPods.
+	podList, err := gpm.kubernetesClient.CoreV1().Pods(gpm.namespace).List(metav1.ListOptions{
+		LabelSelector: labels.Set(map[string]string{"unmanaged": "true"}).AsSelector().String(),
+	})
+	if err != nil {
+		log.Printf("Error listing specialized pods: %v", err)
+		return 0
+	}
+
+	pods := make([]*apiv1.Pod, 0, len(podList.Items))
+	for i := range podList.Items {
+		pods = append(pods, &podList.Items[i])
+	}
+	guard := makeMinScaleGuard(gpm.listFunctions(), pods)
+	envVersions := gpm.environmentVersions()
+
+	reaped := 0
+	for _, pod := range pods {
+		if pod.ObjectMeta.DeletionTimestamp != nil {
+			continue
+		}
+
+		lastAccess := podLastAccessTime(pod)
+		idleTimeout := podIdleTimeout(pod, DefaultIdlePodReapTime)
+		fsvc, cached := funcSvcByPod[pod.ObjectMeta.UID]
+		if cached {
+			if fsvc.Atime.After(lastAccess) {
+				lastAccess = fsvc.Atime
+			}
+			if fsvc.IdleTimeout > 0 {
+				idleTimeout = fsvc.IdleTimeout
+			}
+		}
+		if isOutdatedPod(pod, envVersions[types.UID(pod.ObjectMeta.Labels["environmentUid"])]) &&
+			idleTimeout > outdatedPodIdleTime {
+			// pods of previous versions of their environment are
+			// reaped as soon as they're drained
+			idleTimeout = outdatedPodIdleTime
+		}
+		if time.Since(lastAccess) < idleTimeout || !guard.mayReap(pod) {
+			continue
+		}
+
+		log.Printf("Reaping pod %v idle since %v", pod.ObjectMeta.Name, lastAccess)
+		reaped++
+
+		if !cached {
+			gpm.deletePod(pod.ObjectMeta.Namespace, pod.ObjectMeta.Name)
+			continue
+		}
+
+		_, err := gpm.fsCache.DeleteOld(fsvc, idleTimeout)
+		if err != nil {
+			log.Printf("Error deleting fsvc '%v' from cache: %v", fsvc.Name, err)
+		}
+		for _, kubeobj := range fsvc.KubernetesObjects {
+			if strings.ToLower(kubeobj.Kind) == "pod" {
+				gpm.deletePod(kubeobj.Namespace, kubeobj.Name)
+			}
+		}
+	}
+	return reaped + gpm.reapIdleSharedPods()
+}
+
+// funcSvcsByPod returns the function services in this replica's cache by
+// the UID of their pod.
+func (gpm *GenericPoolManager) funcSvcsByPod() (map[types.UID]*fscache.FuncSvc, error) {
+	funcSvcs, err := gpm.fsCache.List()
+	if err != nil {
+		return nil, err
+	}
+	funcSvcByPod := make(map[types.UID]*fscache.FuncSvc)
+	for _, fsvc := range funcSvcs {
+		for _, kubeobj := range fsvc.KubernetesObjects {
+			if strings.ToLower(kubeobj.Kind) == "pod" {
+				funcSvcByPod[kubeobj.UID] = fsvc
+			}
+		}
+	}
+	return funcSvcByPod, nil
+}
+
+func (gpm *GenericPoolManager) deletePod(namespace, name string) {
+	err := gpm.kubernetesClient.CoreV1().Pods(namespace).Delete(name, nil)
+	if err != nil {
+		log.Printf("Error cleaning up pod %v: %v", name, err)
+	}
+}
+
+// podLastAccessTime returns when a specialized pod was last used, as
+// recorded on it by the executor replicas.
+func podLastAccessTime(pod *apiv1.Pod) time.Time {
+	t, err := time.Parse(time.RFC3339, pod.ObjectMeta.Annotations[fission.LAST_ACCESS_TIME_ANNOTATION])
+	if err != nil {
+		// specialized before access times were recorded
+		return pod.ObjectMeta.CreationTimestamp.Time
+	}
+	return t
+}
+
+// podIdleTimeout returns how long a specialized pod may stay idle.
+func podIdleTimeout(pod *apiv1.Pod, defaultTimeout time.Duration) time.Duration {
+	d, err := time.ParseDuration(pod.ObjectMeta.Annotations[fission.IDLE_TIMEOUT_ANNOTATION])
+	if err != nil || d <= 0 {
+		return defaultTimeout
+	}
+	return d
+}
+
+// accessTimeSyncer periodically records on their pods when the function
+// services in this replica's cache were last used, so that the reaper on
+// the leader replica sees accesses made through any replica.
+func (gpm *GenericPoolManager) accessTimeSyncer(ctx context.Context) {
+	ticker := time.NewTicker(15 * time.Second)
+	defer ticker.Stop()
+
+	lastSync := time.Now()
+	for {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+		syncTime := time.Now()
+
+		funcSvcs, err := gpm.fsCache.List()
+		if err != nil {
+			log.Printf("Error syncing access times: %v", err)
+			continue
+		}
+
+		for _, fsvc := range funcSvcs {
+			if fsvc.Executor != fscache.POOLMGR || !fsvc.Atime.After(lastSync) {
+				continue
+			}
+			patch := fmt.Sprintf(`{"metadata":{"annotations":{%q:%q}}}`,
+				fission.LAST_ACCESS_TIME_ANNOTATION, fsvc.Atime.UTC().Format(time.RFC3339))
+			for _, kubeobj := range fsvc.KubernetesObjects {
+				if strings.ToLower(kubeobj.Kind) != "pod" {
+					continue
+				}
+				_, err := gpm.kubernetesClient.CoreV1().Pods(kubeobj.Namespace).Patch(
+					kubeobj.Name, types.StrategicMergePatchType, []byte(patch))
+				if err != nil {
+					log.Printf("Error recording access time of pod %v: %v", kubeobj.Name, err)
+				}
+			}
+		}
+		lastSync = syncTime
+	}
+}
@@ -0,0 +1,216 @@
+/*
+Copyright 2018 The Fission Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package poolmgr
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	apiv1 "k8s.io/client-go/pkg/api/v1"
+
+	"github.com/fission/fission"
+	"github.com/fission/fission/crd"
+	"github.com/fission/fission/executor/fscache"
+)
+
+// Every version of an environment gets its own pool.  Versions are told
+// apart by a hash of the fields of the environment that its pods are made
+// from, so that updating other fields, such as the pool size or timeouts,
+// keeps the pool.  When the runtime of an environment is updated, the pool
+// of the new version is created alongside the old one, which keeps serving
+// specializations until the new pool has a ready pod, and is then
+// destroyed.  Pods specialized from a previous version keep serving their
+// function until it has an instance of the current version, or goes idle;
+// they're then dropped from the cache, and reaped once their last requests
+// are done.
+
+const (
+	// environmentVersionLabel is the label of pool deployments and pods,
+	// and of the pods specialized from them, with the version of the
+	// environment they run.
+	environmentVersionLabel = "environmentVersion"
+
+	// outdatedPodIdleTime is how long a pod specialized from a previous
+	// version of its environment may stay unused before it's reaped.
+	outdatedPodIdleTime = 30 * time.Second
+)
+
+// isOutdatedPod returns true if the pod runs another version of its
+// environment than the given one.  Pods created before pools were
+// versioned aren't labeled with a version, and aren't outdated.
+func isOutdatedPod(pod *apiv1.Pod, envVersion string) bool {
+	podVersion, ok := pod.ObjectMeta.Labels[environmentVersionLabel]
+	return ok && len(envVersion) > 0 && podVersion != envVersion
+}
+
+// environmentVersion returns the version of the environment's pods: a hash
+// of the fields of its spec that pool pods are made from.
+func environmentVersion(env *crd.Environment) string {
+	runtime := struct {
+		Version                      int                                  `json:"version"`
+		Runtime                      fission.Runtime                      `json:"runtime"`
+		Resources                    apiv1.ResourceRequirements           `json:"resources"`
+		PodSpec                      *apiv1.PodSpec                       `json:"podspec,omitempty"`
+		AllowedFunctionsPerContainer fission.AllowedFunctionsPerContainer `json:"allowedFunctionsPerContainer,omitempty"`
+		AllowAccessToExternalNetwork bool                                 `json:"allowAccessToExternalNetwork,omitempty"`
+		TerminationGracePeriod       int64                                `json:"terminationGracePeriod,omitempty"`
+	}{
+		Version:                      env.Spec.Version,
+		Runtime:                      env.Spec.Runtime,
+		Resources:                    env.Spec.Resources,
+		PodSpec:                      env.Spec.PodSpec,
+		AllowedFunctionsPerContainer: env.Spec.AllowedFunctionsPerContainer,
+		AllowAccessToExternalNetwork: env.Spec.AllowAccessToExternalNetwork,
+		TerminationGracePeriod:       env.Spec.TerminationGracePeriod,
+	}
+	data, err := json.Marshal(&runtime)
+	if err != nil {
+		// can't happen; fall back to a version per update
+		return env.Metadata.ResourceVersion
+	}
+	sum := sha256.Sum256(data)
+	// short enough for a label value
+	return hex.EncodeToString(sum[:])[:16]
+}
+
+// poolKey returns the key of the pool of the environment's version.
+func poolKey(env *crd.Environment) string {
+	return fmt.Sprintf("%v_%v", env.Metadata.UID, environmentVersion(env))
+}
+
+// refreshPool replaces the pool of an environment that was updated without
+// changing its pods by a pool of the same deployment, with the new
+// settings of the environment, such as its pool size and timeouts.
+func (gpm *GenericPoolManager) refreshPool(pool *GenericPool, env *crd.Environment) *GenericPool {
+	pool.stop()
+	pool.scaleLock.Lock()
+	replicas := pool.replicas
+	pool.scaleLock.Unlock()
+
+	refreshed := gpm.adoptGenericPool(env, pool.deployment)
+	refreshed.scaleLock.Lock()
+	refreshed.replicas = replicas
+	refreshed.scaleLock.Unlock()
+
+	if gpm.IsLeader() && !refreshed.autoscale {
+		go func() {
+			err := refreshed.scalePool(refreshed.deployment.ObjectMeta.Name, gpm.getEnvPoolsize(env))
+			if err != nil {
+				log.Printf("[%v] Error resizing pool: %v", env.Metadata.Name, err)
+			}
+		}()
+	}
+	log.Printf("Updated the settings of the pool of environment [%v]", env.Metadata.Name)
+	return refreshed
+}
+
+// hasReadyPod returns true if one of the pool's pods is ready to be
+// specialized.
+func (gp *GenericPool) hasReadyPod() bool {
+	if !gp.pods.hasSynced() {
+		return false
+	}
+	for _, pod := range gp.pods.listPods(labels.Set(gp.deployment.Spec.Selector.MatchLabels).AsSelector()) {
+		if isReadyPoolPod(pod) {
+			return true
+		}
+	}
+	return false
+}
+
+// environmentVersions returns the version of every environment, by UID,
+// or nil if the environments can't be listed.
+func (gpm *GenericPoolManager) environmentVersions() map[types.UID]string {
+	envs, err := gpm.fissionClient.Environments(metav1.NamespaceAll).List(metav1.ListOptions{})
+	if err != nil {
+		log.Printf("Error listing environments: %v", err)
+		return nil
+	}
+	versions := make(map[types.UID]string)
+	for i := range envs.Items {
+		versions[envs.Items[i].Metadata.UID] = environmentVersion(&envs.Items[i])
+	}
+	return versions
+}
+
+// rollFunctions moves the function services in this replica's cache that
+// were specialized from a previous version of their environment onto its
+// current version.
+func (gpm *GenericPoolManager) rollFunctions(envs []crd.Environment) {
+	latestEnvs := make(map[types.UID]*crd.Environment)
+	for i := range envs {
+		latestEnvs[envs[i].Metadata.UID] = &envs[i]
+	}
+
+	fsvcs, err := gpm.fsCache.List()
+	if err != nil {
+		return
+	}
+	for _, fsvc := range fsvcs {
+		if fsvc.Executor != fscache.POOLMGR || fsvc.Environment == nil {
+			continue
+		}
+		env, ok := latestEnvs[fsvc.Environment.Metadata.UID]
+		if !ok || environmentVersion(env) == environmentVersion(fsvc.Environment) {
+			continue
+		}
+		gpm.rollFunction(fsvc, env)
+	}
+}
+
+// rollFunction drops a function service of a previous version of the
+// environment from the cache once its function has an instance of the
+// current version, or once it's idle.  A busy function first gets an
+// instance specialized from the pool of the current version, in the
+// background.  The dropped pod finishes the requests it has, and is then
+// reaped.
+func (gpm *GenericPoolManager) rollFunction(fsvc *fscache.FuncSvc, env *crd.Environment) {
+	current := false
+	if instances, err := gpm.fsCache.ListByFunction(fsvc.Function); err == nil {
+		for _, instance := range instances {
+			if instance.Environment != nil &&
+				instance.Environment.Metadata.ResourceVersion == env.Metadata.ResourceVersion {
+				current = true
+			}
+		}
+	}
+	if current || time.Since(fsvc.Atime) > outdatedPodIdleTime {
+		log.Printf("[%v] Draining pod %v of a previous version of environment %v",
+			fsvc.Function.Name, fsvc.Name, env.Metadata.Name)
+		gpm.fsCache.DeleteEntry(fsvc)
+		return
+	}
+
+	pool, err := gpm.GetPool(env)
+	if err != nil || environmentVersion(pool.env) != environmentVersion(env) ||
+		!pool.canScaleFunctions() {
+		// the pool of the current version isn't ready yet, or the
+		// function can't have another instance while it's busy
+		return
+	}
+	if pool.specializeInBackground(fsvc.Function, fsvc.ExecutionStrategy) {
+		log.Printf("[%v] Specializing a pod of the current version of environment %v",
+			fsvc.Function.Name, env.Metadata.Name)
+	}
+}
@@ -0,0 +1,58 @@
+package poolmgr
+
+import (
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	apiv1 "k8s.io/client-go/pkg/api/v1"
+
+	"github.com/fission/fission/crd"
+)
+
+func TestIsOutdatedPod(t *testing.T) {
+	pod := func(podLabels map[string]string) *apiv1.Pod {
+		return &apiv1.Pod{ObjectMeta: metav1.ObjectMeta{Labels: podLabels}}
+	}
+
+	if !isOutdatedPod(pod(map[string]string{environmentVersionLabel: "1"}), "2") {
+		t.Errorf("expected a pod of a previous version to be outdated")
+	}
+	if isOutdatedPod(pod(map[string]string{environmentVersionLabel: "2"}), "2") {
+		t.Errorf("expected a pod of the current version not to be outdated")
+	}
+	if isOutdatedPod(pod(map[string]string{}), "2") {
+		t.Errorf("expected a pod without a version not to be outdated")
+	}
+	if isOutdatedPod(pod(map[string]string{environmentVersionLabel: "1"}), "") {
+		t.Errorf("expected a pod of an unknown environment not to be outdated")
+	}
+}
+
+func TestEnvironmentVersion(t *testing.T) {
+	env := &crd.Environment{
+		Metadata: metav1.ObjectMeta{Name: "nodejs", UID: types.UID("1"), ResourceVersion: "1"},
+	}
+	env.Spec.Version = 2
+	env.Spec.Runtime.Image = "fission/node-env"
+	version := environmentVersion(env)
+
+	updated := *env
+	updated.Metadata.ResourceVersion = "2"
+	updated.Spec.Poolsize = 5
+	updated.Spec.IdleTimeout = 60
+	if v := environmentVersion(&updated); v != version {
+		t.Errorf("expected settings that don't change the pods to keep the version, got %v and %v", version, v)
+	}
+	if poolKey(&updated) != poolKey(env) {
+		t.Errorf("expected settings that don't change the pods to keep the pool")
+	}
+
+	updated.Spec.Runtime.Image = "fission/node-env:0.5.0"
+	if v := environmentVersion(&updated); v == version {
+		t.Errorf("expected a new image to change the version")
+	}
+	if len(version) > 63 {
+		t.Errorf("expected the version to fit in a label value, got %v", version)
+	}
+}
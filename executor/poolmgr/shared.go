@@ -0,0 +1,183 @@
+/*
+Copyright 2018 The Fission Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package poolmgr
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	apiv1 "k8s.io/client-go/pkg/api/v1"
+
+	"github.com/fission/fission"
+	"github.com/fission/fission/crd"
+	"github.com/fission/fission/executor/fscache"
+)
+
+// Pods of environments allowing infinite functions per container are
+// shared: functions are loaded into them, each at its own path, without
+// taking them out of the pool.  Functions are packed into the oldest ready
+// pod of the pool, so that every executor replica loads them into the same
+// pod, and the router addresses them by path.  A shared pod is reaped once
+// all of its functions are idle; the pool's deployment replaces it with a
+// fresh one.  Only environments of version 2 or later can load functions
+// at their own path; older ones get a pod per function.
+//
+// Environment variables are process-wide, so functions with an Env can't
+// be loaded into shared pods.
+//
+// Every function loaded into a shared pod is recorded on it in an
+// annotation, so that a restarted executor can serve the function from
+// the pod again.
+
+// loadedFunctionAnnotationPrefix, followed by the uid of a function, is
+// the annotation of a shared pod recording the version of the function
+// loaded into it.
+const loadedFunctionAnnotationPrefix = "loadedFunction."
+
+// sharesPods returns true if functions of the environment are loaded into
+// shared pods.
+func sharesPods(env *crd.Environment) bool {
+	return env.Spec.AllowedFunctionsPerContainer == fission.AllowedFunctionsPerContainerInfinite &&
+		env.Spec.Version >= 2
+}
+
+// functionPath returns the path a version of a function is served at in a
+// shared pod.
+func functionPath(m *metav1.ObjectMeta) string {
+	return fmt.Sprintf("/fission-function/%v/%v", m.UID, m.ResourceVersion)
+}
+
+// functionFilename returns the name the package of a version of a function
+// is fetched as into a shared pod.  Functions can't be unloaded, so every
+// version gets its own file.
+func functionFilename(m *metav1.ObjectMeta) string {
+	return fmt.Sprintf("%v-%v", m.UID, m.ResourceVersion)
+}
+
+// isSharedPod returns true if functions were loaded into the pool pod.
+func isSharedPod(pod *apiv1.Pod) bool {
+	_, loaded := pod.ObjectMeta.Annotations[fission.LAST_ACCESS_TIME_ANNOTATION]
+	return loaded && pod.ObjectMeta.Labels["executorType"] == fission.ExecutorTypePoolmgr
+}
+
+// oldestPod returns the pod created first, by name if they were created
+// at the same time.
+func oldestPod(pods []*apiv1.Pod) *apiv1.Pod {
+	sort.Slice(pods, func(i, j int) bool {
+		ti, tj := pods[i].ObjectMeta.CreationTimestamp.Time, pods[j].ObjectMeta.CreationTimestamp.Time
+		if !ti.Equal(tj) {
+			return ti.Before(tj)
+		}
+		return pods[i].ObjectMeta.Name < pods[j].ObjectMeta.Name
+	})
+	return pods[0]
+}
+
+// loadedFunctions returns the resource versions of the functions loaded
+// into a shared pod, by function uid.
+func loadedFunctions(pod *apiv1.Pod) map[types.UID]string {
+	loaded := make(map[types.UID]string)
+	for key, rv := range pod.ObjectMeta.Annotations {
+		if strings.HasPrefix(key, loadedFunctionAnnotationPrefix) {
+			loaded[types.UID(strings.TrimPrefix(key, loadedFunctionAnnotationPrefix))] = rv
+		}
+	}
+	return loaded
+}
+
+// annotateSharedPod records on a shared pod that the function was just
+// loaded into it, and how long the pod may stay idle.
+func (gp *GenericPool) annotateSharedPod(pod *apiv1.Pod, m *metav1.ObjectMeta) {
+	patch := fmt.Sprintf(`{"metadata":{"annotations":{%q:%q,%q:%q,%q:%q}}}`,
+		fission.LAST_ACCESS_TIME_ANNOTATION, time.Now().UTC().Format(time.RFC3339),
+		fission.IDLE_TIMEOUT_ANNOTATION, gp.idlePodReapTime.String(),
+		loadedFunctionAnnotationPrefix+string(m.UID), m.ResourceVersion)
+	_, err := gp.kubernetesClient.CoreV1().Pods(gp.namespace).Patch(
+		pod.ObjectMeta.Name, types.StrategicMergePatchType, []byte(patch))
+	if err != nil {
+		log.Printf("Error annotating shared pod %v: %v", pod.ObjectMeta.Name, err)
+	}
+}
+
+// sharedPodFuncSvcs returns the function services in this replica's cache
+// that are served by the pod.
+func (gpm *GenericPoolManager) sharedPodFuncSvcs(pod *apiv1.Pod) []*fscache.FuncSvc {
+	fsvcs, err := gpm.fsCache.List()
+	if err != nil {
+		return nil
+	}
+	served := make([]*fscache.FuncSvc, 0)
+	for _, fsvc := range fsvcs {
+		for _, kubeobj := range fsvc.KubernetesObjects {
+			if strings.ToLower(kubeobj.Kind) == "pod" && kubeobj.UID == pod.ObjectMeta.UID {
+				served = append(served, fsvc)
+			}
+		}
+	}
+	return served
+}
+
+// reapIdleSharedPods deletes the shared pods whose functions are all idle.
+// Like ReapIdle, it goes by the access times recorded on the pods by every
+// replica.
+func (gpm *GenericPoolManager) reapIdleSharedPods() int {
+	podList, err := gpm.kubernetesClient.CoreV1().Pods(gpm.namespace).List(metav1.ListOptions{
+		LabelSelector: labels.Set(map[string]string{
+			"executorType": fission.ExecutorTypePoolmgr,
+		}).AsSelector().String(),
+	})
+	if err != nil {
+		log.Printf("Error listing shared pods: %v", err)
+		return 0
+	}
+
+	reaped := 0
+	for i := range podList.Items {
+		pod := &podList.Items[i]
+		if !isSharedPod(pod) || pod.ObjectMeta.DeletionTimestamp != nil {
+			continue
+		}
+
+		// this replica's own accesses may not have been recorded on the
+		// pod yet
+		lastAccess := podLastAccessTime(pod)
+		fsvcs := gpm.sharedPodFuncSvcs(pod)
+		for _, fsvc := range fsvcs {
+			if fsvc.Atime.After(lastAccess) {
+				lastAccess = fsvc.Atime
+			}
+		}
+		if time.Since(lastAccess) < podIdleTimeout(pod, DefaultIdlePodReapTime) {
+			continue
+		}
+
+		log.Printf("Reaping shared pod %v with %v functions, idle since %v",
+			pod.ObjectMeta.Name, len(fsvcs), lastAccess)
+		for _, fsvc := range fsvcs {
+			gpm.fsCache.DeleteEntry(fsvc)
+		}
+		gpm.deletePod(pod.ObjectMeta.Namespace, pod.ObjectMeta.Name)
+		reaped++
+	}
+	return reaped
+}
@@ -0,0 +1,138 @@
+package poolmgr
+
+import (
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+	apiv1 "k8s.io/client-go/pkg/api/v1"
+
+	"github.com/fission/fission"
+	"github.com/fission/fission/crd"
+	"github.com/fission/fission/executor/fscache"
+)
+
+func TestOldestPod(t *testing.T) {
+	now := time.Now()
+	pod := func(name string, created time.Time) *apiv1.Pod {
+		return &apiv1.Pod{ObjectMeta: metav1.ObjectMeta{
+			Name:              name,
+			CreationTimestamp: metav1.NewTime(created),
+		}}
+	}
+
+	oldest := oldestPod([]*apiv1.Pod{
+		pod("c", now),
+		pod("b", now.Add(-time.Minute)),
+		pod("a", now),
+	})
+	if oldest.ObjectMeta.Name != "b" {
+		t.Errorf("expected the pod created first, got %v", oldest.ObjectMeta.Name)
+	}
+
+	oldest = oldestPod([]*apiv1.Pod{pod("b", now), pod("a", now)})
+	if oldest.ObjectMeta.Name != "a" {
+		t.Errorf("expected pods created at the same time to be ordered by name, got %v", oldest.ObjectMeta.Name)
+	}
+}
+
+func TestIsSharedPod(t *testing.T) {
+	pod := func(podLabels, annotations map[string]string) *apiv1.Pod {
+		return &apiv1.Pod{ObjectMeta: metav1.ObjectMeta{Labels: podLabels, Annotations: annotations}}
+	}
+	poolLabels := map[string]string{"executorType": fission.ExecutorTypePoolmgr}
+	accessed := map[string]string{fission.LAST_ACCESS_TIME_ANNOTATION: "2018-01-01T00:00:00Z"}
+
+	if !isSharedPod(pod(poolLabels, accessed)) {
+		t.Errorf("expected a pool pod functions were loaded into to be shared")
+	}
+	if isSharedPod(pod(poolLabels, nil)) {
+		t.Errorf("expected a pool pod no function was loaded into not to be shared")
+	}
+	if isSharedPod(pod(map[string]string{"unmanaged": "true"}, accessed)) {
+		t.Errorf("expected a specialized pod not to be shared")
+	}
+}
+
+func TestSharesPods(t *testing.T) {
+	env := &crd.Environment{}
+	env.Spec.Version = 2
+	env.Spec.AllowedFunctionsPerContainer = fission.AllowedFunctionsPerContainerInfinite
+	if !sharesPods(env) {
+		t.Errorf("expected a v2 environment allowing infinite functions per container to share pods")
+	}
+
+	env.Spec.Version = 1
+	if sharesPods(env) {
+		t.Errorf("expected a v1 environment to get a pod per function")
+	}
+
+	env.Spec.Version = 2
+	env.Spec.AllowedFunctionsPerContainer = fission.AllowedFunctionsPerContainerSingle
+	if sharesPods(env) {
+		t.Errorf("expected an environment allowing a single function per container not to share pods")
+	}
+}
+
+func TestAdoptSharedPods(t *testing.T) {
+	env := crd.Environment{
+		Metadata: metav1.ObjectMeta{Name: "go", Namespace: "default", UID: types.UID("env")},
+	}
+	env.Spec.Version = 2
+	env.Spec.AllowedFunctionsPerContainer = fission.AllowedFunctionsPerContainerInfinite
+
+	fn := func(uid, rv string) *crd.Function {
+		f := &crd.Function{
+			Metadata: metav1.ObjectMeta{Name: uid, Namespace: "default", UID: types.UID(uid), ResourceVersion: rv},
+		}
+		f.Spec.Environment = fission.EnvironmentReference{Name: "go", Namespace: "default"}
+		return f
+	}
+	current, updated := fn("current", "1"), fn("updated", "3")
+	fnByUID := map[types.UID]*crd.Function{
+		current.Metadata.UID: current,
+		updated.Metadata.UID: updated,
+	}
+
+	pod := &apiv1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "go-pool-abc",
+			Namespace: evictionTestNamespace,
+			UID:       types.UID("pod"),
+			Labels: map[string]string{
+				"executorType":   fission.ExecutorTypePoolmgr,
+				"environmentUid": "env",
+			},
+			Annotations: map[string]string{
+				fission.LAST_ACCESS_TIME_ANNOTATION:        time.Now().UTC().Format(time.RFC3339),
+				loadedFunctionAnnotationPrefix + "current": "1",
+				loadedFunctionAnnotationPrefix + "updated": "2",
+			},
+		},
+		Status: apiv1.PodStatus{PodIP: "10.0.0.1"},
+	}
+	gpm := &GenericPoolManager{
+		pools:            make(map[string]*GenericPool),
+		kubernetesClient: fake.NewSimpleClientset(pod),
+		namespace:        evictionTestNamespace,
+		fsCache:          fscache.MakeFunctionServiceCache(),
+	}
+	gpm.pools[poolKey(&env)] = &GenericPool{env: &env, namespace: evictionTestNamespace}
+
+	adopted := gpm.adoptSharedPods([]crd.Environment{env}, fnByUID)
+	if len(adopted) != 1 || adopted[0].UID != pod.ObjectMeta.UID {
+		t.Errorf("expected the shared pod to be adopted once, got %v", adopted)
+	}
+	fsvc, err := gpm.fsCache.GetByFunctionUID(current.Metadata.UID)
+	if err != nil {
+		t.Fatalf("expected the function loaded into the pod to be cached: %v", err)
+	}
+	if fsvc.Address != "10.0.0.1:8888"+functionPath(&current.Metadata) {
+		t.Errorf("expected the function to be addressed by path in the pod, got %v", fsvc.Address)
+	}
+	if _, err := gpm.fsCache.GetByFunctionUID(updated.Metadata.UID); err == nil {
+		t.Errorf("expected a function updated since it was loaded not to be cached")
+	}
+}
@@ -0,0 +1,154 @@
+/*
+Copyright 2018 The Fission Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package poolmgr
+
+import (
+	"fmt"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	apiv1 "k8s.io/client-go/pkg/api/v1"
+	"k8s.io/client-go/pkg/apis/extensions/v1beta1"
+
+	"github.com/fission/fission"
+	"github.com/fission/fission/crd"
+	"github.com/fission/fission/executor/util"
+)
+
+// FunctionStatus reports the ready pods specialized for the current version
+// of the function.  Shared pods aren't labeled for a function, so only
+// this replica's cache tells about them.
+func (gpm *GenericPoolManager) FunctionStatus(fn *crd.Function, env *crd.Environment, status *fission.FunctionStatus) {
+	if !gpm.pods.hasSynced() {
+		return
+	}
+
+	ready := 0
+	if sharesPods(env) {
+		fsvcs, err := gpm.fsCache.ListByFunction(&fn.Metadata)
+		if err == nil {
+			ready = len(fsvcs)
+		}
+	} else {
+		pods := gpm.pods.listPods(labels.Set(map[string]string{
+			"functionUid": string(fn.Metadata.UID),
+			"unmanaged":   "true",
+		}).AsSelector())
+		for _, pod := range pods {
+			if isSpecializedFor(pod, fn.Metadata.UID, fn.Metadata.ResourceVersion) && isReadyPoolPod(pod) {
+				ready++
+			}
+		}
+	}
+
+	status.ReadyReplicas = ready
+	if ready > 0 {
+		status.SetCondition(fission.FunctionSpecialized, apiv1.ConditionTrue, "",
+			fmt.Sprintf("%v pods specialized", ready))
+		return
+	}
+
+	// pods can't be specialized for the function while its environment's
+	// pool is broken
+	pods := gpm.pods.listPods(labels.Set(map[string]string{
+		"environmentUid": string(env.Metadata.UID),
+		"executorType":   fission.ExecutorTypePoolmgr,
+	}).AsSelector())
+	for _, pod := range pods {
+		reason, message := util.GetPodFailure(pod)
+		if len(reason) > 0 {
+			status.SetCondition(fission.FunctionSpecialized, apiv1.ConditionFalse, reason,
+				fmt.Sprintf("pod %v of environment %v: %v", pod.ObjectMeta.Name, env.Metadata.Name, message))
+			return
+		}
+	}
+	status.SetCondition(fission.FunctionSpecialized, apiv1.ConditionFalse, fission.FunctionReasonIdle,
+		"no pods are specialized for the function")
+}
+
+// EnvironmentStatus reports the rollout of the current version of the
+// environment: the ready pods of its pool, and what's left of previous
+// versions.  Pools are told apart by the version recorded on their
+// deployments, so that any replica can report on the leader's pools.
+func (gpm *GenericPoolManager) EnvironmentStatus(env *crd.Environment, status *fission.EnvironmentStatus) {
+	if !gpm.pods.hasSynced() {
+		return
+	}
+
+	pods := gpm.pods.listPods(labels.Set(map[string]string{
+		"environmentUid": string(env.Metadata.UID),
+		"unmanaged":      "true",
+	}).AsSelector())
+	for _, pod := range pods {
+		if pod.ObjectMeta.DeletionTimestamp == nil && isOutdatedPod(pod, environmentVersion(env)) {
+			status.OutdatedPods++
+		}
+	}
+
+	deplList, err := gpm.kubernetesClient.ExtensionsV1beta1().Deployments(gpm.namespace).List(
+		metav1.ListOptions{
+			LabelSelector: labels.Set(map[string]string{
+				"environmentUid": string(env.Metadata.UID),
+				"executorType":   fission.ExecutorTypePoolmgr,
+			}).AsSelector().String(),
+		})
+	if err != nil {
+		return
+	}
+	var current *v1beta1.Deployment
+	outdatedPools := 0
+	for i := range deplList.Items {
+		depl := &deplList.Items[i]
+		if depl.ObjectMeta.DeletionTimestamp != nil {
+			continue
+		}
+		if depl.ObjectMeta.Labels[environmentVersionLabel] == environmentVersion(env) {
+			current = depl
+		} else {
+			outdatedPools++
+		}
+	}
+
+	failure := ""
+	if current != nil {
+		for _, pod := range gpm.pods.listPods(labels.Set(current.Spec.Selector.MatchLabels).AsSelector()) {
+			if isReadyPoolPod(pod) {
+				status.ReadyPods++
+			} else if reason, message := util.GetPodFailure(pod); len(reason) > 0 && len(failure) == 0 {
+				failure = fmt.Sprintf("pod %v: %v: %v", pod.ObjectMeta.Name, reason, message)
+			}
+		}
+	}
+
+	switch {
+	case gpm.getEnvPoolsize(env) > 0 && current == nil:
+		status.Phase = fission.EnvironmentPending
+		status.Message = "the pool of the current version isn't created yet"
+	case current != nil && status.ReadyPods == 0:
+		status.Phase = fission.EnvironmentPending
+		status.Message = "waiting for the pods of the pool of the current version to be ready"
+		if len(failure) > 0 {
+			status.Message = failure
+		}
+	case outdatedPools > 0 || status.OutdatedPods > 0:
+		status.Phase = fission.EnvironmentRollingOut
+		status.Message = fmt.Sprintf("%v pools and %v specialized pods of previous versions are left",
+			outdatedPools, status.OutdatedPods)
+	default:
+		status.Phase = fission.EnvironmentReady
+	}
+}
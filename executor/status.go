@@ -0,0 +1,326 @@
+/*
+Copyright 2018 The Fission Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package executor
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	apiv1 "k8s.io/client-go/pkg/api/v1"
+
+	"github.com/fission/fission"
+	"github.com/fission/fission/crd"
+	executorClient "github.com/fission/fission/executor/client"
+	"github.com/fission/fission/executor/util"
+)
+
+const (
+	// After a failure to specialize a version of a function, requests
+	// for it fail with the same error until the backoff is over, so
+	// that a broken function doesn't drain pools and load the fetcher.
+	// The backoff doubles with each consecutive failure.
+	minFailureBackoff = 2 * time.Second
+	maxFailureBackoff = 5 * time.Minute
+)
+
+type (
+	// functionFailure is the last failure of this replica to specialize
+	// a version of a function.
+	functionFailure struct {
+		resourceVersion string
+		err             error
+		time            time.Time
+		count           int       // consecutive failures of the version
+		retryTime       time.Time // requests fail fast until then
+	}
+
+	// statusLookup memoizes the packages and environments of functions
+	// while their statuses are computed.
+	statusLookup struct {
+		packages     map[string]*crd.Package
+		environments map[string]*crd.Environment
+	}
+)
+
+// failureBackoff returns how long requests for a function fail fast after
+// its count-th consecutive failure.
+func failureBackoff(count int) time.Duration {
+	backoff := minFailureBackoff
+	for i := 1; i < count && backoff < maxFailureBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > maxFailureBackoff {
+		backoff = maxFailureBackoff
+	}
+	return backoff
+}
+
+// recordFailure records the outcome of creating a service for the function,
+// and returns the error to fail the request with.  Specialization failures
+// back off requests for the version of the function; a success clears the
+// failure recorded for it.  Other failures, such as API errors, timeouts
+// or a lack of room, are left to the next request to retry.  Failures are
+// also added to the function's status record, and reported in an event.
+func (executor *Executor) recordFailure(m *metav1.ObjectMeta, err error) error {
+	err, specialization := util.UnwrapSpecializationError(err)
+	if err != nil && !specialization {
+		return err
+	}
+
+	executor.failureLock.Lock()
+	f, failed := executor.failures[m.UID]
+	if err == nil {
+		if failed {
+			delete(executor.failures, m.UID)
+		}
+		executor.failureLock.Unlock()
+		if failed && executor.statusRecords != nil {
+			executor.statusRecords.clearFailure(m)
+		}
+		return nil
+	}
+	count := 1
+	if failed && f.resourceVersion == m.ResourceVersion {
+		count = f.count + 1
+	}
+	now := time.Now()
+	backoff := failureBackoff(count)
+	log.Printf("[%v] Failed to specialize %v times, failing requests for %v: %v",
+		m.Name, count, backoff, err)
+	f = &functionFailure{
+		resourceVersion: m.ResourceVersion,
+		err:             err,
+		time:            now,
+		count:           count,
+		retryTime:       now.Add(backoff),
+	}
+	executor.failures[m.UID] = f
+	executor.failureLock.Unlock()
+
+	if executor.statusRecords != nil {
+		executor.statusRecords.recordFailure(m, f)
+		executor.statusRecords.failureEvent(m, f, backoff)
+	}
+	return err
+}
+
+// backoffError returns the error to fail requests for the function with,
+// or nil if its current version isn't backing off.
+func (executor *Executor) backoffError(m *metav1.ObjectMeta) error {
+	f := executor.getFailure(m)
+	if f == nil || !time.Now().Before(f.retryTime) {
+		return nil
+	}
+	code := fission.ErrorInternal
+	msg := f.err.Error()
+	if fe, ok := f.err.(fission.Error); ok {
+		code = int(fe.Code)
+		msg = fe.Message
+	}
+	return fission.MakeError(code, fmt.Sprintf("%v (failed %v times, retrying after %v)",
+		msg, f.count, f.retryTime.UTC().Format(time.RFC3339)))
+}
+
+// getFailure returns the last failure of the current version of the
+// function, or nil if there's none.
+func (executor *Executor) getFailure(m *metav1.ObjectMeta) *functionFailure {
+	executor.failureLock.Lock()
+	defer executor.failureLock.Unlock()
+
+	f, ok := executor.failures[m.UID]
+	if !ok || f.resourceVersion != m.ResourceVersion {
+		return nil
+	}
+	return f
+}
+
+// functionStatuses returns the status of the named function, or of all
+// functions in the namespace if name is empty, from their status records.
+// Functions the leader hasn't recorded the status of yet get it computed.
+func (executor *Executor) functionStatuses(namespace string, name string) ([]executorClient.FunctionStatusInfo, error) {
+	var fns []crd.Function
+	if len(name) > 0 {
+		if len(namespace) == 0 {
+			namespace = metav1.NamespaceDefault
+		}
+		fn, err := executor.fissionClient.Functions(namespace).Get(name)
+		if err != nil {
+			return nil, err
+		}
+		fns = []crd.Function{*fn}
+	} else {
+		fnList, err := executor.fissionClient.Functions(namespace).List(metav1.ListOptions{})
+		if err != nil {
+			return nil, err
+		}
+		fns = fnList.Items
+	}
+
+	records := make(map[types.UID]*apiv1.ConfigMap)
+	if executor.statusRecords != nil {
+		var err error
+		records, err = executor.statusRecords.list(namespace)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	lookup := &statusLookup{
+		packages:     make(map[string]*crd.Package),
+		environments: make(map[string]*crd.Environment),
+	}
+	statuses := make([]executorClient.FunctionStatusInfo, 0, len(fns))
+	for i := range fns {
+		var status fission.FunctionStatus
+		if record, ok := records[fns[i].Metadata.UID]; ok && len(record.Data[statusDataKey]) > 0 {
+			status = recordedStatus(record)
+		} else {
+			status = executor.functionStatus(&fns[i], lookup, recordedFailures(record))
+		}
+		statuses = append(statuses, executorClient.FunctionStatusInfo{
+			Function: fns[i].Metadata,
+			Status:   status,
+		})
+	}
+	return statuses, nil
+}
+
+// functionStatus computes the status of the function from its package and
+// environment, its instances as seen by its backend, and the latest of the
+// failures of the replicas to specialize its current version.
+func (executor *Executor) functionStatus(fn *crd.Function, lookup *statusLookup, failures map[string]*failureRecord) fission.FunctionStatus {
+	status := fission.FunctionStatus{}
+
+	// functions with an image have no package
+	if len(fn.Spec.Image) == 0 {
+		pkg, err := lookup.getPackage(executor.fissionClient, fn.Spec.Package.PackageRef.Namespace, fn.Spec.Package.PackageRef.Name)
+		if err != nil {
+			status.SetCondition(fission.FunctionPackageReady, apiv1.ConditionFalse, "PackageNotFound", err.Error())
+		} else {
+			switch pkg.Status.BuildStatus {
+			case fission.BuildStatusFailed:
+				status.SetCondition(fission.FunctionPackageReady, apiv1.ConditionFalse, "BuildFailed",
+					fmt.Sprintf("package %v failed to build, see its build log", pkg.Metadata.Name))
+			case fission.BuildStatusPending, fission.BuildStatusRunning:
+				status.SetCondition(fission.FunctionPackageReady, apiv1.ConditionFalse, "Building",
+					fmt.Sprintf("package %v is being built", pkg.Metadata.Name))
+			default:
+				status.SetCondition(fission.FunctionPackageReady, apiv1.ConditionTrue, "", "")
+			}
+		}
+	}
+
+	var failure *failureRecord
+	for _, f := range failures {
+		if f.ResourceVersion == fn.Metadata.ResourceVersion && (failure == nil || failure.Time.Before(f.Time)) {
+			failure = f
+		}
+	}
+	if failure != nil {
+		t := metav1.NewTime(failure.Time)
+		status.LastError = failure.Error
+		status.LastErrorTime = &t
+		status.Failures = failure.Count
+		if time.Now().Before(failure.RetryTime) {
+			retryTime := metav1.NewTime(failure.RetryTime)
+			status.RetryTime = &retryTime
+		}
+	}
+
+	env, err := lookup.getEnvironment(executor.fissionClient, fn.Spec.Environment.Namespace, fn.Spec.Environment.Name)
+	if err != nil {
+		if len(status.LastError) == 0 {
+			status.LastError = fmt.Sprintf("error getting environment %v: %v", fn.Spec.Environment.Name, err)
+		}
+		return status
+	}
+	backend, err := executor.getBackend(fn.Spec.InvokeStrategy.ExecutionStrategy.ExecutorType)
+	if err != nil {
+		return status
+	}
+	if reporter, ok := backend.(FunctionStatusReporter); ok {
+		reporter.FunctionStatus(fn, env, &status)
+	}
+	return status
+}
+
+// environmentStatuses returns the status of the named environment, or of
+// all environments in the namespace if name is empty, as reported by the
+// backends running their pods.
+func (executor *Executor) environmentStatuses(namespace string, name string) ([]executorClient.EnvironmentStatusInfo, error) {
+	var envs []crd.Environment
+	if len(name) > 0 {
+		if len(namespace) == 0 {
+			namespace = metav1.NamespaceDefault
+		}
+		env, err := executor.fissionClient.Environments(namespace).Get(name)
+		if err != nil {
+			return nil, err
+		}
+		envs = []crd.Environment{*env}
+	} else {
+		envList, err := executor.fissionClient.Environments(namespace).List(metav1.ListOptions{})
+		if err != nil {
+			return nil, err
+		}
+		envs = envList.Items
+	}
+
+	statuses := make([]executorClient.EnvironmentStatusInfo, 0, len(envs))
+	for i := range envs {
+		status := fission.EnvironmentStatus{}
+		for _, backend := range executor.backends {
+			if reporter, ok := backend.(EnvironmentStatusReporter); ok {
+				reporter.EnvironmentStatus(&envs[i], &status)
+			}
+		}
+		statuses = append(statuses, executorClient.EnvironmentStatusInfo{
+			Environment: envs[i].Metadata,
+			Status:      status,
+		})
+	}
+	return statuses, nil
+}
+
+func (l *statusLookup) getPackage(fissionClient *crd.FissionClient, namespace string, name string) (*crd.Package, error) {
+	key := fmt.Sprintf("%v/%v", namespace, name)
+	if pkg, ok := l.packages[key]; ok {
+		return pkg, nil
+	}
+	pkg, err := fissionClient.Packages(namespace).Get(name)
+	if err != nil {
+		return nil, err
+	}
+	l.packages[key] = pkg
+	return pkg, nil
+}
+
+func (l *statusLookup) getEnvironment(fissionClient *crd.FissionClient, namespace string, name string) (*crd.Environment, error) {
+	key := fmt.Sprintf("%v/%v", namespace, name)
+	if env, ok := l.environments[key]; ok {
+		return env, nil
+	}
+	env, err := fissionClient.Environments(namespace).Get(name)
+	if err != nil {
+		return nil, err
+	}
+	l.environments[key] = env
+	return env, nil
+}
@@ -0,0 +1,75 @@
+package executor
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/fission/fission"
+	"github.com/fission/fission/executor/util"
+)
+
+func TestFailureBackoff(t *testing.T) {
+	if b := failureBackoff(1); b != minFailureBackoff {
+		t.Errorf("expected the first failure to back off for %v, got %v", minFailureBackoff, b)
+	}
+	if b := failureBackoff(3); b != 4*minFailureBackoff {
+		t.Errorf("expected the backoff to double with each failure, got %v", b)
+	}
+	if b := failureBackoff(100); b != maxFailureBackoff {
+		t.Errorf("expected the backoff to be capped at %v, got %v", maxFailureBackoff, b)
+	}
+}
+
+func TestFailureBackoffByVersion(t *testing.T) {
+	executor := &Executor{failures: make(map[types.UID]*functionFailure)}
+	m := &metav1.ObjectMeta{Name: "foo", UID: types.UID("1"), ResourceVersion: "1"}
+
+	executor.recordFailure(m, util.MakeSpecializationError(fission.MakeError(fission.ErrorNotFound, "package not found")))
+	err := executor.recordFailure(m, util.MakeSpecializationError(errors.New("failed to load")))
+	if _, ok := err.(util.SpecializationError); ok || err.Error() != "failed to load" {
+		t.Errorf("expected the request to fail with the specialization failure, got %v", err)
+	}
+	if f := executor.getFailure(m); f == nil || f.count != 2 {
+		t.Fatalf("expected two consecutive failures to be recorded, got %+v", f)
+	}
+	err = executor.backoffError(m)
+	if fe, ok := err.(fission.Error); !ok || fe.Code != fission.ErrorInternal {
+		t.Errorf("expected requests to fail with the last error while backing off, got %v", err)
+	}
+
+	updated := &metav1.ObjectMeta{Name: "foo", UID: types.UID("1"), ResourceVersion: "2"}
+	if err := executor.backoffError(updated); err != nil {
+		t.Errorf("expected a new version not to back off, got %v", err)
+	}
+	executor.recordFailure(updated, util.MakeSpecializationError(errors.New("failed to load")))
+	if f := executor.getFailure(updated); f == nil || f.count != 1 {
+		t.Errorf("expected the failures of a new version to be counted anew, got %+v", f)
+	}
+
+	noSpace := fission.MakeError(fission.ErrorNoSpace, "no room for the function")
+	if err := executor.recordFailure(updated, noSpace); err != noSpace {
+		t.Errorf("expected the request to fail with the error, got %v", err)
+	}
+	if f := executor.getFailure(updated); f == nil || f.count != 1 {
+		t.Errorf("expected failures other than specialization failures not to be counted, got %+v", f)
+	}
+
+	executor.recordFailure(updated, nil)
+	if err := executor.backoffError(updated); err != nil {
+		t.Errorf("expected a success to clear the backoff, got %v", err)
+	}
+
+	executor.failures[m.UID] = &functionFailure{
+		resourceVersion: m.ResourceVersion,
+		err:             errors.New("failed to load"),
+		count:           1,
+		retryTime:       time.Now().Add(-time.Second),
+	}
+	if err := executor.backoffError(m); err != nil {
+		t.Errorf("expected requests to be retried once the backoff is over, got %v", err)
+	}
+}
@@ -0,0 +1,315 @@
+/*
+Copyright 2018 The Fission Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package executor
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"reflect"
+	"strings"
+	"time"
+
+	k8s_err "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	apiv1 "k8s.io/client-go/pkg/api/v1"
+
+	"github.com/fission/fission"
+	"github.com/fission/fission/crd"
+)
+
+// The status of each function is kept in a record, a ConfigMap in the
+// namespace of function pods, so that every executor replica reports the
+// same status and it survives restarts.  It isn't stored on the function
+// itself, since any update of a function changes its resource version,
+// which versions the function's pods and deployments.
+//
+// The leader replica periodically computes the status of every function
+// into its record.  Failures to specialize a function happen on any
+// replica, so each replica adds its own to the record under a key of its
+// own, and removes it once it creates a service for the function.  The
+// leader drops failures of previous versions of the function, and old ones
+// left by replicas that are gone.  Each failure is also reported in an
+// event on the function.
+
+const (
+	// how often the leader updates the status records
+	functionStatusSyncInterval = 30 * time.Second
+
+	// failures older than this are dropped from the records
+	failureRecordMaxAge = time.Hour
+
+	statusRecordLabel    = "functionStatus"
+	statusDataKey        = "status"
+	failureDataKeyPrefix = "failure."
+)
+
+type (
+	// statusRecords reads and writes the status records of functions.
+	statusRecords struct {
+		kubernetesClient *kubernetes.Clientset
+		namespace        string
+		replicaName      string // key of this replica's failures
+	}
+
+	// failureRecord is a replica's last failure to specialize a version
+	// of a function.
+	failureRecord struct {
+		ResourceVersion string    `json:"resourceVersion"`
+		Error           string    `json:"error"`
+		Time            time.Time `json:"time"`
+		Count           int       `json:"count"`
+		RetryTime       time.Time `json:"retryTime"`
+	}
+)
+
+func makeStatusRecords(kubernetesClient *kubernetes.Clientset, namespace string, replicaName string) *statusRecords {
+	return &statusRecords{
+		kubernetesClient: kubernetesClient,
+		namespace:        namespace,
+		replicaName:      replicaName,
+	}
+}
+
+func statusRecordName(uid types.UID) string {
+	return fmt.Sprintf("function-status-%v", uid)
+}
+
+func statusRecordLabels(m *metav1.ObjectMeta) map[string]string {
+	return map[string]string{
+		statusRecordLabel:   "true",
+		"functionName":      m.Name,
+		"functionNamespace": m.Namespace,
+		"functionUid":       string(m.UID),
+	}
+}
+
+// patch sets the data keys of the function's record, creating the record
+// if needed; keys set to nil are removed.
+func (sr *statusRecords) patch(m *metav1.ObjectMeta, data map[string]interface{}) error {
+	patch, err := json.Marshal(map[string]interface{}{"data": data})
+	if err != nil {
+		return err
+	}
+	_, err = sr.kubernetesClient.CoreV1().ConfigMaps(sr.namespace).Patch(
+		statusRecordName(m.UID), types.MergePatchType, patch)
+	if !k8s_err.IsNotFound(err) {
+		return err
+	}
+
+	cm := &apiv1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   statusRecordName(m.UID),
+			Labels: statusRecordLabels(m),
+		},
+		Data: make(map[string]string),
+	}
+	for key, value := range data {
+		if s, ok := value.(string); ok {
+			cm.Data[key] = s
+		}
+	}
+	_, err = sr.kubernetesClient.CoreV1().ConfigMaps(sr.namespace).Create(cm)
+	return err
+}
+
+// recordFailure adds this replica's failure to the function's record.
+func (sr *statusRecords) recordFailure(m *metav1.ObjectMeta, f *functionFailure) {
+	value, err := json.Marshal(&failureRecord{
+		ResourceVersion: f.resourceVersion,
+		Error:           f.err.Error(),
+		Time:            f.time,
+		Count:           f.count,
+		RetryTime:       f.retryTime,
+	})
+	if err != nil {
+		return
+	}
+	err = sr.patch(m, map[string]interface{}{failureDataKeyPrefix + sr.replicaName: string(value)})
+	if err != nil {
+		log.Printf("[%v] Error recording failure in the function's status: %v", m.Name, err)
+	}
+}
+
+// clearFailure removes this replica's failure from the function's record.
+func (sr *statusRecords) clearFailure(m *metav1.ObjectMeta) {
+	err := sr.patch(m, map[string]interface{}{failureDataKeyPrefix + sr.replicaName: nil})
+	if err != nil {
+		log.Printf("[%v] Error clearing failure from the function's status: %v", m.Name, err)
+	}
+}
+
+// failureEvent reports this replica's failure in an event on the function,
+// so that it shows up next to the function's other events.
+func (sr *statusRecords) failureEvent(m *metav1.ObjectMeta, f *functionFailure, backoff time.Duration) {
+	now := metav1.NewTime(f.time)
+	event := &apiv1.Event{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      fmt.Sprintf("%v.%x", m.Name, f.time.UnixNano()),
+			Namespace: m.Namespace,
+		},
+		InvolvedObject: apiv1.ObjectReference{
+			Kind:            "Function",
+			APIVersion:      "fission.io/v1",
+			Namespace:       m.Namespace,
+			Name:            m.Name,
+			UID:             m.UID,
+			ResourceVersion: m.ResourceVersion,
+		},
+		Reason: "SpecializationFailed",
+		Message: fmt.Sprintf("Failed to specialize %v times, failing requests for %v: %v",
+			f.count, backoff, f.err),
+		Source:         apiv1.EventSource{Component: "executor", Host: sr.replicaName},
+		FirstTimestamp: now,
+		LastTimestamp:  now,
+		Count:          1,
+		Type:           apiv1.EventTypeWarning,
+	}
+	_, err := sr.kubernetesClient.CoreV1().Events(m.Namespace).Create(event)
+	if err != nil {
+		log.Printf("[%v] Error creating an event for the function's failure: %v", m.Name, err)
+	}
+}
+
+// list returns the records of the functions in the namespace, or in all
+// namespaces if it's empty, by function uid.
+func (sr *statusRecords) list(namespace string) (map[types.UID]*apiv1.ConfigMap, error) {
+	sel := map[string]string{statusRecordLabel: "true"}
+	if len(namespace) > 0 {
+		sel["functionNamespace"] = namespace
+	}
+	cmList, err := sr.kubernetesClient.CoreV1().ConfigMaps(sr.namespace).List(metav1.ListOptions{
+		LabelSelector: labels.Set(sel).AsSelector().String(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	records := make(map[types.UID]*apiv1.ConfigMap, len(cmList.Items))
+	for i := range cmList.Items {
+		records[types.UID(cmList.Items[i].ObjectMeta.Labels["functionUid"])] = &cmList.Items[i]
+	}
+	return records, nil
+}
+
+func (sr *statusRecords) delete(cm *apiv1.ConfigMap) error {
+	err := sr.kubernetesClient.CoreV1().ConfigMaps(sr.namespace).Delete(cm.ObjectMeta.Name, nil)
+	if k8s_err.IsNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+// recordedStatus returns the status stored in a record.
+func recordedStatus(cm *apiv1.ConfigMap) fission.FunctionStatus {
+	status := fission.FunctionStatus{}
+	if cm == nil {
+		return status
+	}
+	err := json.Unmarshal([]byte(cm.Data[statusDataKey]), &status)
+	if err != nil && len(cm.Data[statusDataKey]) > 0 {
+		log.Printf("Error decoding function status record %v: %v", cm.ObjectMeta.Name, err)
+	}
+	return status
+}
+
+// recordedFailures returns the failures stored in a record, by data key.
+func recordedFailures(cm *apiv1.ConfigMap) map[string]*failureRecord {
+	failures := make(map[string]*failureRecord)
+	if cm == nil {
+		return failures
+	}
+	for key, value := range cm.Data {
+		if !strings.HasPrefix(key, failureDataKeyPrefix) {
+			continue
+		}
+		f := &failureRecord{}
+		if err := json.Unmarshal([]byte(value), f); err == nil {
+			failures[key] = f
+		}
+	}
+	return failures
+}
+
+// functionStatusSyncer has the leader keep the status record of every
+// function up to date, and delete the records of deleted functions.
+func (executor *Executor) functionStatusSyncer() {
+	for {
+		executor.syncFunctionStatuses()
+		time.Sleep(functionStatusSyncInterval)
+	}
+}
+
+func (executor *Executor) syncFunctionStatuses() {
+	fnList, err := executor.fissionClient.Functions(metav1.NamespaceAll).List(metav1.ListOptions{})
+	if err != nil {
+		log.Printf("Error listing functions to update their status: %v", err)
+		return
+	}
+	records, err := executor.statusRecords.list(metav1.NamespaceAll)
+	if err != nil {
+		log.Printf("Error listing function status records: %v", err)
+		return
+	}
+
+	lookup := &statusLookup{
+		packages:     make(map[string]*crd.Package),
+		environments: make(map[string]*crd.Environment),
+	}
+	for i := range fnList.Items {
+		fn := &fnList.Items[i]
+		record := records[fn.Metadata.UID]
+		delete(records, fn.Metadata.UID)
+
+		data := make(map[string]interface{})
+		failures := recordedFailures(record)
+		for key, f := range failures {
+			// failures of previous versions, or too old, don't count
+			if f.ResourceVersion != fn.Metadata.ResourceVersion || time.Since(f.Time) > failureRecordMaxAge {
+				data[key] = nil
+				delete(failures, key)
+			}
+		}
+
+		status := executor.functionStatus(fn, lookup, failures)
+		old := recordedStatus(record)
+		if record == nil || !reflect.DeepEqual(old, status) {
+			value, err := json.Marshal(&status)
+			if err != nil {
+				continue
+			}
+			data[statusDataKey] = string(value)
+		}
+		if len(data) == 0 {
+			continue
+		}
+		err = executor.statusRecords.patch(&fn.Metadata, data)
+		if err != nil {
+			log.Printf("[%v] Error updating the function's status: %v", fn.Metadata.Name, err)
+		}
+	}
+
+	// the rest are records of deleted functions
+	for _, cm := range records {
+		err := executor.statusRecords.delete(cm)
+		if err != nil {
+			log.Printf("Error deleting function status record %v: %v", cm.ObjectMeta.Name, err)
+		}
+	}
+}
@@ -17,8 +17,24 @@ limitations under the License.
 package util
 
 import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"time"
+
+	k8s_err "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/pkg/api/v1"
+
+	"github.com/fission/fission"
+	"github.com/fission/fission/crd"
 )
 
 var resources map[string]resource.Quantity
@@ -48,3 +64,340 @@ func GetFetcherResources() (v1.ResourceRequirements, error) {
 	}
 	return fetcherResources, nil
 }
+
+// GetFunctionResources overrides only the resources which are overridden at function level otherwise
+// default to resources specified at environment level
+func GetFunctionResources(env *crd.Environment, fn *crd.Function) v1.ResourceRequirements {
+	resources := env.Spec.Resources
+	if resources.Requests == nil {
+		resources.Requests = make(map[v1.ResourceName]resource.Quantity)
+	}
+	if resources.Limits == nil {
+		resources.Limits = make(map[v1.ResourceName]resource.Quantity)
+	}
+	// Only override the once specified at function, rest default to values from env.
+	_, ok := fn.Spec.Resources.Requests[v1.ResourceCPU]
+	if ok {
+		resources.Requests[v1.ResourceCPU] = fn.Spec.Resources.Requests[v1.ResourceCPU]
+	}
+
+	_, ok = fn.Spec.Resources.Requests[v1.ResourceMemory]
+	if ok {
+		resources.Requests[v1.ResourceMemory] = fn.Spec.Resources.Requests[v1.ResourceMemory]
+	}
+
+	_, ok = fn.Spec.Resources.Limits[v1.ResourceCPU]
+	if ok {
+		resources.Limits[v1.ResourceCPU] = fn.Spec.Resources.Limits[v1.ResourceCPU]
+	}
+
+	_, ok = fn.Spec.Resources.Limits[v1.ResourceMemory]
+	if ok {
+		resources.Limits[v1.ResourceMemory] = fn.Spec.Resources.Limits[v1.ResourceMemory]
+	}
+
+	return resources
+}
+
+// FunctionEnvChecksumAnnotation is set on the template of pods whose
+// environment refers to a copy of the function's keys, so that the pods are
+// replaced when the values of the keys change.
+const FunctionEnvChecksumAnnotation = "functionEnvChecksum"
+
+// ResolveFunctionEnv returns the environment variables of the function with
+// the values of the secret and configmap keys they refer to, for the
+// FunctionLoadRequest.  Variables referring to an optional key that doesn't
+// exist are left out.  The values must not be written into pod specs.
+func ResolveFunctionEnv(kubernetesClient kubernetes.Interface, fn *crd.Function) ([]v1.EnvVar, error) {
+	env := make([]v1.EnvVar, 0, len(fn.Spec.Env))
+	for _, e := range fn.Spec.Env {
+		if e.ValueFrom == nil {
+			env = append(env, v1.EnvVar{Name: e.Name, Value: e.Value})
+			continue
+		}
+		value, found, err := getEnvKey(kubernetesClient, fn, e)
+		if err != nil {
+			return nil, err
+		}
+		if found {
+			env = append(env, v1.EnvVar{Name: e.Name, Value: value})
+		}
+	}
+	return env, nil
+}
+
+// GetFunctionEnv returns the environment variables of a container running
+// the function.  Function pods don't run in the function's namespace, so
+// the secret and configmap keys the variables refer to are copied into a
+// secret named name in namespace, which the variables refer to instead;
+// their values never appear in the pod spec.  The checksum of the copied
+// values is returned too, empty if there are none.  Variables referring to
+// an optional key that doesn't exist are left out.
+func GetFunctionEnv(kubernetesClient kubernetes.Interface, fn *crd.Function, namespace string, name string) ([]v1.EnvVar, string, error) {
+	env := make([]v1.EnvVar, 0, len(fn.Spec.Env))
+	data := make(map[string][]byte)
+	for _, e := range fn.Spec.Env {
+		if e.ValueFrom == nil {
+			env = append(env, v1.EnvVar{Name: e.Name, Value: e.Value})
+			continue
+		}
+		value, found, err := getEnvKey(kubernetesClient, fn, e)
+		if err != nil {
+			return nil, "", err
+		}
+		if !found {
+			continue
+		}
+		data[e.Name] = []byte(value)
+		env = append(env, v1.EnvVar{
+			Name: e.Name,
+			ValueFrom: &v1.EnvVarSource{
+				SecretKeyRef: &v1.SecretKeySelector{
+					LocalObjectReference: v1.LocalObjectReference{Name: name},
+					Key:                  e.Name,
+				},
+			},
+		})
+	}
+	if len(data) == 0 {
+		return env, "", nil
+	}
+
+	err := saveFunctionEnv(kubernetesClient, fn, namespace, name, data)
+	if err != nil {
+		return nil, "", err
+	}
+	return env, envChecksum(data), nil
+}
+
+// SetFunctionEnvOwner makes owner, the object whose pods refer to the copy
+// of the function's keys named name, own the copy, so that they're deleted
+// together.  There's nothing to do if the function has no copy.
+func SetFunctionEnvOwner(kubernetesClient kubernetes.Interface, namespace string, name string, owner metav1.OwnerReference) error {
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"ownerReferences": []metav1.OwnerReference{owner},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	_, err = kubernetesClient.CoreV1().Secrets(namespace).Patch(name, types.MergePatchType, patch)
+	if k8s_err.IsNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+func saveFunctionEnv(kubernetesClient kubernetes.Interface, fn *crd.Function, namespace string, name string, data map[string][]byte) error {
+	secret, err := kubernetesClient.CoreV1().Secrets(namespace).Get(name, metav1.GetOptions{})
+	if k8s_err.IsNotFound(err) {
+		_, err = kubernetesClient.CoreV1().Secrets(namespace).Create(&v1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: name,
+				Labels: map[string]string{
+					"functionName":      fn.Metadata.Name,
+					"functionNamespace": fn.Metadata.Namespace,
+					"functionUid":       string(fn.Metadata.UID),
+				},
+			},
+			Data: data,
+			Type: v1.SecretTypeOpaque,
+		})
+		return err
+	}
+	if err != nil {
+		return err
+	}
+	if reflect.DeepEqual(secret.Data, data) {
+		return nil
+	}
+	// keep the owner set on the copy
+	secret.Data = data
+	_, err = kubernetesClient.CoreV1().Secrets(namespace).Update(secret)
+	return err
+}
+
+func envChecksum(data map[string][]byte) string {
+	keys := make([]string, 0, len(data))
+	for key := range data {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	h := sha256.New()
+	for _, key := range keys {
+		fmt.Fprintf(h, "%v=%v\n", key, base64.StdEncoding.EncodeToString(data[key]))
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// getEnvKey returns the value of the secret or configmap key in the
+// function's namespace that the variable refers to, and whether it was
+// found; an error is returned if it's required and not found.
+func getEnvKey(kubernetesClient kubernetes.Interface, fn *crd.Function, e v1.EnvVar) (string, bool, error) {
+	var value string
+	var found bool
+	var optional *bool
+	switch {
+	case e.ValueFrom.SecretKeyRef != nil:
+		ref := e.ValueFrom.SecretKeyRef
+		optional = ref.Optional
+		secret, err := kubernetesClient.CoreV1().Secrets(fn.Metadata.Namespace).Get(ref.Name, metav1.GetOptions{})
+		if err != nil && !k8s_err.IsNotFound(err) {
+			return "", false, err
+		}
+		if err == nil {
+			var data []byte
+			data, found = secret.Data[ref.Key]
+			value = string(data)
+		}
+	case e.ValueFrom.ConfigMapKeyRef != nil:
+		ref := e.ValueFrom.ConfigMapKeyRef
+		optional = ref.Optional
+		cfgmap, err := kubernetesClient.CoreV1().ConfigMaps(fn.Metadata.Namespace).Get(ref.Name, metav1.GetOptions{})
+		if err != nil && !k8s_err.IsNotFound(err) {
+			return "", false, err
+		}
+		if err == nil {
+			value, found = cfgmap.Data[ref.Key]
+		}
+	default:
+		return "", false, fission.MakeError(fission.ErrorInvalidArgument,
+			fmt.Sprintf("environment variable %v must refer to a secret or configmap key", e.Name))
+	}
+
+	if !found && (optional == nil || !*optional) {
+		return "", false, fission.MakeError(fission.ErrorNotFound,
+			fmt.Sprintf("key referred to by environment variable %v of function %v not found", e.Name, fn.Metadata.Name))
+	}
+	return value, found, nil
+}
+
+// EnvVarMap returns the environment variables as a map, for the
+// FunctionLoadRequest.
+func EnvVarMap(env []v1.EnvVar) map[string]string {
+	if len(env) == 0 {
+		return nil
+	}
+	m := make(map[string]string, len(env))
+	for _, e := range env {
+		m[e.Name] = e.Value
+	}
+	return m
+}
+
+// MergeFunctionPodSpec overlays the PodSpec of the function, then the one
+// of its environment, on the spec of a pod running only this function.  The
+// runtime container of such a pod is named after the function, so overlay
+// containers named after the environment are merged into it.
+func MergeFunctionPodSpec(spec *v1.PodSpec, env *crd.Environment, fn *crd.Function) v1.PodSpec {
+	return fission.MergePodSpecs(spec,
+		renameContainer(fn.Spec.PodSpec, env.Metadata.Name, fn.Metadata.Name),
+		renameContainer(env.Spec.PodSpec, env.Metadata.Name, fn.Metadata.Name))
+}
+
+func renameContainer(podSpec *v1.PodSpec, oldName string, newName string) *v1.PodSpec {
+	if podSpec == nil {
+		return nil
+	}
+	result := *podSpec
+	result.Containers = make([]v1.Container, len(podSpec.Containers))
+	for i, c := range podSpec.Containers {
+		if c.Name == oldName {
+			c.Name = newName
+		}
+		result.Containers[i] = c
+	}
+	return &result
+}
+
+// GetPodFailure returns the reason and message of a container of the pod
+// that's stuck and won't become ready without a change, such as an image
+// that can't be pulled or a container that keeps crashing, or empty strings
+// if there's none.
+func GetPodFailure(pod *v1.Pod) (string, string) {
+	statuses := make([]v1.ContainerStatus, 0, len(pod.Status.InitContainerStatuses)+len(pod.Status.ContainerStatuses))
+	statuses = append(statuses, pod.Status.InitContainerStatuses...)
+	statuses = append(statuses, pod.Status.ContainerStatuses...)
+	for _, cs := range statuses {
+		if cs.State.Waiting == nil {
+			continue
+		}
+		switch cs.State.Waiting.Reason {
+		case "ErrImagePull", "ImagePullBackOff", "InvalidImageName",
+			"CrashLoopBackOff", "CreateContainerConfigError", "RunContainerError":
+			return cs.State.Waiting.Reason, fmt.Sprintf("container %v: %v", cs.Name, cs.State.Waiting.Message)
+		}
+	}
+	return "", ""
+}
+
+// PodFailureError returns an error telling why the pod won't become ready
+// without a change, or nil if it may still become ready.
+func PodFailureError(pod *v1.Pod) error {
+	reason, message := GetPodFailure(pod)
+	if len(reason) == 0 {
+		return nil
+	}
+	hint := ""
+	switch reason {
+	case "ErrImagePull", "ImagePullBackOff", "InvalidImageName":
+		hint = "; check the image name and that the cluster can pull it"
+	case "CrashLoopBackOff":
+		hint = "; the container keeps crashing, see its logs"
+	}
+	return fission.MakeError(fission.ErrorInternal,
+		fmt.Sprintf("pod %v won't become ready: %v: %v%v", pod.ObjectMeta.Name, reason, message, hint))
+}
+
+// SpecializationError is a failure to fetch a function into a pod, or to
+// load it there, including a function pod that crashes or can't pull its
+// image.  Retrying right away would most likely fail the same way, so the
+// executor backs off requests for the function after one.  Other failures,
+// such as API errors and timeouts waiting for a ready pod, aren't wrapped.
+type SpecializationError struct {
+	Err error
+}
+
+// MakeSpecializationError wraps err as a specialization failure; nil stays
+// nil.
+func MakeSpecializationError(err error) error {
+	if err == nil {
+		return nil
+	}
+	return SpecializationError{Err: err}
+}
+
+func (e SpecializationError) Error() string {
+	return e.Err.Error()
+}
+
+// UnwrapSpecializationError returns the failure wrapped by err and true if
+// it's a specialization failure, or err and false otherwise.
+func UnwrapSpecializationError(err error) (error, bool) {
+	if se, ok := err.(SpecializationError); ok {
+		return se.Err, true
+	}
+	return err, false
+}
+
+// IsLeftover returns true if the object was made by an executor before
+// startTime, and hasn't been specialized since.  Executor replicas share an
+// instance id, which also survives restarts, so leftovers of previous runs
+// are told apart by age rather than by id.
+func IsLeftover(obj *metav1.ObjectMeta, startTime time.Time) bool {
+	_, ok := obj.Labels[fission.EXECUTOR_INSTANCEID_LABEL]
+	// Backward compatibility with older label name
+	_, pok := obj.Labels[fission.POOLMGR_INSTANCEID_LABEL]
+	if !ok && !pok {
+		return false
+	}
+	if !obj.CreationTimestamp.Time.Before(startTime) {
+		return false
+	}
+	if atime, err := time.Parse(time.RFC3339, obj.Annotations[fission.LAST_ACCESS_TIME_ANNOTATION]); err == nil &&
+		!atime.Before(startTime) {
+		return false
+	}
+	return true
+}
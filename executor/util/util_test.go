@@ -0,0 +1,55 @@
+package util
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/fission/fission"
+)
+
+func TestIsLeftover(t *testing.T) {
+	startTime := time.Now()
+	before := metav1.NewTime(startTime.Add(-time.Hour))
+	after := metav1.NewTime(startTime.Add(time.Minute))
+	labels := map[string]string{fission.EXECUTOR_INSTANCEID_LABEL: "abcd1234"}
+
+	// same instance id as the running executor, made by a previous run
+	if !IsLeftover(&metav1.ObjectMeta{Labels: labels, CreationTimestamp: before}, startTime) {
+		t.Errorf("expected an object made before the executor started to be a leftover")
+	}
+	if IsLeftover(&metav1.ObjectMeta{Labels: labels, CreationTimestamp: after}, startTime) {
+		t.Errorf("expected an object made by a running replica to be kept")
+	}
+	if IsLeftover(&metav1.ObjectMeta{CreationTimestamp: before}, startTime) {
+		t.Errorf("expected an object not made by the executor to be kept")
+	}
+
+	specialized := &metav1.ObjectMeta{
+		Labels:            labels,
+		CreationTimestamp: before,
+		Annotations: map[string]string{
+			fission.LAST_ACCESS_TIME_ANNOTATION: after.UTC().Format(time.RFC3339),
+		},
+	}
+	if IsLeftover(specialized, startTime) {
+		t.Errorf("expected a pod specialized since the executor started to be kept")
+	}
+}
+
+func TestEnvChecksum(t *testing.T) {
+	a := envChecksum(map[string][]byte{"USER": []byte("admin"), "PASSWORD": []byte("secret")})
+	b := envChecksum(map[string][]byte{"PASSWORD": []byte("secret"), "USER": []byte("admin")})
+	if a != b {
+		t.Errorf("expected the checksum not to depend on the order of the keys")
+	}
+	c := envChecksum(map[string][]byte{"USER": []byte("admin"), "PASSWORD": []byte("changed")})
+	if a == c {
+		t.Errorf("expected the checksum to change with the values")
+	}
+	if strings.Contains(a, "secret") {
+		t.Errorf("expected the checksum not to reveal the values")
+	}
+}
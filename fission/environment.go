@@ -92,6 +92,12 @@ func envCreate(c *cli.Context) error {
 				Command: envBuildCmd,
 			},
 			Poolsize:                     poolsize,
+			MinPoolsize:                  c.Int("minpoolsize"),
+			MaxPoolsize:                  c.Int("maxpoolsize"),
+			IdleTimeout:                  getIdleTimeout(c),
+			MaxSpecializedPods:           c.Int("maxspecializedpods"),
+			PodReadyTimeout:              c.Int("podreadytimeout"),
+			SpecializationTimeout:        c.Int("specializationtimeout"),
 			Resources:                    resourceReq,
 			AllowAccessToExternalNetwork: envExternalNetwork,
 			TerminationGracePeriod:       envGracePeriod,
@@ -128,11 +134,21 @@ func envGet(c *cli.Context) error {
 	env, err := client.EnvironmentGet(m)
 	checkErr(err, "get environment")
 
+	phase := env.Status.Phase
+	if len(phase) == 0 {
+		phase = "Unknown"
+	}
+
 	w := tabwriter.NewWriter(os.Stdout, 0, 0, 1, ' ', 0)
-	fmt.Fprintf(w, "%v\t%v\t%v\n", "NAME", "UID", "IMAGE")
-	fmt.Fprintf(w, "%v\t%v\t%v\n",
-		env.Metadata.Name, env.Metadata.UID, env.Spec.Runtime.Image)
+	fmt.Fprintf(w, "%v\t%v\t%v\t%v\t%v\t%v\n", "NAME", "UID", "IMAGE", "STATUS", "READY", "OUTDATED")
+	fmt.Fprintf(w, "%v\t%v\t%v\t%v\t%v\t%v\n",
+		env.Metadata.Name, env.Metadata.UID, env.Spec.Runtime.Image,
+		phase, env.Status.ReadyPods, env.Status.OutdatedPods)
 	w.Flush()
+
+	if len(env.Status.Message) > 0 {
+		fmt.Printf("\n%v\n", env.Status.Message)
+	}
 	return nil
 }
 
@@ -178,6 +194,30 @@ func envUpdate(c *cli.Context) error {
 		env.Spec.Poolsize = c.Int("poolsize")
 	}
 
+	if c.IsSet("minpoolsize") {
+		env.Spec.MinPoolsize = c.Int("minpoolsize")
+	}
+
+	if c.IsSet("maxpoolsize") {
+		env.Spec.MaxPoolsize = c.Int("maxpoolsize")
+	}
+
+	if c.IsSet("idletimeout") {
+		env.Spec.IdleTimeout = getIdleTimeout(c)
+	}
+
+	if c.IsSet("maxspecializedpods") {
+		env.Spec.MaxSpecializedPods = c.Int("maxspecializedpods")
+	}
+
+	if c.IsSet("podreadytimeout") {
+		env.Spec.PodReadyTimeout = c.Int("podreadytimeout")
+	}
+
+	if c.IsSet("specializationtimeout") {
+		env.Spec.SpecializationTimeout = c.Int("specializationtimeout")
+	}
+
 	if c.IsSet("period") {
 		env.Spec.TerminationGracePeriod = c.Int64("period")
 	}
@@ -289,3 +329,41 @@ func getResourceReq(c *cli.Context, resources v1.ResourceRequirements) v1.Resour
 	}
 	return resources
 }
+
+func envPool(c *cli.Context) error {
+	client := getClient(c.GlobalString("server"))
+
+	envName := c.String("name")
+	if len(envName) == 0 {
+		fatal("Need a name, use --name.")
+	}
+	if c.Bool("drain") && c.Bool("recreate") {
+		fatal("Use only one of --drain and --recreate")
+	}
+
+	m := &metav1.ObjectMeta{
+		Name:      envName,
+		Namespace: metav1.NamespaceDefault,
+	}
+
+	if c.Bool("drain") {
+		err := client.EnvironmentPoolDrain(m)
+		checkErr(err, "drain environment pool")
+		fmt.Printf("draining the pool of environment '%v'\n", envName)
+	}
+	if c.Bool("recreate") {
+		err := client.EnvironmentPoolRecreate(m)
+		checkErr(err, "recreate environment pool")
+		fmt.Printf("recreating the pool of environment '%v'\n", envName)
+	}
+
+	pool, err := client.EnvironmentPoolGet(m)
+	checkErr(err, "get environment pool")
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 1, ' ', 0)
+	fmt.Fprintf(w, "%v\t%v\t%v\t%v\t%v\n", "DEPLOYMENT", "POOLSIZE", "PODS", "READY", "SPECIALIZED")
+	fmt.Fprintf(w, "%v\t%v\t%v\t%v\t%v\n",
+		pool.Deployment, pool.Replicas, pool.Pods, pool.ReadyPods, pool.Specialized)
+	w.Flush()
+	return nil
+}
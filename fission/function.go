@@ -18,6 +18,7 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io/ioutil"
@@ -35,6 +36,8 @@ import (
 
 	"github.com/fission/fission"
 	"github.com/fission/fission/crd"
+	executorClient "github.com/fission/fission/executor/client"
+	"github.com/fission/fission/executor/jobmgr"
 	"github.com/fission/fission/fission/logdb"
 )
 
@@ -84,8 +87,10 @@ func getInvokeStrategy(minScale int, maxScale int, executorType string, targetcp
 		fnExecutor = fission.ExecutorTypePoolmgr
 	case fission.ExecutorTypeNewdeploy:
 		fnExecutor = fission.ExecutorTypeNewdeploy
+	case fission.ExecutorTypeJob:
+		fnExecutor = fission.ExecutorTypeJob
 	default:
-		fatal("Executor type must be one of 'poolmgr' or 'newdeploy', defaults to 'poolmgr'")
+		fatal("Executor type must be one of 'poolmgr', 'newdeploy' or 'job', defaults to 'poolmgr'")
 	}
 
 	// Right now a simple single case strategy implementation
@@ -102,6 +107,54 @@ func getInvokeStrategy(minScale int, maxScale int, executorType string, targetcp
 	return strategy
 }
 
+func getIdleTimeout(c *cli.Context) int {
+	idleTimeout := c.Int("idletimeout")
+	if idleTimeout < 0 {
+		fatal("Idle timeout must be greater or equal to 0")
+	}
+	return idleTimeout
+}
+
+func getTargetConcurrency(c *cli.Context) int {
+	targetConcurrency := c.Int("targetconcurrency")
+	if targetConcurrency < 0 {
+		fatal("Target concurrency must be greater or equal to 0")
+	}
+	return targetConcurrency
+}
+
+func getTargetLatency(c *cli.Context) int {
+	targetLatency := c.Int("targetlatency")
+	if targetLatency < 0 {
+		fatal("Target latency must be greater or equal to 0")
+	}
+	return targetLatency
+}
+
+func getTargetRPS(c *cli.Context) int {
+	targetRPS := c.Int("targetrps")
+	if targetRPS < 0 {
+		fatal("Target requests per second must be greater or equal to 0")
+	}
+	return targetRPS
+}
+
+func getRetries(c *cli.Context) int {
+	retries := c.Int("retries")
+	if retries < 0 {
+		fatal("Retries must be greater or equal to 0")
+	}
+	return retries
+}
+
+func getActiveDeadline(c *cli.Context) int {
+	activeDeadline := c.Int("activedeadline")
+	if activeDeadline < 0 {
+		fatal("Active deadline must be greater or equal to 0")
+	}
+	return activeDeadline
+}
+
 func getTargetCPU(c *cli.Context) int {
 	var targetCPU int
 	if c.IsSet("targetcpu") {
@@ -141,6 +194,10 @@ func fnCreate(c *cli.Context) error {
 	}
 	entrypoint := c.String("entrypoint")
 	pkgName := c.String("pkg")
+	image := c.String("image")
+	if len(image) > 0 && len(pkgName) > 0 {
+		fatal("Need either of --image or --pkg and not both arguments.")
+	}
 
 	var pkgMetadata *metav1.ObjectMeta
 	var envName string
@@ -180,23 +237,41 @@ func fnCreate(c *cli.Context) error {
 			}
 		}
 
-		srcArchiveName := c.String("src")
-		deployArchiveName := c.String("code")
-		if len(deployArchiveName) == 0 {
-			deployArchiveName = c.String("deploy")
-		}
-		// fatal when both src & deploy archive are empty
-		if len(srcArchiveName) == 0 && len(deployArchiveName) == 0 {
-			fatal("Need --deploy or --src argument.")
-		}
+		// functions with an image have no package
+		if len(image) == 0 {
+			srcArchiveName := c.String("src")
+			deployArchiveName := c.String("code")
+			if len(deployArchiveName) == 0 {
+				deployArchiveName = c.String("deploy")
+			}
+			// fatal when both src & deploy archive are empty
+			if len(srcArchiveName) == 0 && len(deployArchiveName) == 0 {
+				fatal("Need --deploy or --src argument.")
+			}
 
-		buildcmd := c.String("buildcmd")
+			buildcmd := c.String("buildcmd")
+
+			// create new package
+			pkgMetadata = createPackage(client, envName, srcArchiveName, deployArchiveName, buildcmd, specFile)
+		}
+	}
 
-		// create new package
-		pkgMetadata = createPackage(client, envName, srcArchiveName, deployArchiveName, buildcmd, specFile)
+	executorType := c.String("executortype")
+	if len(image) > 0 {
+		if !c.IsSet("executortype") {
+			executorType = fission.ExecutorTypeNewdeploy
+		} else if executorType != fission.ExecutorTypeNewdeploy {
+			fatal("Functions with an --image are only supported by the newdeploy executor.")
+		}
 	}
 
-	invokeStrategy := getInvokeStrategy(c.Int("minscale"), c.Int("maxscale"), c.String("executortype"), getTargetCPU(c))
+	invokeStrategy := getInvokeStrategy(c.Int("minscale"), c.Int("maxscale"), executorType, getTargetCPU(c))
+	invokeStrategy.ExecutionStrategy.IdleTimeout = getIdleTimeout(c)
+	invokeStrategy.ExecutionStrategy.TargetConcurrency = getTargetConcurrency(c)
+	invokeStrategy.ExecutionStrategy.TargetLatency = getTargetLatency(c)
+	invokeStrategy.ExecutionStrategy.TargetRPS = getTargetRPS(c)
+	invokeStrategy.ExecutionStrategy.Retries = getRetries(c)
+	invokeStrategy.ExecutionStrategy.ActiveDeadline = getActiveDeadline(c)
 	resourceReq := getResourceReq(c, apiv1.ResourceRequirements{})
 	if (c.IsSet("mincpu") || c.IsSet("maxcpu") || c.IsSet("minmemory") || c.IsSet("maxmemory")) &&
 		invokeStrategy.ExecutionStrategy.ExecutorType == fission.ExecutorTypePoolmgr {
@@ -238,20 +313,24 @@ func fnCreate(c *cli.Context) error {
 				Name:      envName,
 				Namespace: metav1.NamespaceDefault,
 			},
-			Package: fission.FunctionPackageRef{
-				FunctionName: entrypoint,
-				PackageRef: fission.PackageRef{
-					Namespace:       pkgMetadata.Namespace,
-					Name:            pkgMetadata.Name,
-					ResourceVersion: pkgMetadata.ResourceVersion,
-				},
-			},
+			Image:          image,
+			Port:           c.Int("port"),
 			Secrets:        secrets,
 			ConfigMaps:     cfgmaps,
 			Resources:      resourceReq,
 			InvokeStrategy: invokeStrategy,
 		},
 	}
+	if pkgMetadata != nil {
+		function.Spec.Package = fission.FunctionPackageRef{
+			FunctionName: entrypoint,
+			PackageRef: fission.PackageRef{
+				Namespace:       pkgMetadata.Namespace,
+				Name:            pkgMetadata.Name,
+				ResourceVersion: pkgMetadata.ResourceVersion,
+			},
+		}
+	}
 
 	// if we're writing a spec, don't create the function
 	if spec {
@@ -314,6 +393,9 @@ func fnGet(c *cli.Context) error {
 	}
 	fn, err := client.FunctionGet(m)
 	checkErr(err, "get function")
+	if len(fn.Spec.Image) > 0 {
+		fatal(fmt.Sprintf("Function '%v' runs image %v and has no code", fnName, fn.Spec.Image))
+	}
 
 	pkg, err := client.PackageGet(&metav1.ObjectMeta{
 		Name:      fn.Spec.Package.PackageRef.Name,
@@ -342,13 +424,47 @@ func fnGetMeta(c *cli.Context) error {
 	checkErr(err, "get function")
 
 	w := tabwriter.NewWriter(os.Stdout, 0, 0, 1, ' ', 0)
-	fmt.Fprintf(w, "%v\t%v\t%v\n", "NAME", "UID", "ENV")
-	fmt.Fprintf(w, "%v\t%v\t%v\n",
-		f.Metadata.Name, f.Metadata.UID, f.Spec.Environment.Name)
+	fmt.Fprintf(w, "%v\t%v\t%v\t%v\t%v\n", "NAME", "UID", "ENV", "STATUS", "READY")
+	fmt.Fprintf(w, "%v\t%v\t%v\t%v\t%v\n",
+		f.Metadata.Name, f.Metadata.UID, f.Spec.Environment.Name,
+		functionHealth(&f.Status), f.Status.ReadyReplicas)
 	w.Flush()
+
+	if len(f.Status.Conditions) > 0 {
+		fmt.Println()
+		w = tabwriter.NewWriter(os.Stdout, 0, 0, 1, ' ', 0)
+		fmt.Fprintf(w, "%v\t%v\t%v\t%v\n", "CONDITION", "STATUS", "REASON", "MESSAGE")
+		for _, cond := range f.Status.Conditions {
+			fmt.Fprintf(w, "%v\t%v\t%v\t%v\n", cond.Type, cond.Status, cond.Reason, cond.Message)
+		}
+		w.Flush()
+	}
+	if len(f.Status.LastError) > 0 {
+		fmt.Printf("\nLast error")
+		if f.Status.LastErrorTime != nil {
+			fmt.Printf(" (%v)", f.Status.LastErrorTime.Time.Format(time.RFC3339))
+		}
+		fmt.Printf(": %v\n", f.Status.LastError)
+		if f.Status.RetryTime != nil {
+			fmt.Printf("Failed %v times, retrying after %v\n",
+				f.Status.Failures, f.Status.RetryTime.Time.Format(time.RFC3339))
+		}
+	}
 	return err
 }
 
+// functionHealth sums up the status of a function reported by the
+// executor.
+func functionHealth(status *fission.FunctionStatus) string {
+	if !status.IsKnown() {
+		return "Unknown"
+	}
+	if !status.IsHealthy() {
+		return "Unhealthy"
+	}
+	return "Healthy"
+}
+
 func fnUpdate(c *cli.Context) error {
 	client := getClient(c.GlobalString("server"))
 
@@ -424,50 +540,67 @@ func fnUpdate(c *cli.Context) error {
 		function.Spec.Environment.Name = envName
 	}
 
-	if len(entrypoint) > 0 {
-		function.Spec.Package.FunctionName = entrypoint
+	if c.IsSet("image") {
+		if len(function.Spec.Image) == 0 {
+			fatal("Only functions created with --image can be updated with --image.")
+		}
+		function.Spec.Image = c.String("image")
 	}
-	if len(pkgName) == 0 {
-		pkgName = function.Spec.Package.PackageRef.Name
+	if c.IsSet("port") {
+		function.Spec.Port = c.Int("port")
 	}
 
-	pkg, err := client.PackageGet(&metav1.ObjectMeta{
-		Namespace: metav1.NamespaceDefault,
-		Name:      pkgName,
-	})
-	checkErr(err, fmt.Sprintf("read package '%v'", pkgName))
+	// functions with an image have no package
+	if len(function.Spec.Image) > 0 {
+		if len(deployArchiveName) > 0 || len(srcArchiveName) > 0 || len(pkgName) > 0 || len(buildcmd) > 0 || len(entrypoint) > 0 {
+			fatal("Function runs an image, use --image to update it.")
+		}
+	} else {
+		if len(entrypoint) > 0 {
+			function.Spec.Package.FunctionName = entrypoint
+		}
+		if len(pkgName) == 0 {
+			pkgName = function.Spec.Package.PackageRef.Name
+		}
 
-	pkgMetadata := &pkg.Metadata
+		pkg, err := client.PackageGet(&metav1.ObjectMeta{
+			Namespace: metav1.NamespaceDefault,
+			Name:      pkgName,
+		})
+		checkErr(err, fmt.Sprintf("read package '%v'", pkgName))
 
-	if len(deployArchiveName) != 0 || len(srcArchiveName) != 0 || len(buildcmd) != 0 || len(envName) != 0 {
-		fnList, err := getFunctionsByPackage(client, pkg.Metadata.Name)
-		checkErr(err, "get function list")
+		pkgMetadata := &pkg.Metadata
 
-		if !force && len(fnList) > 1 {
-			fatal("Package is used by multiple functions, use --force to force update")
-		}
+		if len(deployArchiveName) != 0 || len(srcArchiveName) != 0 || len(buildcmd) != 0 || len(envName) != 0 {
+			fnList, err := getFunctionsByPackage(client, pkg.Metadata.Name)
+			checkErr(err, "get function list")
+
+			if !force && len(fnList) > 1 {
+				fatal("Package is used by multiple functions, use --force to force update")
+			}
 
-		pkgMetadata = updatePackage(client, pkg, envName, srcArchiveName, deployArchiveName, buildcmd)
-		checkErr(err, fmt.Sprintf("update package '%v'", pkgName))
+			pkgMetadata = updatePackage(client, pkg, envName, srcArchiveName, deployArchiveName, buildcmd)
+			checkErr(err, fmt.Sprintf("update package '%v'", pkgName))
 
-		fmt.Printf("package '%v' updated\n", pkgMetadata.GetName())
+			fmt.Printf("package '%v' updated\n", pkgMetadata.GetName())
 
-		// update resource version of package reference of functions that shared the same package
-		for _, fn := range fnList {
-			// ignore the update for current function here, it will be updated later.
-			if fn.Metadata.Name != fnName {
-				fn.Spec.Package.PackageRef.ResourceVersion = pkgMetadata.ResourceVersion
-				_, err := client.FunctionUpdate(&fn)
-				checkErr(err, "update function")
+			// update resource version of package reference of functions that shared the same package
+			for _, fn := range fnList {
+				// ignore the update for current function here, it will be updated later.
+				if fn.Metadata.Name != fnName {
+					fn.Spec.Package.PackageRef.ResourceVersion = pkgMetadata.ResourceVersion
+					_, err := client.FunctionUpdate(&fn)
+					checkErr(err, "update function")
+				}
 			}
 		}
-	}
 
-	// update function spec with new package metadata
-	function.Spec.Package.PackageRef = fission.PackageRef{
-		Namespace:       pkgMetadata.Namespace,
-		Name:            pkgMetadata.Name,
-		ResourceVersion: pkgMetadata.ResourceVersion,
+		// update function spec with new package metadata
+		function.Spec.Package.PackageRef = fission.PackageRef{
+			Namespace:       pkgMetadata.Namespace,
+			Name:            pkgMetadata.Name,
+			ResourceVersion: pkgMetadata.ResourceVersion,
+		}
 	}
 
 	function.Spec.Resources = getResourceReq(c, function.Spec.Resources)
@@ -476,6 +609,30 @@ func fnUpdate(c *cli.Context) error {
 		function.Spec.InvokeStrategy.ExecutionStrategy.TargetCPUPercent = getTargetCPU(c)
 	}
 
+	if c.IsSet("idletimeout") {
+		function.Spec.InvokeStrategy.ExecutionStrategy.IdleTimeout = getIdleTimeout(c)
+	}
+
+	if c.IsSet("targetconcurrency") {
+		function.Spec.InvokeStrategy.ExecutionStrategy.TargetConcurrency = getTargetConcurrency(c)
+	}
+
+	if c.IsSet("targetlatency") {
+		function.Spec.InvokeStrategy.ExecutionStrategy.TargetLatency = getTargetLatency(c)
+	}
+
+	if c.IsSet("targetrps") {
+		function.Spec.InvokeStrategy.ExecutionStrategy.TargetRPS = getTargetRPS(c)
+	}
+
+	if c.IsSet("retries") {
+		function.Spec.InvokeStrategy.ExecutionStrategy.Retries = getRetries(c)
+	}
+
+	if c.IsSet("activedeadline") {
+		function.Spec.InvokeStrategy.ExecutionStrategy.ActiveDeadline = getActiveDeadline(c)
+	}
+
 	if c.IsSet("minscale") {
 		minscale := c.Int("minscale")
 		maxscale := c.Int("maxscale")
@@ -508,8 +665,10 @@ func fnUpdate(c *cli.Context) error {
 			fnExecutor = fission.ExecutorTypePoolmgr
 		case fission.ExecutorTypeNewdeploy:
 			fnExecutor = fission.ExecutorTypeNewdeploy
+		case fission.ExecutorTypeJob:
+			fnExecutor = fission.ExecutorTypeJob
 		default:
-			fatal("Executor type must be one of 'poolmgr' or 'newdeploy', defaults to 'poolmgr'")
+			fatal("Executor type must be one of 'poolmgr', 'newdeploy' or 'job', defaults to 'poolmgr'")
 		}
 		if (c.IsSet("mincpu") || c.IsSet("maxcpu") || c.IsSet("minmemory") || c.IsSet("maxmemory")) &&
 			fnExecutor == fission.ExecutorTypePoolmgr {
@@ -553,11 +712,11 @@ func fnList(c *cli.Context) error {
 
 	w := tabwriter.NewWriter(os.Stdout, 0, 0, 1, ' ', 0)
 
-	fmt.Fprintf(w, "%v\t%v\t%v\t%v\t%v\t%v\t%v\t%v\t%v\t%v\t%v\n", "NAME", "UID", "ENV", "EXECUTORTYPE", "MINSCALE", "MAXSCALE", "MINCPU", "MAXCPU", "MINMEMORY", "MAXMEMORY", "TARGETCPU")
+	fmt.Fprintf(w, "%v\t%v\t%v\t%v\t%v\t%v\t%v\t%v\t%v\t%v\t%v\t%v\t%v\n", "NAME", "UID", "ENV", "EXECUTORTYPE", "MINSCALE", "MAXSCALE", "MINCPU", "MAXCPU", "MINMEMORY", "MAXMEMORY", "TARGETCPU", "STATUS", "READY")
 	for _, f := range fns {
 		mincpu := f.Spec.Resources.Requests.Cpu
 		mincpu().Value()
-		fmt.Fprintf(w, "%v\t%v\t%v\t%v\t%v\t%v\t%v\t%v\t%v\t%v\t%v\n",
+		fmt.Fprintf(w, "%v\t%v\t%v\t%v\t%v\t%v\t%v\t%v\t%v\t%v\t%v\t%v\t%v\n",
 			f.Metadata.Name, f.Metadata.UID, f.Spec.Environment.Name,
 			f.Spec.InvokeStrategy.ExecutionStrategy.ExecutorType,
 			f.Spec.InvokeStrategy.ExecutionStrategy.MinScale,
@@ -566,7 +725,8 @@ func fnList(c *cli.Context) error {
 			f.Spec.Resources.Limits.Cpu().String(),
 			f.Spec.Resources.Requests.Memory().String(),
 			f.Spec.Resources.Limits.Memory().String(),
-			f.Spec.InvokeStrategy.ExecutionStrategy.TargetCPUPercent)
+			f.Spec.InvokeStrategy.ExecutionStrategy.TargetCPUPercent,
+			functionHealth(&f.Status), f.Status.ReadyReplicas)
 	}
 	w.Flush()
 
@@ -654,23 +814,26 @@ func fnLogs(c *cli.Context) error {
 	}
 }
 
-func fnTest(c *cli.Context) error {
-	fnName := c.String("name")
-	if len(fnName) == 0 {
-		fatal("Need function name to be specified with --name")
-	}
-
+// getRouterURL returns the host and port of the fission router, port
+// forwarding to it unless FISSION_ROUTER is set.
+func getRouterURL() string {
 	routerURL := os.Getenv("FISSION_ROUTER")
 	if len(routerURL) == 0 {
 		// Portforward to the fission router
 		localRouterPort := setupPortForward(getKubeConfigPath(),
 			getFissionNamespace(), "application=fission-router")
-		routerURL = "127.0.0.1:" + localRouterPort
-	} else {
-		routerURL = strings.TrimPrefix(routerURL, "http://")
+		return "127.0.0.1:" + localRouterPort
 	}
+	return strings.TrimPrefix(routerURL, "http://")
+}
 
-	url := fmt.Sprintf("http://%s/fission-function/%s", routerURL, fnName)
+func fnTest(c *cli.Context) error {
+	fnName := c.String("name")
+	if len(fnName) == 0 {
+		fatal("Need function name to be specified with --name")
+	}
+
+	url := fmt.Sprintf("http://%s/fission-function/%s", getRouterURL(), fnName)
 
 	resp := httpRequest(c.String("method"), url, c.String("body"), c.StringSlice("header"))
 	if resp.StatusCode < 400 {
@@ -692,3 +855,101 @@ func fnTest(c *cli.Context) error {
 
 	return nil
 }
+
+func fnInvocation(c *cli.Context) error {
+	id := c.String("id")
+	if len(id) == 0 {
+		fatal("Need an invocation id, use --id")
+	}
+
+	url := fmt.Sprintf("http://%s%s", getRouterURL(), fission.UrlForInvocation(id))
+	resp, err := http.Get(url)
+	checkErr(err, "get invocation")
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	checkErr(err, "read invocation")
+	if resp.StatusCode != 200 {
+		fatal(fmt.Sprintf("Error getting invocation %v: %v %v", id, resp.StatusCode, string(body)))
+	}
+
+	inv := &jobmgr.Invocation{}
+	err = json.Unmarshal(body, inv)
+	checkErr(err, "parse invocation")
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 1, ' ', 0)
+	fmt.Fprintf(w, "%v\t%v\n", "ID:", inv.ID)
+	fmt.Fprintf(w, "%v\t%v\n", "FUNCTION:", inv.Function.Name)
+	fmt.Fprintf(w, "%v\t%v\n", "STATUS:", inv.Status)
+	fmt.Fprintf(w, "%v\t%v\n", "ATTEMPTS:", inv.Attempts)
+	fmt.Fprintf(w, "%v\t%v\n", "STARTED:", inv.StartTime.Format(time.RFC3339))
+	if inv.CompletionTime != nil {
+		fmt.Fprintf(w, "%v\t%v\n", "COMPLETED:", inv.CompletionTime.Format(time.RFC3339))
+	}
+	if inv.StatusCode != 0 {
+		fmt.Fprintf(w, "%v\t%v\n", "STATUS CODE:", inv.StatusCode)
+	}
+	if inv.ExitCode != nil {
+		fmt.Fprintf(w, "%v\t%v\n", "EXIT CODE:", *inv.ExitCode)
+	}
+	if len(inv.Message) > 0 {
+		fmt.Fprintf(w, "%v\t%v\n", "MESSAGE:", inv.Message)
+	}
+	w.Flush()
+
+	if len(inv.Response) > 0 {
+		fmt.Printf("\nResponse:\n%v\n", inv.Response)
+	}
+	if len(inv.Logs) > 0 {
+		fmt.Printf("\nLogs:\n%v\n", inv.Logs)
+	}
+	return nil
+}
+
+func fnInstances(c *cli.Context) error {
+	client := getClient(c.GlobalString("server"))
+
+	fnName := c.String("name")
+	if len(fnName) == 0 {
+		fatal("Need name of function, use --name")
+	}
+	if c.Bool("evict") && c.Bool("specialize") {
+		fatal("Use only one of --evict and --specialize")
+	}
+
+	m := &metav1.ObjectMeta{
+		Name:      fnName,
+		Namespace: metav1.NamespaceDefault,
+	}
+
+	if c.Bool("specialize") {
+		_, err := client.FunctionSpecialize(m)
+		checkErr(err, fmt.Sprintf("specialize function '%v'", fnName))
+	}
+
+	var instances []executorClient.FunctionServiceInfo
+	var err error
+	if c.Bool("evict") {
+		instances, err = client.FunctionServiceEvict(m)
+		checkErr(err, fmt.Sprintf("evict function '%v'", fnName))
+		fmt.Printf("evicted %v instances of function '%v'\n", len(instances), fnName)
+	} else {
+		instances, err = client.FunctionServiceList(m)
+		checkErr(err, fmt.Sprintf("list instances of function '%v'", fnName))
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 1, ' ', 0)
+	fmt.Fprintf(w, "%v\t%v\t%v\t%v\t%v\t%v\t%v\n", "VERSION", "EXECUTOR", "ADDRESS", "AGE", "LAST ACCESS", "CONCURRENCY", "OBJECTS")
+	for _, instance := range instances {
+		objects := make([]string, 0, len(instance.KubernetesObjects))
+		for _, obj := range instance.KubernetesObjects {
+			objects = append(objects, fmt.Sprintf("%v/%v", strings.ToLower(obj.Kind), obj.Name))
+		}
+		fmt.Fprintf(w, "%v\t%v\t%v\t%v\t%v\t%v\t%v\n",
+			instance.Function.ResourceVersion, instance.Executor, instance.Address,
+			time.Since(instance.Ctime)/time.Second*time.Second, time.Since(instance.Atime)/time.Second*time.Second,
+			instance.Concurrency, strings.Join(objects, ","))
+	}
+	w.Flush()
+	return nil
+}
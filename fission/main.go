@@ -116,9 +116,15 @@ func main() {
 	maxCpu := cli.StringFlag{Name: "maxcpu", Usage: "Maximum CPU to be assigned to pod (In millicore, minimum 1)"}
 	minMem := cli.StringFlag{Name: "minmemory", Usage: "Minimum memory to be assigned to pod (In megabyte)"}
 	maxMem := cli.StringFlag{Name: "maxmemory", Usage: "Maximum memory to be assigned to pod (In megabyte)"}
-	minScale := cli.StringFlag{Name: "minscale", Usage: "Minimum number of pods (Uses resource inputs to configure HPA)"}
+	minScale := cli.StringFlag{Name: "minscale", Usage: "Minimum number of pods (Uses resource inputs to configure HPA; for poolmgr, the pods kept specialized)"}
 	maxScale := cli.StringFlag{Name: "maxscale", Usage: "Maximum number of pods (Uses resource inputs to configure HPA)"}
 	targetcpu := cli.IntFlag{Name: "targetcpu", Value: 80, Usage: "Target average CPU usage percentage across pods for scaling"}
+	idleTimeout := cli.IntFlag{Name: "idletimeout", Usage: "Seconds a specialized pod may stay unused before it's reaped, or a newdeploy function before it's scaled to zero (0 uses the default)"}
+	targetConcurrency := cli.IntFlag{Name: "targetconcurrency", Usage: "Concurrent requests per pod above which poolmgr specializes another pod, up to maxscale (0 uses the default); for newdeploy, scales the deployment on concurrent requests"}
+	targetRPS := cli.IntFlag{Name: "targetrps", Usage: "Requests per second per pod to scale a newdeploy function's deployment to, instead of on CPU"}
+	targetLatency := cli.IntFlag{Name: "targetlatency", Usage: "Request latency in milliseconds above which poolmgr specializes another pod, up to maxscale (0 disables)"}
+	retries := cli.IntFlag{Name: "retries", Usage: "Number of times a failed invocation of a job function is retried"}
+	activeDeadline := cli.IntFlag{Name: "activedeadline", Usage: "Seconds after which a running invocation of a job function is failed (0 means the default)"}
 
 	// functions
 	fnNameFlag := cli.StringFlag{Name: "name", Usage: "function name"}
@@ -141,17 +147,24 @@ func main() {
 	fnCfgMapnsFlag := cli.StringFlag{Name: "configmapNamespace", Usage: "namespace of configmap"}
 	fnLogCountFlag := cli.StringFlag{Name: "recordcount", Usage: "the n most recent log records"}
 	fnForceFlag := cli.BoolFlag{Name: "force", Usage: "Force update a package even if it is used by one or more functions"}
-	fnExecutorTypeFlag := cli.StringFlag{Name: "executortype", Value: "poolmgr", Usage: "Executor type for execution; one of 'poolmgr', 'newdeploy'"}
+	fnInvocationIdFlag := cli.StringFlag{Name: "id", Usage: "Invocation id, as returned when a job function is invoked"}
+	fnEvictFlag := cli.BoolFlag{Name: "evict", Usage: "Delete the function's instances; the next request creates a new one"}
+	fnSpecializeFlag := cli.BoolFlag{Name: "specialize", Usage: "Create an instance of the function, even if it has one already"}
+	fnImageFlag := cli.StringFlag{Name: "image", Usage: "container image serving the function over HTTP, run by newdeploy instead of a package"}
+	fnPortFlag := cli.IntFlag{Name: "port", Usage: "port the function's --image serves HTTP on (default 8888)"}
+	fnExecutorTypeFlag := cli.StringFlag{Name: "executortype", Value: "poolmgr", Usage: "Executor type for execution; one of 'poolmgr', 'newdeploy', 'job'"}
 
 	fnSubcommands := []cli.Command{
-		{Name: "create", Usage: "Create new function (and optionally, an HTTP route to it)", Flags: []cli.Flag{fnNameFlag, fnEnvNameFlag, specSaveFlag, fnCodeFlag, fnSrcArchiveFlag, fnDeployArchiveFlag, fnEntryPointFlag, fnBuildCmdFlag, fnPkgNameFlag, htUrlFlag, htMethodFlag, minCpu, maxCpu, minMem, maxMem, minScale, maxScale, fnExecutorTypeFlag, targetcpu, fnCfgMapFlag, fnSecretFlag, fnSecretnsFlag, fnCfgMapnsFlag}, Action: fnCreate},
+		{Name: "create", Usage: "Create new function (and optionally, an HTTP route to it)", Flags: []cli.Flag{fnNameFlag, fnEnvNameFlag, specSaveFlag, fnCodeFlag, fnSrcArchiveFlag, fnDeployArchiveFlag, fnEntryPointFlag, fnBuildCmdFlag, fnPkgNameFlag, htUrlFlag, htMethodFlag, minCpu, maxCpu, minMem, maxMem, minScale, maxScale, fnExecutorTypeFlag, targetcpu, idleTimeout, targetConcurrency, targetLatency, targetRPS, retries, activeDeadline, fnCfgMapFlag, fnSecretFlag, fnSecretnsFlag, fnCfgMapnsFlag, fnImageFlag, fnPortFlag}, Action: fnCreate},
 		{Name: "get", Usage: "Get function source code", Flags: []cli.Flag{fnNameFlag}, Action: fnGet},
 		{Name: "getmeta", Usage: "Get function metadata", Flags: []cli.Flag{fnNameFlag}, Action: fnGetMeta},
-		{Name: "update", Usage: "Update function source code", Flags: []cli.Flag{fnNameFlag, fnEnvNameFlag, fnCodeFlag, fnSrcArchiveFlag, fnDeployArchiveFlag, fnEntryPointFlag, fnPkgNameFlag, fnBuildCmdFlag, fnForceFlag, minCpu, maxCpu, minMem, maxMem, minScale, maxScale, fnExecutorTypeFlag, targetcpu}, Action: fnUpdate},
+		{Name: "update", Usage: "Update function source code", Flags: []cli.Flag{fnNameFlag, fnEnvNameFlag, fnCodeFlag, fnSrcArchiveFlag, fnDeployArchiveFlag, fnEntryPointFlag, fnPkgNameFlag, fnBuildCmdFlag, fnForceFlag, minCpu, maxCpu, minMem, maxMem, minScale, maxScale, fnExecutorTypeFlag, targetcpu, idleTimeout, targetConcurrency, targetLatency, targetRPS, retries, activeDeadline, fnImageFlag, fnPortFlag}, Action: fnUpdate},
 		{Name: "delete", Usage: "Delete function", Flags: []cli.Flag{fnNameFlag}, Action: fnDelete},
 		{Name: "list", Usage: "List all functions", Flags: []cli.Flag{}, Action: fnList},
 		{Name: "logs", Usage: "Display function logs", Flags: []cli.Flag{fnNameFlag, fnPodFlag, fnFollowFlag, fnDetailFlag, fnLogDBTypeFlag, fnLogCountFlag}, Action: fnLogs},
 		{Name: "test", Usage: "Test a function", Flags: []cli.Flag{fnNameFlag, fnEnvNameFlag, fnCodeFlag, fnSrcArchiveFlag, htMethodFlag, fnBodyFlag, fnHeaderFlag}, Action: fnTest},
+		{Name: "invocation", Usage: "Show the outcome of an invocation of a job function", Flags: []cli.Flag{fnInvocationIdFlag}, Action: fnInvocation},
+		{Name: "instances", Usage: "List the instances of a function cached by the executor", Flags: []cli.Flag{fnNameFlag, fnEvictFlag, fnSpecializeFlag}, Action: fnInstances},
 	}
 
 	// httptriggers
@@ -195,18 +208,40 @@ func main() {
 	// environments
 	envNameFlag := cli.StringFlag{Name: "name", Usage: "Environment name"}
 	envPoolsizeFlag := cli.IntFlag{Name: "poolsize", Value: 3, Usage: "Size of the pool"}
+	envMinPoolsizeFlag := cli.IntFlag{Name: "minpoolsize", Usage: "Minimum size of the pool when autoscaling"}
+	envMaxPoolsizeFlag := cli.IntFlag{Name: "maxpoolsize", Usage: "Maximum size of the pool when autoscaling; 0 disables pool autoscaling"}
+	envMaxSpecializedPodsFlag := cli.IntFlag{Name: "maxspecializedpods", Usage: "Maximum number of pods specialized for functions at once; the least recently used is evicted beyond it (0 means no limit)"}
+	envPodReadyTimeoutFlag := cli.IntFlag{Name: "podreadytimeout", Usage: "Seconds to wait for a pod of the environment to be ready (0 means the default)"}
+	envSpecializationTimeoutFlag := cli.IntFlag{Name: "specializationtimeout", Usage: "Seconds to spend loading a function into a pod of the environment (0 means the default)"}
 	envImageFlag := cli.StringFlag{Name: "image", Usage: "Environment image URL"}
 	envBuilderImageFlag := cli.StringFlag{Name: "builder", Usage: "Environment builder image URL (optional)"}
 	envBuildCmdFlag := cli.StringFlag{Name: "buildcmd", Usage: "Build command for environment builder to build source package (optional)"}
 	envExternalNetworkFlag := cli.BoolFlag{Name: "externalnetwork", Usage: "Allow environment access external network when istio feature enabled (optional, defaults to false)"}
 	envTerminationGracePeriodFlag := cli.Int64Flag{Name: "graceperiod, period", Value: 360, Usage: "The grace time (in seconds) for pod to perform connection draining before termination (optional)"}
 	envVersionFlag := cli.IntFlag{Name: "version", Value: 1, Usage: "Environment API version (1 means v1 interface)"}
+	envDrainFlag := cli.BoolFlag{Name: "drain", Usage: "Delete the idle pods of the pool; they're replaced with new ones"}
+	envRecreateFlag := cli.BoolFlag{Name: "recreate", Usage: "Replace the idle pods of the pool with new ones, in a rolling update"}
 	envSubcommands := []cli.Command{
-		{Name: "create", Aliases: []string{"add"}, Usage: "Add an environment", Flags: []cli.Flag{envNameFlag, envPoolsizeFlag, envImageFlag, envBuilderImageFlag, envBuildCmdFlag, minCpu, maxCpu, minMem, maxMem, envVersionFlag, envExternalNetworkFlag, envTerminationGracePeriodFlag, specSaveFlag}, Action: envCreate},
+		{Name: "create", Aliases: []string{"add"}, Usage: "Add an environment", Flags: []cli.Flag{envNameFlag, envPoolsizeFlag, envMinPoolsizeFlag, envMaxPoolsizeFlag, envMaxSpecializedPodsFlag, envPodReadyTimeoutFlag, envSpecializationTimeoutFlag, idleTimeout, envImageFlag, envBuilderImageFlag, envBuildCmdFlag, minCpu, maxCpu, minMem, maxMem, envVersionFlag, envExternalNetworkFlag, envTerminationGracePeriodFlag, specSaveFlag}, Action: envCreate},
 		{Name: "get", Usage: "Get environment details", Flags: []cli.Flag{envNameFlag}, Action: envGet},
-		{Name: "update", Usage: "Update environment", Flags: []cli.Flag{envNameFlag, envPoolsizeFlag, envImageFlag, envBuilderImageFlag, envBuildCmdFlag, minCpu, maxCpu, minMem, maxMem, envExternalNetworkFlag, envTerminationGracePeriodFlag}, Action: envUpdate},
+		{Name: "update", Usage: "Update environment", Flags: []cli.Flag{envNameFlag, envPoolsizeFlag, envMinPoolsizeFlag, envMaxPoolsizeFlag, envMaxSpecializedPodsFlag, envPodReadyTimeoutFlag, envSpecializationTimeoutFlag, idleTimeout, envImageFlag, envBuilderImageFlag, envBuildCmdFlag, minCpu, maxCpu, minMem, maxMem, envExternalNetworkFlag, envTerminationGracePeriodFlag}, Action: envUpdate},
 		{Name: "delete", Usage: "Delete environment", Flags: []cli.Flag{envNameFlag}, Action: envDelete},
 		{Name: "list", Usage: "List all environments", Flags: []cli.Flag{}, Action: envList},
+		{Name: "pool", Usage: "Show the pool of generic pods of an environment", Flags: []cli.Flag{envNameFlag, envDrainFlag, envRecreateFlag}, Action: envPool},
+	}
+
+	// quotas
+	quotaNameFlag := cli.StringFlag{Name: "name", Value: "default", Usage: "Quota name"}
+	quotaMaxFunctionsFlag := cli.IntFlag{Name: "maxfunctions", Usage: "Maximum number of functions in the namespace (0 means no limit)"}
+	quotaMaxScaleFlag := cli.IntFlag{Name: "maxscale", Usage: "Maximum total MaxScale of the functions in the namespace (0 means no limit)"}
+	quotaMaxSpecializedPodsFlag := cli.IntFlag{Name: "maxspecializedpods", Usage: "Maximum number of pods specialized for functions in the namespace at once (0 means no limit)"}
+	quotaMaxPackageBytesFlag := cli.Int64Flag{Name: "maxpackagebytes", Usage: "Maximum total size in bytes of the archives Fission stores for packages in the namespace (0 means no limit)"}
+	quotaSubcommands := []cli.Command{
+		{Name: "create", Aliases: []string{"add"}, Usage: "Create a quota", Flags: []cli.Flag{quotaNameFlag, quotaMaxFunctionsFlag, quotaMaxScaleFlag, quotaMaxSpecializedPodsFlag, quotaMaxPackageBytesFlag}, Action: quotaCreate},
+		{Name: "get", Usage: "Get a quota and the current usage", Flags: []cli.Flag{quotaNameFlag}, Action: quotaGet},
+		{Name: "update", Usage: "Update a quota", Flags: []cli.Flag{quotaNameFlag, quotaMaxFunctionsFlag, quotaMaxScaleFlag, quotaMaxSpecializedPodsFlag, quotaMaxPackageBytesFlag}, Action: quotaUpdate},
+		{Name: "delete", Usage: "Delete a quota", Flags: []cli.Flag{quotaNameFlag}, Action: quotaDelete},
+		{Name: "list", Usage: "List quotas and their usage", Flags: []cli.Flag{}, Action: quotaList},
 	}
 
 	// watches
@@ -276,6 +311,7 @@ func main() {
 		{Name: "timetrigger", Aliases: []string{"tt", "timer"}, Usage: "Manage Time triggers (timers) for functions", Subcommands: ttSubcommands},
 		{Name: "mqtrigger", Aliases: []string{"mqt", "messagequeue"}, Usage: "Manage message queue triggers for functions", Subcommands: mqtSubcommands},
 		{Name: "environment", Aliases: []string{"env"}, Usage: "Manage environments", Subcommands: envSubcommands},
+		{Name: "quota", Usage: "Manage namespace quotas", Subcommands: quotaSubcommands},
 		{Name: "watch", Aliases: []string{"w"}, Usage: "Manage watches", Subcommands: wSubCommands},
 		{Name: "package", Aliases: []string{"pkg"}, Usage: "Manage packages", Subcommands: pkgSubCommands},
 		{Name: "spec", Aliases: []string{"specs"}, Usage: "Manage a declarative app specification", Subcommands: specSubCommands},
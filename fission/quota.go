@@ -0,0 +1,171 @@
+/*
+Copyright 2018 The Fission Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/urfave/cli"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/fission/fission"
+	"github.com/fission/fission/crd"
+)
+
+func quotaCreate(c *cli.Context) error {
+	client := getClient(c.GlobalString("server"))
+
+	quotaName := c.String("name")
+	if len(quotaName) == 0 {
+		fatal("Need a name, use --name.")
+	}
+
+	q := &crd.FissionQuota{
+		Metadata: metav1.ObjectMeta{
+			Name:      quotaName,
+			Namespace: metav1.NamespaceDefault,
+		},
+		Spec: fission.FissionQuotaSpec{
+			MaxFunctions:       c.Int("maxfunctions"),
+			MaxScale:           c.Int("maxscale"),
+			MaxSpecializedPods: c.Int("maxspecializedpods"),
+			MaxPackageBytes:    c.Int64("maxpackagebytes"),
+		},
+	}
+
+	_, err := client.FissionQuotaCreate(q)
+	checkErr(err, "create quota")
+
+	fmt.Printf("quota '%v' created\n", quotaName)
+	return nil
+}
+
+// quotaLimit formats a limit of a quota, where 0 means no limit.
+func quotaLimit(limit int64) string {
+	if limit <= 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%v", limit)
+}
+
+func quotaGet(c *cli.Context) error {
+	client := getClient(c.GlobalString("server"))
+
+	quotaName := c.String("name")
+	if len(quotaName) == 0 {
+		fatal("Need a name, use --name.")
+	}
+
+	q, err := client.FissionQuotaGet(&metav1.ObjectMeta{
+		Name:      quotaName,
+		Namespace: metav1.NamespaceDefault,
+	})
+	checkErr(err, "get quota")
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 1, ' ', 0)
+	fmt.Fprintf(w, "%v\t%v\t%v\n", "LIMIT", "USED", "MAX")
+	fmt.Fprintf(w, "%v\t%v\t%v\n", "functions", q.Status.Functions, quotaLimit(int64(q.Spec.MaxFunctions)))
+	fmt.Fprintf(w, "%v\t%v\t%v\n", "scale", q.Status.Scale, quotaLimit(int64(q.Spec.MaxScale)))
+	fmt.Fprintf(w, "%v\t%v\t%v\n", "specializedpods", q.Status.SpecializedPods, quotaLimit(int64(q.Spec.MaxSpecializedPods)))
+	fmt.Fprintf(w, "%v\t%v\t%v\n", "packagebytes", q.Status.PackageBytes, quotaLimit(q.Spec.MaxPackageBytes))
+	w.Flush()
+
+	return nil
+}
+
+func quotaUpdate(c *cli.Context) error {
+	client := getClient(c.GlobalString("server"))
+
+	quotaName := c.String("name")
+	if len(quotaName) == 0 {
+		fatal("Need a name, use --name.")
+	}
+
+	q, err := client.FissionQuotaGet(&metav1.ObjectMeta{
+		Name:      quotaName,
+		Namespace: metav1.NamespaceDefault,
+	})
+	checkErr(err, "get quota")
+
+	updated := false
+	if c.IsSet("maxfunctions") {
+		q.Spec.MaxFunctions = c.Int("maxfunctions")
+		updated = true
+	}
+	if c.IsSet("maxscale") {
+		q.Spec.MaxScale = c.Int("maxscale")
+		updated = true
+	}
+	if c.IsSet("maxspecializedpods") {
+		q.Spec.MaxSpecializedPods = c.Int("maxspecializedpods")
+		updated = true
+	}
+	if c.IsSet("maxpackagebytes") {
+		q.Spec.MaxPackageBytes = c.Int64("maxpackagebytes")
+		updated = true
+	}
+
+	if !updated {
+		fatal("Nothing to update. Use --maxfunctions, --maxscale, --maxspecializedpods or --maxpackagebytes.")
+	}
+
+	_, err = client.FissionQuotaUpdate(q)
+	checkErr(err, "update quota")
+
+	fmt.Printf("quota '%v' updated\n", quotaName)
+	return nil
+}
+
+func quotaDelete(c *cli.Context) error {
+	client := getClient(c.GlobalString("server"))
+
+	quotaName := c.String("name")
+	if len(quotaName) == 0 {
+		fatal("Need a name, use --name.")
+	}
+
+	err := client.FissionQuotaDelete(&metav1.ObjectMeta{
+		Name:      quotaName,
+		Namespace: metav1.NamespaceDefault,
+	})
+	checkErr(err, "delete quota")
+
+	fmt.Printf("quota '%v' deleted\n", quotaName)
+	return nil
+}
+
+func quotaList(c *cli.Context) error {
+	client := getClient(c.GlobalString("server"))
+
+	quotas, err := client.FissionQuotaList()
+	checkErr(err, "list quotas")
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 1, ' ', 0)
+	fmt.Fprintf(w, "%v\t%v\t%v\t%v\t%v\n", "NAME", "FUNCTIONS", "SCALE", "SPECIALIZEDPODS", "PACKAGEBYTES")
+	for _, q := range quotas {
+		fmt.Fprintf(w, "%v\t%v/%v\t%v/%v\t%v/%v\t%v/%v\n", q.Metadata.Name,
+			q.Status.Functions, quotaLimit(int64(q.Spec.MaxFunctions)),
+			q.Status.Scale, quotaLimit(int64(q.Spec.MaxScale)),
+			q.Status.SpecializedPods, quotaLimit(int64(q.Spec.MaxSpecializedPods)),
+			q.Status.PackageBytes, quotaLimit(q.Spec.MaxPackageBytes))
+	}
+	w.Flush()
+
+	return nil
+}
@@ -301,6 +301,11 @@ func (fr *FissionResources) validate() error {
 	for _, f := range fr.functions {
 		functions[mapKey(&f.Metadata)] = false
 
+		// functions with an image have no package
+		if len(f.Spec.Image) > 0 {
+			continue
+		}
+
 		// check package ref from function
 		pkgMeta := &metav1.ObjectMeta{
 			Name:      f.Spec.Package.PackageRef.Name,
@@ -856,6 +861,9 @@ func applyResources(fclient *client.Client, specDir string, fr *FissionResources
 	// of the package. This ensures that various caches can invalidate themselves
 	// when the package changes.
 	for i, f := range fr.functions {
+		if len(f.Spec.Image) > 0 {
+			continue
+		}
 		k := mapKey(&metav1.ObjectMeta{
 			Namespace: f.Spec.Package.PackageRef.Namespace,
 			Name:      f.Spec.Package.PackageRef.Name,
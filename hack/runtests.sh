@@ -15,6 +15,7 @@ else
     $K delete messagequeuetriggers --all
     $K delete packages --all
     $K delete timetriggers --all
+    $K delete fissionquotas --all
     fi
 fi
 
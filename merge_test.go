@@ -118,3 +118,77 @@ func TestMergeContainerSpecsNil(t *testing.T) {
 	result := MergeContainerSpecs()
 	assert.EqualValues(t, expected, result)
 }
+
+func TestMergePodSpecs(t *testing.T) {
+	spec := apiv1.PodSpec{
+		Volumes: []apiv1.Volume{
+			{Name: SharedVolumeUserfunc},
+		},
+		Containers: []apiv1.Container{
+			{
+				Name:  "runtime",
+				Image: "runtimeImage",
+				VolumeMounts: []apiv1.VolumeMount{
+					{Name: SharedVolumeUserfunc, MountPath: "/userfunc"},
+				},
+			},
+			{
+				Name:  "fetcher",
+				Image: "fetcherImage",
+			},
+		},
+		ServiceAccountName: "fission-fetcher",
+	}
+	functionOverlay := &apiv1.PodSpec{
+		ServiceAccountName: "function-sa",
+		NodeSelector: map[string]string{
+			"disk": "ssd",
+		},
+	}
+	envOverlay := &apiv1.PodSpec{
+		Volumes: []apiv1.Volume{
+			{Name: SharedVolumeUserfunc},
+			{Name: "data"},
+		},
+		Containers: []apiv1.Container{
+			{
+				Name:  "runtime",
+				Image: "shouldNotBeThere",
+				VolumeMounts: []apiv1.VolumeMount{
+					{Name: "data", MountPath: "/data"},
+				},
+			},
+			{
+				Name:  "sidecar",
+				Image: "sidecarImage",
+			},
+		},
+		Tolerations: []apiv1.Toleration{
+			{Key: "dedicated", Operator: apiv1.TolerationOpEqual, Value: "python"},
+		},
+		ServiceAccountName: "env-sa",
+	}
+
+	result := MergePodSpecs(&spec, functionOverlay, envOverlay)
+
+	assert.Equal(t, []apiv1.Volume{{Name: SharedVolumeUserfunc}, {Name: "data"}}, result.Volumes)
+	assert.Equal(t, 3, len(result.Containers))
+	assert.Equal(t, "runtimeImage", result.Containers[0].Image)
+	assert.Equal(t, []apiv1.VolumeMount{
+		{Name: SharedVolumeUserfunc, MountPath: "/userfunc"},
+		{Name: "data", MountPath: "/data"},
+	}, result.Containers[0].VolumeMounts)
+	assert.Equal(t, "fetcher", result.Containers[1].Name)
+	assert.Equal(t, "sidecar", result.Containers[2].Name)
+	assert.Equal(t, envOverlay.Tolerations, result.Tolerations)
+	assert.Equal(t, functionOverlay.NodeSelector, result.NodeSelector)
+	assert.Equal(t, "function-sa", result.ServiceAccountName)
+}
+
+func TestMergePodSpecsNil(t *testing.T) {
+	spec := apiv1.PodSpec{
+		ServiceAccountName: "fission-fetcher",
+	}
+	result := MergePodSpecs(&spec, nil)
+	assert.Equal(t, spec, result)
+}
@@ -0,0 +1,57 @@
+/*
+Copyright 2018 The Fission Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fission
+
+import (
+	"fmt"
+	"strings"
+)
+
+// FunctionScale returns how many instances the function may have, as
+// counted against its namespace's MaxScale: every function may have at
+// least one.
+func FunctionScale(spec *FunctionSpec) int {
+	if spec.InvokeStrategy.ExecutionStrategy.MaxScale > 1 {
+		return spec.InvokeStrategy.ExecutionStrategy.MaxScale
+	}
+	return 1
+}
+
+// Check returns an error naming the limits of the quota that usage
+// exceeds, or nil.  Only the limits that usage grew past from previous are
+// checked, so that a namespace over a lowered limit may still shrink, and
+// change what doesn't count against it.
+func (spec *FissionQuotaSpec) Check(previous *FissionQuotaStatus, usage *FissionQuotaStatus) error {
+	exceeded := make([]string, 0)
+	if spec.MaxFunctions > 0 && usage.Functions > spec.MaxFunctions && usage.Functions > previous.Functions {
+		exceeded = append(exceeded, fmt.Sprintf("functions %v > %v", usage.Functions, spec.MaxFunctions))
+	}
+	if spec.MaxScale > 0 && usage.Scale > spec.MaxScale && usage.Scale > previous.Scale {
+		exceeded = append(exceeded, fmt.Sprintf("scale %v > %v", usage.Scale, spec.MaxScale))
+	}
+	if spec.MaxSpecializedPods > 0 && usage.SpecializedPods > spec.MaxSpecializedPods &&
+		usage.SpecializedPods > previous.SpecializedPods {
+		exceeded = append(exceeded, fmt.Sprintf("specialized pods %v > %v", usage.SpecializedPods, spec.MaxSpecializedPods))
+	}
+	if spec.MaxPackageBytes > 0 && usage.PackageBytes > spec.MaxPackageBytes && usage.PackageBytes > previous.PackageBytes {
+		exceeded = append(exceeded, fmt.Sprintf("package bytes %v > %v", usage.PackageBytes, spec.MaxPackageBytes))
+	}
+	if len(exceeded) == 0 {
+		return nil
+	}
+	return MakeError(ErrorNoSpace, fmt.Sprintf("quota exceeded: %v", strings.Join(exceeded, ", ")))
+}
@@ -0,0 +1,51 @@
+/*
+Copyright 2018 The Fission Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fission
+
+import (
+	"testing"
+)
+
+func TestQuotaCheck(t *testing.T) {
+	spec := &FissionQuotaSpec{MaxFunctions: 2, MaxScale: 10}
+
+	previous := &FissionQuotaStatus{Functions: 2, Scale: 4}
+	if err := spec.Check(previous, &FissionQuotaStatus{Functions: 2, Scale: 10}); err != nil {
+		t.Errorf("expected usage at the limits to be allowed, got %v", err)
+	}
+	err := spec.Check(previous, &FissionQuotaStatus{Functions: 3, Scale: 4})
+	if fe, ok := err.(Error); !ok || fe.Code != ErrorNoSpace {
+		t.Errorf("expected a function beyond the limit to be rejected, got %v", err)
+	}
+	if err := spec.Check(previous, &FissionQuotaStatus{Functions: 2, Scale: 11}); err == nil {
+		t.Errorf("expected scale beyond the limit to be rejected")
+	}
+
+	// a namespace over a lowered limit may still shrink, and change
+	// what doesn't count against the limit
+	previous = &FissionQuotaStatus{Functions: 5, Scale: 4}
+	if err := spec.Check(previous, &FissionQuotaStatus{Functions: 4, Scale: 4}); err != nil {
+		t.Errorf("expected a namespace over its limit to shrink, got %v", err)
+	}
+	if err := spec.Check(previous, &FissionQuotaStatus{Functions: 5, Scale: 6}); err != nil {
+		t.Errorf("expected unrelated usage to change, got %v", err)
+	}
+
+	if err := (&FissionQuotaSpec{}).Check(previous, &FissionQuotaStatus{Functions: 100, PackageBytes: 1 << 30}); err != nil {
+		t.Errorf("expected a quota without limits to allow anything, got %v", err)
+	}
+}
@@ -17,7 +17,9 @@ limitations under the License.
 package router
 
 import (
+	"encoding/json"
 	"fmt"
+	"io/ioutil"
 	"log"
 	"net"
 	"net/http"
@@ -30,12 +32,14 @@ import (
 
 	"github.com/fission/fission"
 	executorClient "github.com/fission/fission/executor/client"
+	"github.com/fission/fission/executor/jobmgr"
 )
 
 type functionHandler struct {
-	fmap     *functionServiceMap
-	executor *executorClient.Client
-	function *metav1.ObjectMeta
+	fmap         *functionServiceMap
+	executor     *executorClient.Client
+	function     *metav1.ObjectMeta
+	executorType fission.ExecutorType
 }
 
 // A layer on top of http.DefaultTransport, with retries.
@@ -49,25 +53,29 @@ type RetryingRoundTripper struct {
 // from router's cache or from executor if router entry is stale.
 //
 // It first checks if the service address for this function came from router's cache.
-// If it didn't, it makes a request to executor to get a new service for function. If that succeeds, it adds the address
-// to it's cache and makes a request to that address with transport.RoundTrip call.
+// If it didn't, it makes a request to executor to get the services for function. If that succeeds, it adds the addresses
+// to it's cache and makes a request to one of them with transport.RoundTrip call.
 // Initial requests to new k8s services sometimes seem to fail, but retries work. So, it retries with an exponential
 // back-off for maxRetries times.
 //
 // Else if it came from the cache, it makes a transport.RoundTrip with that cached address. If the response received is
-// a network dial error (which means that the pod doesn't exist anymore), it removes the cache entry and makes a request
-// to executor to get a new service for function. It then retries transport.RoundTrip with the new address.
+// a network dial error (which means that the pod doesn't exist anymore), it removes the address from the cache and
+// retries with another cached address of the function, or else makes a request to executor to get a new service for
+// function. It then retries transport.RoundTrip with the new address.
+//
+// A function may have several instances; each request goes to the one with the fewest requests in flight, and the
+// executor is told about the concurrency and latency seen, so that it can specialize more instances.
 //
 // At any point in time, if the response received from transport.RoundTrip is other than dial network error, it is
 // relayed as-is to the user, without any retries.
 //
 // While this RoundTripper handles the case where a previously cached address of the function pod isn't valid anymore
 // (probably because the pod got deleted somehow), by making a request to executor to get a new service for this function,
-// it doesn't handle a case where a newly specialized pod gets deleted just after the GetServiceForFunction succeeds.
+// it doesn't handle a case where a newly specialized pod gets deleted just after the GetServicesForFunction succeeds.
 // In such a case, the RoundTripper will retry requests against the new address and give up after maxRetries.
 // However, the subsequent http call for this function will ensure the cache is invalidated.
 //
-// If GetServiceForFunction returns an error or if RoundTripper exits with an error, it get's translated into 502
+// If GetServicesForFunction returns an error or if RoundTripper exits with an error, it get's translated into 502
 // inside ServeHttp function of the reverseProxy.
 // Earlier, GetServiceForFunction was called inside handler function and fission explicitly set http status code to 500
 // if it returned an error.
@@ -88,10 +96,11 @@ func (roundTripper RetryingRoundTripper) RoundTrip(req *http.Request) (resp *htt
 
 	for i := 0; i < roundTripper.maxRetries-1; i++ {
 		if needExecutor {
-			log.Printf("Calling getServiceForFunction for function: %s", roundTripper.funcHandler.function.Name)
+			log.Printf("Calling getServicesForFunction for function: %s", roundTripper.funcHandler.function.Name)
+			serviceUrl = nil
 
 			// send a request to executor to specialize a new pod
-			service, err := roundTripper.funcHandler.executor.GetServiceForFunction(
+			services, err := roundTripper.funcHandler.executor.GetServicesForFunction(
 				roundTripper.funcHandler.function)
 			if err != nil {
 				// We might want a specific error code or header for fission failures as opposed to
@@ -99,14 +108,25 @@ func (roundTripper RetryingRoundTripper) RoundTrip(req *http.Request) (resp *htt
 				return nil, err
 			}
 
-			// parse the address into url
-			serviceUrl, err = url.Parse(fmt.Sprintf("http://%v", service))
-			if err != nil {
-				return nil, err
+			// parse the addresses into urls, and add them in router's cache
+			for _, service := range services {
+				u, err := url.Parse(fmt.Sprintf("http://%v", service))
+				if err != nil {
+					return nil, err
+				}
+				roundTripper.funcHandler.fmap.assign(roundTripper.funcHandler.function, u)
+				if serviceUrl == nil {
+					serviceUrl = u
+				}
+			}
+			if serviceUrl == nil {
+				return nil, fmt.Errorf("no service address for function %v", roundTripper.funcHandler.function.Name)
 			}
 
-			// add the address in router's cache
-			roundTripper.funcHandler.fmap.assign(roundTripper.funcHandler.function, serviceUrl)
+			// spread requests across the function's instances
+			if u, err := roundTripper.funcHandler.fmap.lookup(roundTripper.funcHandler.function); err == nil {
+				serviceUrl = u
+			}
 
 			// flag denotes that service was not obtained from cache, instead, created just now by executor
 			serviceUrlFromExecutor = true
@@ -117,12 +137,15 @@ func (roundTripper RetryingRoundTripper) RoundTrip(req *http.Request) (resp *htt
 		req.URL.Scheme = serviceUrl.Scheme
 		req.URL.Host = serviceUrl.Host
 
-		// To keep the function run container simple, it
-		// doesn't do any routing.  In the future if we have
-		// multiple functions per container, we could use the
-		// function metadata here.
+		// A container running a single function serves it at
+		// "/"; containers running several functions serve each
+		// at its own path, which is part of the service url.
 		// leave the query string intact (req.URL.RawQuery)
-		req.URL.Path = "/"
+		if len(serviceUrl.Path) > 0 {
+			req.URL.Path = serviceUrl.Path
+		} else {
+			req.URL.Path = "/"
+		}
 
 		// Overwrite request host with internal host,
 		// or request will be blocked in some situations
@@ -136,11 +159,15 @@ func (roundTripper RetryingRoundTripper) RoundTrip(req *http.Request) (resp *htt
 		}).DialContext
 
 		// forward the request to the function service
+		concurrency := roundTripper.funcHandler.fmap.requestStarted(roundTripper.funcHandler.function, serviceUrl)
+		startTime := time.Now()
 		resp, err = transport.RoundTrip(req)
+		latency := time.Since(startTime)
+		roundTripper.funcHandler.fmap.requestFinished(roundTripper.funcHandler.function, serviceUrl)
 		if err == nil {
 			// if transport.RoundTrip succeeds and it was a cached entry, then tapService
 			if !serviceUrlFromExecutor {
-				go roundTripper.funcHandler.tapService(serviceUrl)
+				go roundTripper.funcHandler.tapService(serviceUrl, concurrency, latency)
 			}
 			// return response back to user
 			return resp, nil
@@ -163,12 +190,13 @@ func (roundTripper RetryingRoundTripper) RoundTrip(req *http.Request) (resp *htt
 		} else {
 			// if transport.RoundTrip returns a network dial error and serviceUrl was from cache,
 			// it means, the entry in router cache is stale, so invalidate it.
-			// also set needExecutor to true so a new service can be requested for function.
-			log.Printf("request to %s errored out. removing function : %s from router's cache "+
-				"and requesting a new service for function",
+			// try another cached instance of the function, if any, or else set needExecutor
+			// to true so a new service can be requested for function.
+			log.Printf("request to %s errored out. removing it from router's cache of function : %s",
 				req.URL.Host, roundTripper.funcHandler.function.Name)
-			roundTripper.funcHandler.fmap.remove(roundTripper.funcHandler.function)
-			needExecutor = true
+			roundTripper.funcHandler.fmap.invalidate(roundTripper.funcHandler.function, serviceUrl)
+			serviceUrl, err = roundTripper.funcHandler.fmap.lookup(roundTripper.funcHandler.function)
+			needExecutor = err != nil
 		}
 	}
 
@@ -176,11 +204,11 @@ func (roundTripper RetryingRoundTripper) RoundTrip(req *http.Request) (resp *htt
 	return http.DefaultTransport.RoundTrip(req)
 }
 
-func (fh *functionHandler) tapService(serviceUrl *url.URL) {
+func (fh *functionHandler) tapService(serviceUrl *url.URL, concurrency int, latency time.Duration) {
 	if fh.executor == nil {
 		return
 	}
-	fh.executor.TapService(serviceUrl)
+	fh.executor.TapService(serviceUrl, concurrency, latency)
 }
 
 func (fh *functionHandler) handler(responseWriter http.ResponseWriter, request *http.Request) {
@@ -193,6 +221,11 @@ func (fh *functionHandler) handler(responseWriter http.ResponseWriter, request *
 	// System Params
 	MetadataToHeaders(HEADERS_FISSION_FUNCTION_PREFIX, fh.function, request)
 
+	if fh.executorType == fission.ExecutorTypeJob {
+		fh.invokeJob(responseWriter, request)
+		return
+	}
+
 	// TODO: As an optimization we may want to cache proxies too -- this might get us
 	// connection reuse and possibly better performance
 	director := func(req *http.Request) {
@@ -213,3 +246,37 @@ func (fh *functionHandler) handler(responseWriter http.ResponseWriter, request *
 
 	proxy.ServeHTTP(responseWriter, request)
 }
+
+// invokeJob starts an invocation of a job function with the request, and
+// responds with its record right away rather than waiting for the function
+// to finish.  The record's id locates the outcome of the invocation.
+func (fh *functionHandler) invokeJob(responseWriter http.ResponseWriter, request *http.Request) {
+	body, err := ioutil.ReadAll(request.Body)
+	if err != nil {
+		http.Error(responseWriter, "Failed to read request", 500)
+		return
+	}
+
+	inv, err := fh.executor.InvokeJob(&jobmgr.InvocationRequest{
+		Function: *fh.function,
+		Method:   request.Method,
+		Header:   request.Header,
+		Body:     body,
+	})
+	if err != nil {
+		code, msg := fission.GetHTTPError(err)
+		log.Printf("Error invoking job function %v: %v", fh.function.Name, msg)
+		http.Error(responseWriter, msg, code)
+		return
+	}
+
+	resp, err := json.Marshal(inv)
+	if err != nil {
+		http.Error(responseWriter, "Failed to encode response", 500)
+		return
+	}
+	responseWriter.Header().Set("Content-Type", "application/json")
+	responseWriter.Header().Set("Location", fission.UrlForInvocation(inv.ID))
+	responseWriter.WriteHeader(http.StatusAccepted)
+	responseWriter.Write(resp)
+}
@@ -18,6 +18,7 @@ package router
 
 import (
 	"fmt"
+	"log"
 	"time"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -51,6 +52,7 @@ type (
 	resolveResult struct {
 		resolveResultType
 		functionMetadata *metav1.ObjectMeta
+		executorType     fission.ExecutorType
 	}
 
 	// namespacedFunctionReference is just a function reference plus a
@@ -146,6 +148,7 @@ func (frr *functionReferenceResolver) resolveByName(namespace, name string) (*re
 	rr := resolveResult{
 		resolveResultType: resolveResultSingleFunction,
 		functionMetadata:  &f.Metadata,
+		executorType:      f.Spec.InvokeStrategy.ExecutionStrategy.ExecutorType,
 	}
 	return &rr, nil
 }
@@ -158,12 +161,23 @@ func (frr *functionReferenceResolver) delete(namespace string, fr *fission.Funct
 	return frr.refCache.Delete(nfr)
 }
 
-func (frr *functionReferenceResolver) copy() map[namespacedFunctionReference]resolveResult {
-	cache := make(map[namespacedFunctionReference]resolveResult)
-	for k, v := range frr.refCache.Copy() {
+// deleteOutdated drops the cached results of the function references that
+// resolve to a previous version of the function.
+func (frr *functionReferenceResolver) deleteOutdated(fn *crd.Function) {
+	outdated := make([]namespacedFunctionReference, 0)
+	frr.refCache.Range(func(k interface{}, v interface{}) bool {
 		key := k.(namespacedFunctionReference)
-		val := v.(resolveResult)
-		cache[key] = val
+		rr := v.(resolveResult)
+		if key.functionReference.Name == fn.Metadata.Name &&
+			rr.functionMetadata.ResourceVersion != fn.Metadata.ResourceVersion {
+			outdated = append(outdated, key)
+		}
+		return true
+	})
+	for _, key := range outdated {
+		err := frr.delete(key.namespace, &key.functionReference)
+		if err != nil {
+			log.Printf("Error deleting functionReferenceResolver cache: %v", err)
+		}
 	}
-	return cache
 }
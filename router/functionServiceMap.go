@@ -19,16 +19,31 @@ package router
 import (
 	"log"
 	"net/url"
+	"sync"
 	"time"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
+	"github.com/fission/fission"
 	"github.com/fission/fission/cache"
 )
 
 type (
 	functionServiceMap struct {
-		cache *cache.Cache // map[metadataKey]*url.URL
+		cache *cache.Cache // map[metadataKey]*functionServices
+	}
+
+	// functionServices holds the instances of a function's service, and
+	// the requests in flight to each, so that requests can be spread
+	// across them.
+	functionServices struct {
+		lock      sync.Mutex
+		instances []*serviceInstance
+		next      int // rotates the preferred instance among equally loaded ones
+	}
+	serviceInstance struct {
+		url      *url.URL
+		inFlight int
 	}
 
 	// metav1.ObjectMeta is not hashable, so we make a hashable copy
@@ -54,29 +69,142 @@ func keyFromMetadata(m *metav1.ObjectMeta) *metadataKey {
 	}
 }
 
-func (fmap *functionServiceMap) lookup(f *metav1.ObjectMeta) (*url.URL, error) {
+func (fmap *functionServiceMap) getServices(f *metav1.ObjectMeta) (*functionServices, error) {
 	mk := keyFromMetadata(f)
 	item, err := fmap.cache.Get(*mk)
 	if err != nil {
 		return nil, err
 	}
-	u := item.(*url.URL)
-	return u, nil
+	return item.(*functionServices), nil
+}
+
+// lookup returns the url of the function's instance with the fewest
+// requests in flight.
+func (fmap *functionServiceMap) lookup(f *metav1.ObjectMeta) (*url.URL, error) {
+	fs, err := fmap.getServices(f)
+	if err != nil {
+		return nil, err
+	}
+
+	fs.lock.Lock()
+	defer fs.lock.Unlock()
+
+	n := len(fs.instances)
+	if n == 0 {
+		return nil, fission.MakeError(fission.ErrorNotFound, "function has no service instances")
+	}
+	var chosen *serviceInstance
+	for i := 0; i < n; i++ {
+		instance := fs.instances[(fs.next+i)%n]
+		if chosen == nil || instance.inFlight < chosen.inFlight {
+			chosen = instance
+		}
+	}
+	fs.next = (fs.next + 1) % n
+	return chosen.url, nil
 }
 
+// assign adds an instance of the function's service.
 func (fmap *functionServiceMap) assign(f *metav1.ObjectMeta, serviceUrl *url.URL) {
 	mk := keyFromMetadata(f)
-	err, old := fmap.cache.Set(*mk, serviceUrl)
-	if err != nil {
-		if *serviceUrl == *(old.(*url.URL)) {
+	err, old := fmap.cache.Set(*mk, &functionServices{
+		instances: []*serviceInstance{{url: serviceUrl}},
+	})
+	if err == nil {
+		return
+	}
+
+	fs := old.(*functionServices)
+	fs.lock.Lock()
+	defer fs.lock.Unlock()
+	for _, instance := range fs.instances {
+		if *instance.url == *serviceUrl {
 			return
 		}
-		log.Printf("error caching service url for function with a different value: %v", err)
-		// ignore error
 	}
+	log.Printf("Adding service url %v for function %v", serviceUrl, f.Name)
+	fs.instances = append(fs.instances, &serviceInstance{url: serviceUrl})
 }
 
 func (fmap *functionServiceMap) remove(f *metav1.ObjectMeta) error {
 	mk := keyFromMetadata(f)
 	return fmap.cache.Delete(*mk)
 }
+
+// invalidate removes a cached service url of a function, leaving its
+// other instances alone.
+func (fmap *functionServiceMap) invalidate(f *metav1.ObjectMeta, serviceUrl *url.URL) {
+	fs, err := fmap.getServices(f)
+	if err != nil {
+		return
+	}
+
+	fs.lock.Lock()
+	defer fs.lock.Unlock()
+	for i, instance := range fs.instances {
+		if *instance.url == *serviceUrl {
+			log.Printf("Invalidating cached service url %v of function %v", serviceUrl, f.Name)
+			fs.instances = append(fs.instances[:i], fs.instances[i+1:]...)
+			break
+		}
+	}
+	if len(fs.instances) == 0 {
+		fmap.remove(f)
+	}
+}
+
+// retain drops every cached service url that isn't in keep.
+func (fmap *functionServiceMap) retain(keep map[metadataKey]map[url.URL]bool) {
+	stale := make(map[metadataKey][]*url.URL)
+	fmap.cache.Range(func(key interface{}, value interface{}) bool {
+		mk := key.(metadataKey)
+		fs := value.(*functionServices)
+		fs.lock.Lock()
+		for _, instance := range fs.instances {
+			if !keep[mk][*instance.url] {
+				stale[mk] = append(stale[mk], instance.url)
+			}
+		}
+		fs.lock.Unlock()
+		return true
+	})
+
+	for mk, urls := range stale {
+		f := &metav1.ObjectMeta{
+			Name:            mk.Name,
+			Namespace:       mk.Namespace,
+			ResourceVersion: mk.ResourceVersion,
+		}
+		for _, serviceUrl := range urls {
+			fmap.invalidate(f, serviceUrl)
+		}
+	}
+}
+
+// requestStarted records a request in flight to an instance, and returns
+// how many requests are in flight to it.
+func (fmap *functionServiceMap) requestStarted(f *metav1.ObjectMeta, serviceUrl *url.URL) int {
+	return fmap.addInFlight(f, serviceUrl, 1)
+}
+
+func (fmap *functionServiceMap) requestFinished(f *metav1.ObjectMeta, serviceUrl *url.URL) {
+	fmap.addInFlight(f, serviceUrl, -1)
+}
+
+func (fmap *functionServiceMap) addInFlight(f *metav1.ObjectMeta, serviceUrl *url.URL, delta int) int {
+	fs, err := fmap.getServices(f)
+	if err != nil {
+		return 1
+	}
+
+	fs.lock.Lock()
+	defer fs.lock.Unlock()
+	for _, instance := range fs.instances {
+		if *instance.url == *serviceUrl {
+			instance.inFlight += delta
+			return instance.inFlight
+		}
+	}
+	// the instance was invalidated meanwhile
+	return 1
+}
@@ -47,3 +47,93 @@ func TestFunctionServiceMap(t *testing.T) {
 		t.Errorf("No error on missing entry")
 	}
 }
+
+func TestFunctionServiceMapInvalidate(t *testing.T) {
+	m := makeFunctionServiceMap(0)
+	fn := &metav1.ObjectMeta{Name: "foo", Namespace: metav1.NamespaceDefault}
+	oldUrl, _ := url.Parse("http://10.0.0.1:8888")
+	newUrl, _ := url.Parse("http://10.0.0.2:8888")
+
+	m.assign(fn, newUrl)
+
+	// invalidating a stale address must keep the current one
+	m.invalidate(fn, oldUrl)
+	v, err := m.lookup(fn)
+	if err != nil || *v != *newUrl {
+		t.Errorf("Expected %#v to be kept, got %#v (err: %v)", newUrl, v, err)
+	}
+
+	m.invalidate(fn, newUrl)
+	_, err = m.lookup(fn)
+	if err == nil {
+		t.Errorf("Found invalidated entry")
+	}
+}
+
+func TestFunctionServiceMapBalance(t *testing.T) {
+	m := makeFunctionServiceMap(0)
+	fn := &metav1.ObjectMeta{Name: "foo", Namespace: metav1.NamespaceDefault}
+	url1, _ := url.Parse("http://10.0.0.1:8888")
+	url2, _ := url.Parse("http://10.0.0.2:8888")
+
+	m.assign(fn, url1)
+	m.assign(fn, url2)
+	m.assign(fn, url1)
+
+	// equally loaded instances are used in turn
+	seen := make(map[url.URL]bool)
+	for i := 0; i < 2; i++ {
+		v, err := m.lookup(fn)
+		if err != nil {
+			t.Fatalf("Lookup error: %v", err)
+		}
+		seen[*v] = true
+	}
+	if len(seen) != 2 {
+		t.Errorf("Expected both instances to be used, got %v", seen)
+	}
+
+	// the least loaded instance is preferred
+	if n := m.requestStarted(fn, url1); n != 1 {
+		t.Errorf("Expected 1 request in flight, got %v", n)
+	}
+	for i := 0; i < 2; i++ {
+		v, err := m.lookup(fn)
+		if err != nil || *v != *url2 {
+			t.Errorf("Expected %#v, got %#v (err: %v)", url2, v, err)
+		}
+	}
+	m.requestFinished(fn, url1)
+
+	m.invalidate(fn, url2)
+	v, err := m.lookup(fn)
+	if err != nil || *v != *url1 {
+		t.Errorf("Expected %#v to be kept, got %#v (err: %v)", url1, v, err)
+	}
+}
+
+func TestFunctionServiceMapRetain(t *testing.T) {
+	m := makeFunctionServiceMap(0)
+	fn := &metav1.ObjectMeta{Name: "foo", Namespace: metav1.NamespaceDefault}
+	staleUrl, _ := url.Parse("http://10.0.0.1:8888")
+	liveUrl, _ := url.Parse("http://10.0.0.2:8888")
+
+	m.assign(fn, staleUrl)
+	m.assign(fn, liveUrl)
+
+	m.retain(map[metadataKey]map[url.URL]bool{
+		*keyFromMetadata(fn): {*liveUrl: true},
+	})
+	for i := 0; i < 2; i++ {
+		v, err := m.lookup(fn)
+		if err != nil || *v != *liveUrl {
+			t.Errorf("Expected only %#v to be kept, got %#v (err: %v)", liveUrl, v, err)
+		}
+	}
+
+	m.retain(nil)
+	_, err := m.lookup(fn)
+	if err == nil {
+		t.Errorf("Found entry missing from resync")
+	}
+}
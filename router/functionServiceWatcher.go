@@ -0,0 +1,87 @@
+/*
+Copyright 2018 The Fission Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package router
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/url"
+	"time"
+
+	executorClient "github.com/fission/fission/executor/client"
+	"github.com/fission/fission/executor/fscache"
+)
+
+// watchFunctionServices keeps the function service map in sync with the
+// executor. Newly specialized function services are added to the map before
+// the router asks for them, and services removed by the executor (e.g. pods
+// reaped for being idle) are dropped right away instead of waiting for a
+// dial failure or for the cache entry to expire.
+func (fmap *functionServiceMap) watchFunctionServices(ctx context.Context, executor *executorClient.Client) {
+	for {
+		// the stream starts over with every function service the
+		// executor has, so anything not in it went away while we
+		// weren't listening
+		seen := make(map[metadataKey]map[url.URL]bool)
+		synced := false
+		err := executor.WatchFunctionServices(ctx, func(event *fscache.FuncSvcEvent) {
+			if event.Type == fscache.FuncSvcSynced {
+				fmap.retain(seen)
+				synced = true
+				return
+			}
+			serviceUrl := fmap.handleFuncSvcEvent(event)
+			if !synced && serviceUrl != nil {
+				mk := *keyFromMetadata(&event.Function)
+				if seen[mk] == nil {
+					seen[mk] = make(map[url.URL]bool)
+				}
+				seen[mk][*serviceUrl] = true
+			}
+		})
+
+		select {
+		case <-ctx.Done():
+			return
+		default:
+		}
+
+		// The executor might be restarting; cache expiry and dial
+		// failures still protect us from stale entries meanwhile.
+		log.Printf("Function service event stream from executor ended (%v), reconnecting", err)
+		time.Sleep(5 * time.Second)
+	}
+}
+
+// handleFuncSvcEvent applies an ADDED or DELETED event to the map and
+// returns the url of the event's function service.
+func (fmap *functionServiceMap) handleFuncSvcEvent(event *fscache.FuncSvcEvent) *url.URL {
+	serviceUrl, err := url.Parse(fmt.Sprintf("http://%v", event.Address))
+	if err != nil {
+		log.Printf("Error parsing address of function service event: %v", err)
+		return nil
+	}
+
+	switch event.Type {
+	case fscache.FuncSvcAdded:
+		fmap.assign(&event.Function, serviceUrl)
+	case fscache.FuncSvcDeleted:
+		fmap.invalidate(&event.Function, serviceUrl)
+	}
+	return serviceUrl
+}
@@ -18,6 +18,7 @@ package router
 
 import (
 	"context"
+	"encoding/json"
 	"log"
 	"net/http"
 	"time"
@@ -93,6 +94,24 @@ func routerHealthHandler(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusOK)
 }
 
+// invocationHandler responds with the record of an invocation of a job
+// function, as kept by the executor.
+func (ts *HTTPTriggerSet) invocationHandler(w http.ResponseWriter, r *http.Request) {
+	inv, err := ts.executor.GetInvocation(mux.Vars(r)["id"])
+	if err != nil {
+		code, msg := fission.GetHTTPError(err)
+		http.Error(w, msg, code)
+		return
+	}
+	resp, err := json.Marshal(inv)
+	if err != nil {
+		http.Error(w, "Failed to encode response", 500)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(resp)
+}
+
 func (ts *HTTPTriggerSet) getRouter() *mux.Router {
 	muxRouter := mux.NewRouter()
 
@@ -117,9 +136,10 @@ func (ts *HTTPTriggerSet) getRouter() *mux.Router {
 		}
 
 		fh := &functionHandler{
-			fmap:     ts.functionServiceMap,
-			function: rr.functionMetadata,
-			executor: ts.executor,
+			fmap:         ts.functionServiceMap,
+			function:     rr.functionMetadata,
+			executorType: rr.executorType,
+			executor:     ts.executor,
 		}
 
 		ht := muxRouter.HandleFunc(trigger.Spec.RelativeURL, fh.handler)
@@ -147,13 +167,17 @@ func (ts *HTTPTriggerSet) getRouter() *mux.Router {
 	for _, function := range ts.functions {
 		m := function.Metadata
 		fh := &functionHandler{
-			fmap:     ts.functionServiceMap,
-			function: &m,
-			executor: ts.executor,
+			fmap:         ts.functionServiceMap,
+			function:     &m,
+			executorType: function.Spec.InvokeStrategy.ExecutionStrategy.ExecutorType,
+			executor:     ts.executor,
 		}
 		muxRouter.HandleFunc(fission.UrlForFunction(function.Metadata.Name), fh.handler)
 	}
 
+	// Records of the invocations of job functions.
+	muxRouter.HandleFunc(fission.UrlForInvocation("{id}"), ts.invocationHandler).Methods("GET")
+
 	// Healthz endpoint for the router.
 	muxRouter.HandleFunc("/router-healthz", routerHealthHandler).Methods("GET")
 
@@ -196,16 +220,7 @@ func (ts *HTTPTriggerSet) initFunctionController() (k8sCache.Store, k8sCache.Con
 			UpdateFunc: func(oldObj interface{}, newObj interface{}) {
 				fn := newObj.(*crd.Function)
 				// update resolver function reference cache
-				for key, rr := range ts.resolver.copy() {
-					if key.functionReference.Name == fn.Metadata.Name &&
-						rr.functionMetadata.ResourceVersion != fn.Metadata.ResourceVersion {
-						err := ts.resolver.delete(key.namespace, &key.functionReference)
-						if err != nil {
-							log.Printf("Error deleting functionReferenceResolver cache: %v", err)
-						}
-						break
-					}
-				}
+				ts.resolver.deleteOutdated(fn)
 				ts.syncTriggers()
 			},
 		})
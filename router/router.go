@@ -89,12 +89,15 @@ func Start(port int, executorUrl string) {
 
 	restClient := fissionClient.GetCrdClient()
 
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
 	executor := executorClient.MakeClient(executorUrl)
+	go fmap.watchFunctionServices(ctx, executor)
+
 	triggers, _, fnStore := makeHTTPTriggerSet(fmap, fissionClient, executor, restClient)
 	resolver := makeFunctionReferenceResolver(fnStore)
 
 	log.Printf("Starting router at port %v\n", port)
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
 	serve(ctx, port, triggers, resolver)
 }
@@ -0,0 +1,67 @@
+/*
+Copyright 2018 The Fission Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fission
+
+import (
+	apiv1 "k8s.io/client-go/pkg/api/v1"
+)
+
+// SetCondition sets the condition of the given type, replacing any
+// previous one.
+func (fs *FunctionStatus) SetCondition(conditionType FunctionConditionType, status apiv1.ConditionStatus, reason string, message string) {
+	condition := FunctionCondition{
+		Type:    conditionType,
+		Status:  status,
+		Reason:  reason,
+		Message: message,
+	}
+	for i := range fs.Conditions {
+		if fs.Conditions[i].Type == conditionType {
+			fs.Conditions[i] = condition
+			return
+		}
+	}
+	fs.Conditions = append(fs.Conditions, condition)
+}
+
+// GetCondition returns the condition of the given type, or nil if it
+// isn't set.
+func (fs *FunctionStatus) GetCondition(conditionType FunctionConditionType) *FunctionCondition {
+	for i := range fs.Conditions {
+		if fs.Conditions[i].Type == conditionType {
+			return &fs.Conditions[i]
+		}
+	}
+	return nil
+}
+
+// IsKnown returns true if the executor reported the status.
+func (fs *FunctionStatus) IsKnown() bool {
+	return len(fs.Conditions) > 0
+}
+
+// IsHealthy returns false if a condition of the function is false for any
+// other reason than the function being idle, or if the last attempt to
+// run it failed and none of its pods are ready.
+func (fs *FunctionStatus) IsHealthy() bool {
+	for _, c := range fs.Conditions {
+		if c.Status == apiv1.ConditionFalse && c.Reason != FunctionReasonIdle {
+			return false
+		}
+	}
+	return len(fs.LastError) == 0 || fs.ReadyReplicas > 0
+}
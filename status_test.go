@@ -0,0 +1,56 @@
+/*
+Copyright 2018 The Fission Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fission
+
+import (
+	"testing"
+
+	apiv1 "k8s.io/client-go/pkg/api/v1"
+)
+
+func TestFunctionStatus(t *testing.T) {
+	status := &FunctionStatus{}
+	if status.IsKnown() {
+		t.Errorf("expected a status without conditions to be unknown")
+	}
+
+	status.SetCondition(FunctionPackageReady, apiv1.ConditionTrue, "", "")
+	status.SetCondition(FunctionSpecialized, apiv1.ConditionFalse, FunctionReasonIdle, "")
+	if !status.IsKnown() || !status.IsHealthy() {
+		t.Errorf("expected an idle function to be healthy")
+	}
+
+	status.LastError = "failed to specialize"
+	if status.IsHealthy() {
+		t.Errorf("expected a function that failed with no ready pods to be unhealthy")
+	}
+	status.ReadyReplicas = 1
+	if !status.IsHealthy() {
+		t.Errorf("expected a function with ready pods to be healthy")
+	}
+
+	status.SetCondition(FunctionPackageReady, apiv1.ConditionFalse, "BuildFailed", "")
+	if len(status.Conditions) != 2 {
+		t.Errorf("expected the condition to be replaced, got %v conditions", len(status.Conditions))
+	}
+	if c := status.GetCondition(FunctionPackageReady); c == nil || c.Reason != "BuildFailed" {
+		t.Errorf("expected the package condition to have failed, got %+v", c)
+	}
+	if status.IsHealthy() {
+		t.Errorf("expected a function whose package failed to build to be unhealthy")
+	}
+}
@@ -28,20 +28,26 @@ import (
 	"net/url"
 	"os"
 	"strings"
+	"sync"
 
+	"github.com/fission/fission"
 	"github.com/fission/fission/storagesvc"
 )
 
 type (
 	Client struct {
 		url string
+
+		sizeLock sync.Mutex
+		sizes    map[string]int64 // archive sizes by id; archives don't change
 	}
 )
 
 // Client creates a storage service client.
 func MakeClient(url string) *Client {
 	return &Client{
-		url: strings.TrimSuffix(url, "/") + "/v1",
+		url:   strings.TrimSuffix(url, "/") + "/v1",
+		sizes: make(map[string]int64),
 	}
 }
 
@@ -176,3 +182,73 @@ func (c *Client) Delete(id string) error {
 
 	return nil
 }
+
+// GetSize returns the size in bytes of the file identified by ID, or 0 if
+// there's no such file.
+func (c *Client) GetSize(id string) (int64, error) {
+	c.sizeLock.Lock()
+	size, ok := c.sizes[id]
+	c.sizeLock.Unlock()
+	if ok {
+		return size, nil
+	}
+
+	resp, err := http.Head(c.GetUrl(id))
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return 0, nil
+	}
+	if resp.StatusCode != http.StatusOK || resp.ContentLength < 0 {
+		return 0, errors.New(fmt.Sprintf("HTTP error %v", resp.StatusCode))
+	}
+
+	c.sizeLock.Lock()
+	c.sizes[id] = resp.ContentLength
+	c.sizeLock.Unlock()
+	return resp.ContentLength, nil
+}
+
+// ArchiveID returns the ID of the file an archive URL made by GetUrl
+// points to, and whether it's such a URL.  The host isn't checked, since
+// clients reach the storage service by different names.
+func ArchiveID(archiveUrl string) (string, bool) {
+	u, err := url.Parse(archiveUrl)
+	if err != nil || !strings.HasSuffix(u.Path, "/v1/archive") {
+		return "", false
+	}
+	id := u.Query().Get("id")
+	return id, len(id) > 0
+}
+
+// ArchiveBytes returns how many bytes the archive takes in Fission's
+// storage, as counted against its namespace's FissionQuota: the size of a
+// literal, or of a file held by the storage service.  Archives at other
+// URLs aren't stored by Fission, and don't count.
+func (c *Client) ArchiveBytes(archive *fission.Archive) (int64, error) {
+	if archive.Type == fission.ArchiveTypeLiteral || len(archive.Literal) > 0 {
+		return int64(len(archive.Literal)), nil
+	}
+	id, ok := ArchiveID(archive.URL)
+	if !ok {
+		return 0, nil
+	}
+	return c.GetSize(id)
+}
+
+// PackageBytes returns how many bytes the source and deployment archives
+// of the package take in Fission's storage.
+func (c *Client) PackageBytes(spec *fission.PackageSpec) (int64, error) {
+	source, err := c.ArchiveBytes(&spec.Source)
+	if err != nil {
+		return 0, err
+	}
+	deployment, err := c.ArchiveBytes(&spec.Deployment)
+	if err != nil {
+		return 0, err
+	}
+	return source + deployment, nil
+}
@@ -86,6 +86,13 @@ func TestStorageService(t *testing.T) {
 		log.Panic("Contents don't match")
 	}
 
+	// get its size
+	size, err := client.GetSize(fileId)
+	panicIf(err)
+	if size != 10*1024 {
+		log.Panicf("Expected size %v, got %v", 10*1024, size)
+	}
+
 	// delete uploaded file
 	err = client.Delete(fileId)
 	panicIf(err)
@@ -99,3 +106,14 @@ func TestStorageService(t *testing.T) {
 	// cleanup /tmp
 	os.RemoveAll(fmt.Sprintf("/tmp/%v", testId))
 }
+
+func TestArchiveID(t *testing.T) {
+	client := MakeClient("http://storagesvc.fission/")
+	id, ok := ArchiveID(client.GetUrl("abc"))
+	if !ok || id != "abc" {
+		t.Errorf("expected the id of a storage service url, got %v", id)
+	}
+	if _, ok := ArchiveID("https://example.com/archive.zip"); ok {
+		t.Errorf("expected other urls not to be storage service urls")
+	}
+}
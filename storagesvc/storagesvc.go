@@ -149,6 +149,30 @@ func (ss *StorageService) downloadHandler(w http.ResponseWriter, r *http.Request
 	}
 }
 
+// sizeHandler responds to HEAD requests with the size of the file, without
+// its contents.
+func (ss *StorageService) sizeHandler(w http.ResponseWriter, r *http.Request) {
+	// get id from request
+	fileId, err := ss.getIdFromRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), 400)
+		return
+	}
+
+	size, err := ss.storageClient.getFileSize(fileId)
+	if err != nil {
+		log.WithError(err).Errorf("Error getting size of item id '%v'", fileId)
+		if err == ErrNotFound {
+			w.WriteHeader(404)
+		} else {
+			w.WriteHeader(500)
+		}
+		return
+	}
+	w.Header().Set("Content-Length", strconv.FormatInt(size, 10))
+	w.WriteHeader(http.StatusOK)
+}
+
 func (ss *StorageService) healthHandler(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusOK)
 }
@@ -165,6 +189,7 @@ func (ss *StorageService) Start(port int) {
 	r.HandleFunc("/v1/archive", ss.uploadHandler).Methods("POST")
 	r.HandleFunc("/v1/archive", ss.downloadHandler).Methods("GET")
 	r.HandleFunc("/v1/archive", ss.deleteHandler).Methods("DELETE")
+	r.HandleFunc("/v1/archive", ss.sizeHandler).Methods("HEAD")
 	r.HandleFunc("/healthz", ss.healthHandler).Methods("GET")
 
 	address := fmt.Sprintf(":%v", port)
@@ -150,6 +150,19 @@ func (client *StowClient) copyFileToStream(fileId string, w io.Writer) error {
 	return nil
 }
 
+// getFileSize returns the size of the file in bytes
+func (client *StowClient) getFileSize(fileId string) (int64, error) {
+	item, err := client.container.Item(fileId)
+	if err != nil {
+		if err == stow.ErrNotFound {
+			return 0, ErrNotFound
+		} else {
+			return 0, ErrRetrievingItem
+		}
+	}
+	return item.Size()
+}
+
 // removeFileByID deletes the file from storage
 func (client *StowClient) removeFileByID(itemID string) error {
 	return client.container.RemoveItem(itemID)
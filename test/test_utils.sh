@@ -360,6 +360,7 @@ dump_fission_crds() {
     dump_fission_crd messagequeuetriggers.fission.io
     dump_fission_crd packages.fission.io
     dump_fission_crd timetriggers.fission.io
+    dump_fission_crd fissionquotas.fission.io
 }
 
 dump_env_pods() {
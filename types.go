@@ -91,6 +91,43 @@ type (
 		BuildLog    string      `json:"buildlog,omitempty"` // output of the build (errors etc)
 	}
 
+	// FunctionStatus is the state of a function and its instances, as
+	// reported by the executor. It isn't stored with the function: any
+	// update of a function changes its resource version, and with it the
+	// version of the function that gets specialized. The executor keeps
+	// it in a record of its own, and the controller fills it in when
+	// functions are read.
+	FunctionStatus struct {
+		Conditions    []FunctionCondition `json:"conditions,omitempty"`
+		ReadyReplicas int                 `json:"readyReplicas"`           // ready pods serving the current version of the function
+		LastError     string              `json:"lastError,omitempty"`     // last failure to specialize or deploy the function
+		LastErrorTime *metav1.Time        `json:"lastErrorTime,omitempty"` // when LastError happened
+		Failures      int                 `json:"failures,omitempty"`      // consecutive failures of the current version
+		RetryTime     *metav1.Time        `json:"retryTime,omitempty"`     // until when requests fail with LastError without retrying
+	}
+
+	FunctionConditionType string
+
+	FunctionCondition struct {
+		Type    FunctionConditionType `json:"type"`
+		Status  apiv1.ConditionStatus `json:"status"`
+		Reason  string                `json:"reason,omitempty"`
+		Message string                `json:"message,omitempty"`
+	}
+
+	// EnvironmentStatus is the state of the pool of an environment, as
+	// reported by the executor. Like FunctionStatus, it isn't stored
+	// with the environment; the controller fills it in when
+	// environments are read.
+	EnvironmentStatus struct {
+		Phase        EnvironmentPhase `json:"phase,omitempty"`
+		Message      string           `json:"message,omitempty"`
+		ReadyPods    int              `json:"readyPods"`    // ready idle pods of the pool of the current version
+		OutdatedPods int              `json:"outdatedPods"` // pods specialized from previous versions, still serving until they drain
+	}
+
+	EnvironmentPhase string
+
 	PackageRef struct {
 		Namespace string `json:"namespace"`
 		Name      string `json:"name"`
@@ -127,17 +164,47 @@ type (
 		// function cannot be invoked.
 		Environment EnvironmentReference `json:"environment"`
 
-		// Reference to a package containing deployment and optionally the source
+		// Reference to a package containing deployment and optionally the source.
+		// Not used by functions with an Image.
 		Package FunctionPackageRef `json:"package"`
 
+		// Image is a container image that serves the function over HTTP on
+		// Port. The newdeploy executor runs it as is, instead of the
+		// environment's runtime specialized with the package by the fetcher;
+		// the environment still provides defaults such as the resources,
+		// idle timeout and PodSpec. Secrets and ConfigMaps aren't fetched for
+		// it; refer to them in Env or mount them with the PodSpec instead.
+		// Only the newdeploy executor supports it.
+		// Optional
+		Image string `json:"image,omitempty"`
+
+		// Port is the port the Image serves HTTP on.
+		// Optional, defaults to 8888
+		Port int `json:"port,omitempty"`
+
 		Secrets    []SecretReference    `json:"secrets"`
 		ConfigMaps []ConfigMapReference `json:"configmaps"`
 
+		// Env is the environment of the function: literal values, or keys
+		// of secrets and configmaps in the function's namespace. The
+		// newdeploy and job executors set them on the function's
+		// container, copying the keys into a secret next to its pods;
+		// poolmgr passes them in the FunctionLoadRequest. Not supported
+		// for environments loading several functions per container.
+		// Optional
+		Env []apiv1.EnvVar `json:"env,omitempty"`
+
 		// cpu and memory resources as per K8S standards
 		Resources apiv1.ResourceRequirements `json:"resources"`
 
 		// InvokeStrategy is a set of controls which affect how function executes
 		InvokeStrategy InvokeStrategy
+
+		// PodSpec is overlaid on the environment's PodSpec for the pods
+		// of this function. Only the newdeploy and job executors run
+		// pods for a single function, so it's not allowed for poolmgr.
+		// Optional
+		PodSpec *apiv1.PodSpec `json:"podspec,omitempty"`
 	}
 
 	/*InvokeStrategy is a set of controls over how the function executes.
@@ -161,16 +228,44 @@ type (
 	deployment is created on first invocation of function and is good for requests of
 	asynchronous nature. If MinScale is greater than 0 then MinScale number of pods are
 	created at the time of creation of function. This ensures faster response during first
-	invocation at the cost of consuming resources.
+	invocation at the cost of consuming resources. For the poolmgr executor, MinScale pods
+	are specialized when the function is created or updated, and idle pods aren't reaped
+	below MinScale.
 
 	MaxScale is the maximum number of pods that function will scale to based on TargetCPUPercent
-	and resources allocated to the function pod.
+	and resources allocated to the function pod. For the poolmgr executor it's the maximum
+	number of pods specialized for the function.
+
+	TargetConcurrency and TargetLatency (in milliseconds) are the per-pod concurrent requests
+	and request latency above which the poolmgr executor specializes another pod for the
+	function, up to MaxScale. A TargetConcurrency of 0 means the default; a TargetLatency
+	of 0 means latency is not considered.
+
+	For the newdeploy executor, setting TargetConcurrency or TargetRPS (requests per second)
+	scales the function's deployment between MinScale and MaxScale on the requests per pod
+	reported by the router, instead of on TargetCPUPercent.
+
+	IdleTimeout is the number of seconds a specialized pod may stay unused before it's
+	reaped. If it's 0, the environment's IdleTimeout is used. For the newdeploy executor,
+	the deployment of a function with a MinScale of 0 is scaled to zero replicas once it's
+	been unused for IdleTimeout (5 minutes if neither the function nor the environment set
+	one), and scaled back up by the next request.
+
+	Retries and ActiveDeadline (in seconds) apply to the job executor: a failed invocation
+	is retried on a new pod up to Retries times, and an invocation still running after
+	ActiveDeadline is failed. An ActiveDeadline of 0 means the default.
 	*/
 	ExecutionStrategy struct {
-		ExecutorType     ExecutorType
-		MinScale         int
-		MaxScale         int
-		TargetCPUPercent int
+		ExecutorType      ExecutorType
+		MinScale          int
+		MaxScale          int
+		TargetCPUPercent  int
+		IdleTimeout       int
+		TargetConcurrency int
+		TargetLatency     int
+		TargetRPS         int
+		Retries           int
+		ActiveDeadline    int
 	}
 
 	FunctionReferenceType string
@@ -265,9 +360,52 @@ type (
 		// The initial pool size for environment
 		Poolsize int `json:"poolsize,omitempty"`
 
+		// MinPoolsize and MaxPoolsize turn on pool autoscaling when
+		// MaxPoolsize is greater than zero. The number of warm pods is
+		// then adjusted between these bounds based on the recent rate of
+		// specializations, starting from Poolsize.
+		// Optional, defaults to a fixed size pool.
+		MinPoolsize int `json:"minpoolsize,omitempty"`
+		MaxPoolsize int `json:"maxpoolsize,omitempty"`
+
+		// The number of seconds a specialized pod of this environment may stay
+		// unused before it's reaped, or a newdeploy function's deployment before
+		// it's scaled to zero, unless the function overrides it.
+		// Optional, defaults to 120 seconds for pods and 300 for deployments
+		IdleTimeout int `json:"idletimeout,omitempty"`
+
+		// MaxSpecializedPods caps the number of pods of this environment
+		// specialized for functions at once. When it's reached, the least
+		// recently used specialized pod is evicted to make room for the
+		// next one. Not used when AllowedFunctionsPerContainer is infinite.
+		// Optional, defaults to no cap
+		MaxSpecializedPods int `json:"maxspecializedpods,omitempty"`
+
+		// PodReadyTimeout is the number of seconds to wait for a pod of
+		// this environment to become ready before failing a function's
+		// request. Pods whose image can't be pulled, or that keep
+		// crashing, fail it right away.
+		// Optional, defaults to 300 seconds for pools and 120 for newdeploy
+		PodReadyTimeout int `json:"podreadytimeout,omitempty"`
+
+		// SpecializationTimeout is the number of seconds to spend loading
+		// a function into a pod of this environment, including retries
+		// while its server isn't up yet.
+		// Optional, defaults to 180 seconds
+		SpecializationTimeout int `json:"specializationtimeout,omitempty"`
+
 		// The grace time for pod to perform connection draining before termination. The unit is in seconds.
 		// Optional, defaults to 360 seconds
 		TerminationGracePeriod int64
+
+		// PodSpec is overlaid on the spec of the pods running this
+		// environment, to set node selectors, tolerations, affinity, a
+		// service account, extra volumes, sidecars and the like. Volumes
+		// and containers named like the ones Fission adds are merged into
+		// them; see MergePodSpecs. A service account replacing the default
+		// one needs the permissions of the fetcher.
+		// Optional
+		PodSpec *apiv1.PodSpec `json:"podspec,omitempty"`
 	}
 
 	AllowedFunctionsPerContainer string
@@ -309,6 +447,33 @@ type (
 		FunctionReference `json:"functionref"`
 	}
 
+	// FissionQuotaSpec caps what the functions of a namespace may
+	// use. Every quota of a namespace is enforced. Zero values mean
+	// no cap.
+	//
+	// MaxScale caps the MaxScale the functions declare, at least 1
+	// each; the executor never runs more instances of a function
+	// than its MaxScale, so it caps their instances too. Like
+	// MaxFunctions and MaxPackageBytes, it's checked when functions
+	// and packages are created or updated through the controller,
+	// and when packages are built.
+	FissionQuotaSpec struct {
+		MaxFunctions       int   `json:"maxfunctions,omitempty"`       // functions in the namespace
+		MaxScale           int   `json:"maxscale,omitempty"`           // sum of the MaxScale of the namespace's functions
+		MaxSpecializedPods int   `json:"maxspecializedpods,omitempty"` // poolmgr pods specialized for the namespace's functions at once
+		MaxPackageBytes    int64 `json:"maxpackagebytes,omitempty"`    // bytes of literal archives, and of archives in the storage service, of the namespace's packages
+	}
+
+	// FissionQuotaStatus is the usage of a namespace. Like
+	// FunctionStatus, it isn't stored with the quota; the controller
+	// fills it in when quotas are read.
+	FissionQuotaStatus struct {
+		Functions       int   `json:"functions"`
+		Scale           int   `json:"scale"`
+		SpecializedPods int   `json:"specializedPods"`
+		PackageBytes    int64 `json:"packageBytes"`
+	}
+
 	// Errors returned by the Fission API.
 	Error struct {
 		Code    errorCode `json:"code"`
@@ -337,17 +502,33 @@ type (
 		FunctionName string `json:"functionName"`
 
 		// URL to expose this function at. Optional; defaults
-		// to "/".
+		// to "/". Containers running several functions serve
+		// each one at its own URL.
 		URL string `json:"url"`
 
 		// Metatdata
 		FunctionMetadata *metav1.ObjectMeta
+
+		// EnvVars are environment variables of the function,
+		// which the environment should set before loading it.
+		// Optional.
+		EnvVars map[string]string `json:"envVars,omitempty"`
 	}
 )
 
 const EXECUTOR_INSTANCEID_LABEL string = "executorInstanceId"
 const POOLMGR_INSTANCEID_LABEL string = "poolmgrInstanceId"
 
+// Resource versions recorded on executor objects, so that a restarted
+// executor can tell whether they're still up to date before adopting them.
+const ENVIRONMENT_RESOURCEVERSION_ANNOTATION string = "environmentResourceVersion"
+const FUNCTION_RESOURCEVERSION_ANNOTATION string = "functionResourceVersion"
+
+// Shared state of specialized pods, so that any executor replica can tell
+// when a pod was last used and when it may be reaped.
+const LAST_ACCESS_TIME_ANNOTATION string = "lastAccessTime"
+const IDLE_TIMEOUT_ANNOTATION string = "idleTimeout"
+
 const (
 	ChecksumTypeSHA256 ChecksumType = "sha256"
 )
@@ -369,6 +550,40 @@ const (
 	BuildStatusNone      = "none"
 )
 
+const (
+	// FunctionPackageReady is true once the function's package is built.
+	FunctionPackageReady FunctionConditionType = "PackageReady"
+
+	// FunctionSpecialized is true while poolmgr pods are specialized for
+	// the current version of the function.
+	FunctionSpecialized FunctionConditionType = "Specialized"
+
+	// FunctionDeployed is true while the newdeploy deployment of the
+	// current version of the function has ready pods, or has been scaled
+	// to zero while idle.
+	FunctionDeployed FunctionConditionType = "Deployed"
+)
+
+const (
+	// FunctionReasonIdle is the reason of a Specialized or Deployed
+	// condition that's false only because the function isn't in use.
+	FunctionReasonIdle = "Idle"
+)
+
+const (
+	// EnvironmentPending is the phase of an environment until the pool
+	// of its current version has a ready pod.
+	EnvironmentPending EnvironmentPhase = "Pending"
+
+	// EnvironmentRollingOut is the phase of an environment while pools or
+	// specialized pods of its previous versions are left.
+	EnvironmentRollingOut EnvironmentPhase = "RollingOut"
+
+	// EnvironmentReady is the phase of an environment whose pods all run
+	// its current version.
+	EnvironmentReady EnvironmentPhase = "Ready"
+)
+
 const (
 	AllowedFunctionsPerContainerSingle   = "single"
 	AllowedFunctionsPerContainerInfinite = "infinite"
@@ -377,6 +592,7 @@ const (
 const (
 	ExecutorTypePoolmgr   = "poolmgr"
 	ExecutorTypeNewdeploy = "newdeploy"
+	ExecutorTypeJob       = "job"
 )
 
 const (
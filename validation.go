@@ -276,11 +276,41 @@ func (spec FunctionSpec) Validate() error {
 	for _, c := range spec.ConfigMaps {
 		result = multierror.Append(result, c.Validate())
 	}
+	for _, e := range spec.Env {
+		if len(e.Name) == 0 {
+			result = multierror.Append(result, MakeValidationErr(ErrorInvalidValue, "FunctionSpec.Env.Name", e.Name, "name must not be empty"))
+		}
+		if e.ValueFrom != nil && ((e.ValueFrom.SecretKeyRef == nil) == (e.ValueFrom.ConfigMapKeyRef == nil) || len(e.Value) > 0) {
+			result = multierror.Append(result, MakeValidationErr(ErrorUnsupportedType, "FunctionSpec.Env.ValueFrom", e.Name, "must refer to either a secret or a configmap key, without a value"))
+		}
+	}
 
 	if spec.InvokeStrategy != (InvokeStrategy{}) {
 		result = multierror.Append(result, spec.InvokeStrategy.Validate())
 	}
 
+	if len(spec.Image) > 0 {
+		if spec.InvokeStrategy.ExecutionStrategy.ExecutorType != ExecutorTypeNewdeploy {
+			result = multierror.Append(result, MakeValidationErr(ErrorUnsupportedType, "FunctionSpec.Image", spec.InvokeStrategy.ExecutionStrategy.ExecutorType, "Image is only supported by the newdeploy executor"))
+		}
+		if spec.Package.PackageRef != (PackageRef{}) {
+			result = multierror.Append(result, MakeValidationErr(ErrorInvalidValue, "FunctionSpec.Package", spec.Package.PackageRef.Name, "functions with an Image don't have a package"))
+		}
+		if len(spec.Secrets) > 0 || len(spec.ConfigMaps) > 0 {
+			result = multierror.Append(result, MakeValidationErr(ErrorInvalidValue, "FunctionSpec.Image", spec.Image, "secrets and configmaps aren't fetched for functions with an Image, use Env or PodSpec instead"))
+		}
+	}
+
+	if spec.Port < 0 || spec.Port > 65535 {
+		result = multierror.Append(result, MakeValidationErr(ErrorInvalidValue, "FunctionSpec.Port", spec.Port, "Port must be between 0 and 65535"))
+	}
+
+	// functions without an executor type run on poolmgr
+	if spec.PodSpec != nil && (spec.InvokeStrategy.ExecutionStrategy.ExecutorType == ExecutorTypePoolmgr ||
+		len(spec.InvokeStrategy.ExecutionStrategy.ExecutorType) == 0) {
+		result = multierror.Append(result, MakeValidationErr(ErrorUnsupportedType, "FunctionSpec.PodSpec", spec.InvokeStrategy.ExecutionStrategy.ExecutorType, "PodSpec is not supported by the poolmgr executor, set it on the environment instead"))
+	}
+
 	return result.ErrorOrNil()
 }
 
@@ -302,7 +332,7 @@ func (es ExecutionStrategy) Validate() error {
 	var result *multierror.Error
 
 	switch es.ExecutorType {
-	case ExecutorTypeNewdeploy, ExecutorTypePoolmgr: // no op
+	case ExecutorTypeNewdeploy, ExecutorTypePoolmgr, ExecutorTypeJob: // no op
 	default:
 		result = multierror.Append(result, MakeValidationErr(ErrorUnsupportedType, "ExecutionStrategy.ExecutorType", es.ExecutorType, "not a valid executor type"))
 	}
@@ -319,6 +349,30 @@ func (es ExecutionStrategy) Validate() error {
 		result = multierror.Append(result, MakeValidationErr(ErrorInvalidValue, "ExecutionStrategy.TargetCPUPercent", es.TargetCPUPercent, "TargetCPUPercent must be a value between 1 - 100"))
 	}
 
+	if es.IdleTimeout < 0 {
+		result = multierror.Append(result, MakeValidationErr(ErrorInvalidValue, "ExecutionStrategy.IdleTimeout", es.IdleTimeout, "IdleTimeout must be greater or equal to 0"))
+	}
+
+	if es.TargetConcurrency < 0 {
+		result = multierror.Append(result, MakeValidationErr(ErrorInvalidValue, "ExecutionStrategy.TargetConcurrency", es.TargetConcurrency, "TargetConcurrency must be greater or equal to 0"))
+	}
+
+	if es.TargetLatency < 0 {
+		result = multierror.Append(result, MakeValidationErr(ErrorInvalidValue, "ExecutionStrategy.TargetLatency", es.TargetLatency, "TargetLatency must be greater or equal to 0"))
+	}
+
+	if es.TargetRPS < 0 {
+		result = multierror.Append(result, MakeValidationErr(ErrorInvalidValue, "ExecutionStrategy.TargetRPS", es.TargetRPS, "TargetRPS must be greater or equal to 0"))
+	}
+
+	if es.Retries < 0 {
+		result = multierror.Append(result, MakeValidationErr(ErrorInvalidValue, "ExecutionStrategy.Retries", es.Retries, "Retries must be greater or equal to 0"))
+	}
+
+	if es.ActiveDeadline < 0 {
+		result = multierror.Append(result, MakeValidationErr(ErrorInvalidValue, "ExecutionStrategy.ActiveDeadline", es.ActiveDeadline, "ActiveDeadline must be greater or equal to 0"))
+	}
+
 	return result.ErrorOrNil()
 }
 
@@ -380,6 +434,30 @@ func (spec EnvironmentSpec) Validate() error {
 		result = multierror.Append(result, MakeValidationErr(ErrorInvalidValue, "EnvironmentSpec.Poolsize", spec.Poolsize, "Poolsize must be greater or equal to 0"))
 	}
 
+	if spec.MinPoolsize < 0 {
+		result = multierror.Append(result, MakeValidationErr(ErrorInvalidValue, "EnvironmentSpec.MinPoolsize", spec.MinPoolsize, "MinPoolsize must be greater or equal to 0"))
+	}
+
+	if spec.MaxPoolsize < 0 || (spec.MaxPoolsize > 0 && spec.MaxPoolsize < spec.MinPoolsize) {
+		result = multierror.Append(result, MakeValidationErr(ErrorInvalidValue, "EnvironmentSpec.MaxPoolsize", spec.MaxPoolsize, "MaxPoolsize must be 0 (autoscaling disabled) or greater or equal to MinPoolsize"))
+	}
+
+	if spec.IdleTimeout < 0 {
+		result = multierror.Append(result, MakeValidationErr(ErrorInvalidValue, "EnvironmentSpec.IdleTimeout", spec.IdleTimeout, "IdleTimeout must be greater or equal to 0"))
+	}
+
+	if spec.MaxSpecializedPods < 0 {
+		result = multierror.Append(result, MakeValidationErr(ErrorInvalidValue, "EnvironmentSpec.MaxSpecializedPods", spec.MaxSpecializedPods, "MaxSpecializedPods must be greater or equal to 0"))
+	}
+
+	if spec.PodReadyTimeout < 0 {
+		result = multierror.Append(result, MakeValidationErr(ErrorInvalidValue, "EnvironmentSpec.PodReadyTimeout", spec.PodReadyTimeout, "PodReadyTimeout must be greater or equal to 0"))
+	}
+
+	if spec.SpecializationTimeout < 0 {
+		result = multierror.Append(result, MakeValidationErr(ErrorInvalidValue, "EnvironmentSpec.SpecializationTimeout", spec.SpecializationTimeout, "SpecializationTimeout must be greater or equal to 0"))
+	}
+
 	return result.ErrorOrNil()
 }
 
@@ -456,3 +534,25 @@ func (spec TimeTriggerSpec) Validate() error {
 
 	return result.ErrorOrNil()
 }
+
+func (spec FissionQuotaSpec) Validate() error {
+	var result *multierror.Error
+
+	if spec.MaxFunctions < 0 {
+		result = multierror.Append(result, MakeValidationErr(ErrorInvalidValue, "FissionQuotaSpec.MaxFunctions", spec.MaxFunctions, "MaxFunctions must be greater or equal to 0"))
+	}
+
+	if spec.MaxScale < 0 {
+		result = multierror.Append(result, MakeValidationErr(ErrorInvalidValue, "FissionQuotaSpec.MaxScale", spec.MaxScale, "MaxScale must be greater or equal to 0"))
+	}
+
+	if spec.MaxSpecializedPods < 0 {
+		result = multierror.Append(result, MakeValidationErr(ErrorInvalidValue, "FissionQuotaSpec.MaxSpecializedPods", spec.MaxSpecializedPods, "MaxSpecializedPods must be greater or equal to 0"))
+	}
+
+	if spec.MaxPackageBytes < 0 {
+		result = multierror.Append(result, MakeValidationErr(ErrorInvalidValue, "FissionQuotaSpec.MaxPackageBytes", spec.MaxPackageBytes, "MaxPackageBytes must be greater or equal to 0"))
+	}
+
+	return result.ErrorOrNil()
+}